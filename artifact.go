@@ -0,0 +1,340 @@
+package wfl
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/dgruber/drmaa2interface"
+	"github.com/dgruber/wfl/pkg/matrix"
+)
+
+// Artifact is a named output of a task which can be used as input
+// by later tasks of the workflow (see Produces() and Consumes()).
+type Artifact struct {
+	Name string
+	// Path of the file on the host running the workflow.
+	Path string
+	// JobID of the task which produced the artifact.
+	JobID string
+}
+
+// artifactOutput is an output declared for the next task of a job.
+type artifactOutput struct {
+	name string
+	// path of the file written by the task; empty for the output
+	path string
+}
+
+// artifactInput is an input declared for the next task of a job.
+type artifactInput struct {
+	name string
+	// path under which the task expects the file
+	path string
+}
+
+// artifact is an output of a submitted task.
+type artifact struct {
+	name string
+	job  *Job
+	task *task
+	// path on the host
+	path string
+	// captureOutput writes the output of the task into path
+	captureOutput bool
+
+	once     sync.Once
+	resolved Artifact
+	err      error
+}
+
+// artifacts is the registry of all artifacts of a workflow.
+type artifacts struct {
+	sync.Mutex
+	dir     string
+	entries map[string]*artifact
+	counter int
+}
+
+// WithArtifactDir sets the directory in which artifacts are stored which
+// are not written to a path of the task itself (like captured outputs or
+// files staged out of containers). By default a temporary directory is
+// created. For remote backends it needs to be on a shared filesystem.
+func (w *Workflow) WithArtifactDir(dir string) *Workflow {
+	w.artifacts.Lock()
+	defer w.artifacts.Unlock()
+	w.artifacts.dir = dir
+	return w
+}
+
+// Artifact waits until the task producing the artifact with the given
+// name is finished and returns where the artifact is stored.
+func (w *Workflow) Artifact(name string) (Artifact, error) {
+	w.artifacts.Lock()
+	a, exists := w.artifacts.entries[name]
+	w.artifacts.Unlock()
+	if !exists {
+		return Artifact{}, fmt.Errorf("unknown artifact %s", name)
+	}
+	return a.resolve()
+}
+
+// Produces declares that the next task submitted by the job (like with
+// RunT() or ThenRun()) writes the file with the given path as artifact
+// with the given name. Later tasks can use it with Consumes(). Job
+// arrays cannot produce artifacts.
+//
+// For Docker the directory of the path is mounted from the artifact
+// directory of the workflow (see WithArtifactDir()) so that the file
+// is available on the host. For all other backends the path needs to
+// be accessible from the host running the workflow.
+func (j *Job) Produces(name, path string) *Job {
+	j.begin(j.ctx, fmt.Sprintf("Produces(%s, %s)", name, path))
+	j.outputs = append(j.outputs, artifactOutput{name: name, path: path})
+	return j
+}
+
+// ProducesOutput declares that the output of the next task submitted by
+// the job is stored as artifact with the given name. See also Produces().
+func (j *Job) ProducesOutput(name string) *Job {
+	j.begin(j.ctx, fmt.Sprintf("ProducesOutput(%s)", name))
+	j.outputs = append(j.outputs, artifactOutput{name: name})
+	return j
+}
+
+// Consumes declares that the next task submitted by the job uses the
+// artifact with the given name. Before the task is submitted wfl waits
+// until the task producing the artifact is finished. The artifact is
+// made available to the task under the given path: it is mounted for
+// Docker (via StageInFiles) and copied for all other backends. If the
+// path is empty the artifact is used where it is stored. In each case
+// the placeholder {{.Artifacts.<name>}} in the job template is replaced
+// with the path of the artifact.
+//
+// Example:
+//
+//	flow.NewJob().
+//		ProducesOutput("list").Run("ls", "/data").
+//		Consumes("list", "").ThenRun("wc", "-l", "{{.Artifacts.list}}")
+func (j *Job) Consumes(name, path string) *Job {
+	j.begin(j.ctx, fmt.Sprintf("Consumes(%s, %s)", name, path))
+	j.inputs = append(j.inputs, artifactInput{name: name, path: path})
+	return j
+}
+
+// stagesByMount returns true if the backend makes StageInFiles available
+// by mounting the host paths into the container.
+func stagesByMount(smType SessionManagerType) bool {
+	return smType == DockerSessionManager
+}
+
+// outputPathIsLocal returns true if the backend writes the OutputPath
+// on the host running the workflow.
+func outputPathIsLocal(smType SessionManagerType) bool {
+	return smType == DefaultSessionManager
+}
+
+// isDevicePath returns true if the OutputPath does not refer to a
+// file, like /dev/stdout of the default template of the process backend.
+func isDevicePath(path string) bool {
+	switch path {
+	case "/dev/stdout", "/dev/stderr", "/dev/null":
+		return true
+	}
+	return false
+}
+
+// artifactPath returns a new path in the artifact directory.
+func (w *Workflow) artifactPath(name string) (string, error) {
+	w.artifacts.Lock()
+	defer w.artifacts.Unlock()
+	if w.artifacts.dir == "" {
+		dir, err := os.MkdirTemp("", "wfl-artifacts")
+		if err != nil {
+			return "", fmt.Errorf("failed creating artifact directory: %w", err)
+		}
+		w.artifacts.dir = dir
+	}
+	w.artifacts.counter++
+	dir := filepath.Join(w.artifacts.dir, fmt.Sprintf("%s-%d", name, w.artifacts.counter))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed creating artifact directory: %w", err)
+	}
+	return dir, nil
+}
+
+// prepareArtifacts applies the declared inputs and outputs of the next
+// task to its job template. It waits for the inputs.
+func (j *Job) prepareArtifacts(jt drmaa2interface.JobTemplate) (drmaa2interface.JobTemplate, []*artifact, error) {
+	inputs, outputs := j.inputs, j.outputs
+	j.inputs, j.outputs = nil, nil
+	smType := j.wfl.ctx.SMType
+
+	for _, input := range inputs {
+		a, err := j.wfl.Artifact(input.name)
+		if err != nil {
+			return jt, nil, err
+		}
+		path := input.path
+		switch {
+		case stagesByMount(smType):
+			if path == "" {
+				path = a.Path
+			}
+			jt.StageInFiles = mergeStringMap(copyStringMap(jt.StageInFiles),
+				map[string]string{a.Path: path})
+		case path == "":
+			path = a.Path
+		case path != a.Path:
+			if err := copyFile(a.Path, path); err != nil {
+				return jt, nil, fmt.Errorf("failed copying artifact %s: %w", input.name, err)
+			}
+		}
+		jt, err = matrix.ReplaceInField(jt, matrix.AllStrings,
+			"{{.Artifacts."+input.name+"}}", path)
+		if err != nil {
+			return jt, nil, err
+		}
+	}
+
+	produced := make([]*artifact, 0, len(outputs))
+	for _, output := range outputs {
+		a := &artifact{name: output.name, job: j, path: output.path}
+		switch {
+		case output.path == "" && (jt.OutputPath == "" || isDevicePath(jt.OutputPath)) &&
+			outputPathIsLocal(smType):
+			dir, err := j.wfl.artifactPath(output.name)
+			if err != nil {
+				return jt, nil, err
+			}
+			a.path = filepath.Join(dir, "output")
+			jt.OutputPath = a.path
+		case output.path == "":
+			dir, err := j.wfl.artifactPath(output.name)
+			if err != nil {
+				return jt, nil, err
+			}
+			a.path = filepath.Join(dir, "output")
+			a.captureOutput = true
+		case stagesByMount(smType):
+			dir, err := j.wfl.artifactPath(output.name)
+			if err != nil {
+				return jt, nil, err
+			}
+			jt.StageInFiles = mergeStringMap(copyStringMap(jt.StageInFiles),
+				map[string]string{dir: filepath.Dir(output.path)})
+			a.path = filepath.Join(dir, filepath.Base(output.path))
+		case !filepath.IsAbs(output.path) && jt.WorkingDirectory != "":
+			a.path = filepath.Join(jt.WorkingDirectory, output.path)
+		}
+		produced = append(produced, a)
+	}
+	return jt, produced, nil
+}
+
+// registerArtifacts makes the artifacts of the submitted task available.
+// A later artifact with the same name replaces an earlier one.
+func (j *Job) registerArtifacts(t *task, produced []*artifact) {
+	if len(produced) == 0 {
+		return
+	}
+	j.wfl.artifacts.Lock()
+	defer j.wfl.artifacts.Unlock()
+	if j.wfl.artifacts.entries == nil {
+		j.wfl.artifacts.entries = make(map[string]*artifact)
+	}
+	for _, a := range produced {
+		a.task = t
+		j.wfl.artifacts.entries[a.name] = a
+	}
+}
+
+// resubmitted lets the artifacts produced by the failed task refer to
+// the task which resubmitted it. The result of an earlier Artifact()
+// call is discarded.
+func (j *Job) resubmitted(failed, t *task) {
+	j.wfl.artifacts.Lock()
+	defer j.wfl.artifacts.Unlock()
+	for name, a := range j.wfl.artifacts.entries {
+		if a.task != failed {
+			continue
+		}
+		j.wfl.artifacts.entries[name] = &artifact{
+			name:          a.name,
+			job:           a.job,
+			task:          t,
+			path:          a.path,
+			captureOutput: a.captureOutput,
+		}
+	}
+}
+
+// resolve waits for the task producing the artifact. The result is
+// cached.
+func (a *artifact) resolve() (Artifact, error) {
+	a.once.Do(func() {
+		a.resolved, a.err = a.wait()
+	})
+	return a.resolved, a.err
+}
+
+func (a *artifact) wait() (Artifact, error) {
+	result := Artifact{Name: a.name, Path: a.path}
+	if a.task.job == nil {
+		return result, fmt.Errorf("task producing artifact %s was not submitted: %v",
+			a.name, a.task.submitError)
+	}
+	result.JobID = a.task.job.GetID()
+	if err := wait(a.job.ctx, a.task, drmaa2interface.InfiniteTime); err != nil {
+		return result, fmt.Errorf("failed waiting for task %s producing artifact %s: %w",
+			result.JobID, a.name, err)
+	}
+	if a.task.failed() {
+		return result, fmt.Errorf("task %s producing artifact %s failed", result.JobID, a.name)
+	}
+	if !a.captureOutput {
+		return result, nil
+	}
+	output, err := a.job.jobOutput(a.task.job)
+	if err != nil {
+		return result, fmt.Errorf("failed getting output of task %s for artifact %s: %w",
+			result.JobID, a.name, err)
+	}
+	if err := os.WriteFile(a.path, []byte(output), 0644); err != nil {
+		return result, fmt.Errorf("failed storing artifact %s: %w", a.name, err)
+	}
+	return result, nil
+}
+
+func copyStringMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	c := make(map[string]string, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
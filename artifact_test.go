@@ -0,0 +1,153 @@
+package wfl_test
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/dgruber/drmaa2interface"
+	"github.com/dgruber/wfl"
+	"github.com/dgruber/wfl/pkg/context/fake"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Artifact", func() {
+
+	var (
+		flow   *wfl.Workflow
+		tmpDir string
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "wflartifact")
+		Ω(err).Should(BeNil())
+		flow = wfl.NewWorkflow(wfl.NewProcessContext()).
+			WithArtifactDir(filepath.Join(tmpDir, "artifacts"))
+		Ω(flow.HasError()).Should(BeFalse())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(tmpDir)
+	})
+
+	It("should pass the output of a task to the next task", func() {
+		job := flow.NewJob().
+			ProducesOutput("greeting").Run("echo", "hello").
+			Consumes("greeting", "").ThenRunT(drmaa2interface.JobTemplate{
+			RemoteCommand: "cat",
+			Args:          []string{"{{.Artifacts.greeting}}"},
+			OutputPath:    filepath.Join(tmpDir, "out"),
+		})
+		Ω(job.LastError()).Should(BeNil())
+		Ω(job.Wait().Success()).Should(BeTrue())
+		Ω(job.Output()).Should(Equal("hello"))
+
+		artifact, err := flow.Artifact("greeting")
+		Ω(err).Should(BeNil())
+		Ω(artifact.Path).Should(HavePrefix(filepath.Join(tmpDir, "artifacts")))
+		Ω(artifact.JobID).ShouldNot(BeEmpty())
+	})
+
+	It("should copy file artifacts to the path of the consumer", func() {
+		produced := filepath.Join(tmpDir, "model.txt")
+		consumed := filepath.Join(tmpDir, "input", "model.txt")
+		job := flow.NewJob().
+			Produces("model", produced).
+			Run("/bin/bash", "-c", "echo weights > "+produced).
+			Consumes("model", consumed).
+			ThenRun("/bin/bash", "-c", "grep weights {{.Artifacts.model}}")
+		Ω(job.LastError()).Should(BeNil())
+		Ω(job.Wait().Success()).Should(BeTrue())
+
+		content, err := os.ReadFile(consumed)
+		Ω(err).Should(BeNil())
+		Ω(string(content)).Should(Equal("weights\n"))
+	})
+
+	It("should not submit a task when its artifact was not produced", func() {
+		job := flow.NewJob().
+			Produces("model", filepath.Join(tmpDir, "model.txt")).Run("false").
+			Consumes("model", "").ThenRun("cat", "{{.Artifacts.model}}")
+		Ω(job.LastError()).ShouldNot(BeNil())
+		Ω(job.LastError().Error()).Should(ContainSubstring("producing artifact model failed"))
+
+		job = flow.NewJob().Consumes("unknown", "").Run("true")
+		Ω(job.LastError()).ShouldNot(BeNil())
+	})
+
+	It("should capture the output of backends without local output files", func() {
+		sm := fake.NewSessionManager().
+			OnCommand("list", fake.Outcome{Output: "a\nb\n"})
+		flow := wfl.NewWorkflow(fake.NewFakeContextByCfg(fake.Config{SessionManager: sm})).
+			WithArtifactDir(tmpDir)
+		job := flow.NewJob().
+			ProducesOutput("files").Run("list").
+			Consumes("files", "").ThenRun("wc", "-l", "{{.Artifacts.files}}")
+		Ω(job.LastError()).Should(BeNil())
+
+		artifact, err := flow.Artifact("files")
+		Ω(err).Should(BeNil())
+		content, err := os.ReadFile(artifact.Path)
+		Ω(err).Should(BeNil())
+		Ω(string(content)).Should(Equal("a\nb"))
+		Ω(sm.Submitted()[1].Args).Should(Equal([]string{"-l", artifact.Path}))
+	})
+
+	It("should store the output when the OutputPath is /dev/stdout", func() {
+		flow := wfl.NewWorkflow(wfl.NewProcessContextByCfg(wfl.ProcessConfig{
+			DefaultTemplate: drmaa2interface.JobTemplate{OutputPath: "/dev/stdout"},
+		})).WithArtifactDir(tmpDir)
+		job := flow.NewJob().ProducesOutput("greeting").Run("echo", "hello")
+		Ω(job.Wait().Success()).Should(BeTrue())
+
+		artifact, err := flow.Artifact("greeting")
+		Ω(err).Should(BeNil())
+		content, err := os.ReadFile(artifact.Path)
+		Ω(err).Should(BeNil())
+		Ω(string(content)).Should(Equal("hello\n"))
+	})
+
+	It("should use the resubmitted task for the artifact", func() {
+		sm := fake.NewSessionManager().OnCommand("train",
+			fake.Outcome{ExitStatus: 1}, fake.Outcome{Output: "model"})
+		flow := wfl.NewWorkflow(fake.NewFakeContextByCfg(fake.Config{SessionManager: sm})).
+			WithArtifactDir(tmpDir)
+		job := flow.NewJob().ProducesOutput("model").Run("train")
+		_, err := flow.Artifact("model")
+		Ω(err).ShouldNot(BeNil())
+
+		job.Retry(1)
+		artifact, err := flow.Artifact("model")
+		Ω(err).Should(BeNil())
+		Ω(artifact.JobID).Should(Equal(job.JobID()))
+		content, err := os.ReadFile(artifact.Path)
+		Ω(err).Should(BeNil())
+		Ω(string(content)).Should(Equal("model"))
+	})
+
+	It("should make artifacts available to job arrays but not produce them", func() {
+		sm := fake.NewSessionManager().OnCommand("list", fake.Outcome{Output: "a"})
+		flow := wfl.NewWorkflow(fake.NewFakeContextByCfg(fake.Config{SessionManager: sm})).
+			WithArtifactDir(tmpDir)
+		job := flow.NewJob().ProducesOutput("files").Run("list").
+			Consumes("files", "").RunArrayT(1, 2, 1, 2, drmaa2interface.JobTemplate{
+			RemoteCommand: "cat",
+			Args:          []string{"{{.Artifacts.files}}"},
+		})
+		Ω(job.LastError()).Should(BeNil())
+		artifact, err := flow.Artifact("files")
+		Ω(err).Should(BeNil())
+		Ω(sm.Submitted()[1].Args).Should(Equal([]string{artifact.Path}))
+
+		job = flow.NewJob().ProducesOutput("array").RunArray(1, 2, 1, 2, "list")
+		Ω(job.LastError()).Should(MatchError(ContainSubstring("cannot produce artifacts")))
+		// the declaration is not applied to the next task
+		job.Run("list")
+		Ω(job.LastError()).Should(BeNil())
+		_, err = flow.Artifact("array")
+		Ω(err).ShouldNot(BeNil())
+	})
+
+})
@@ -0,0 +1,297 @@
+package wfl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/dgruber/drmaa2interface"
+)
+
+// CheckpointStore persists the state of a workflow so that a restarted
+// application can reattach to the tasks submitted before.
+type CheckpointStore interface {
+	// Save stores the given checkpoint and replaces the previous one.
+	Save(cp WorkflowCheckpoint) error
+	// Load returns the previously stored checkpoint. If there is no
+	// checkpoint it returns an empty one and no error.
+	Load() (WorkflowCheckpoint, error)
+}
+
+// WorkflowCheckpoint contains the state of all checkpointed jobs
+// of a workflow.
+type WorkflowCheckpoint struct {
+	// SessionName is the name of the DRMAA2 job session the
+	// jobs were submitted in.
+	SessionName string `json:"sessionName"`
+	// Jobs maps the tag of a job to its state.
+	Jobs map[string]JobCheckpoint `json:"jobs"`
+}
+
+// JobCheckpoint contains the tasks of a job in submission order.
+type JobCheckpoint struct {
+	Tag   string           `json:"tag"`
+	Tasks []TaskCheckpoint `json:"tasks"`
+}
+
+// TaskCheckpoint contains all details required for reattaching
+// to a task.
+type TaskCheckpoint struct {
+	JobID       string                      `json:"jobID"`
+	IsJobArray  bool                        `json:"isJobArray,omitempty"`
+	Template    drmaa2interface.JobTemplate `json:"template"`
+	Retry       int                         `json:"retry,omitempty"`
+	SubmitError string                      `json:"submitError,omitempty"`
+	// Coordinates of a task submitted by a matrix run.
+	Coordinates map[string]string `json:"coordinates,omitempty"`
+	// Members contains the tasks of a job array once failed
+	// array tasks were resubmitted individually.
+	Members []TaskCheckpoint `json:"members,omitempty"`
+}
+
+// FileCheckpointStore stores the workflow checkpoint as JSON file.
+type FileCheckpointStore struct {
+	path string
+}
+
+// NewFileCheckpointStore returns a CheckpointStore which keeps the
+// checkpoint in the given file.
+func NewFileCheckpointStore(path string) *FileCheckpointStore {
+	return &FileCheckpointStore{path: path}
+}
+
+// Save writes the checkpoint atomically into the file.
+func (s *FileCheckpointStore) Save(cp WorkflowCheckpoint) error {
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint: %w", err)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write checkpoint file %s: %w", tmp, err)
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// Load reads the checkpoint from the file. A non-existing file
+// results in an empty checkpoint.
+func (s *FileCheckpointStore) Load() (WorkflowCheckpoint, error) {
+	cp := WorkflowCheckpoint{Jobs: make(map[string]JobCheckpoint)}
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return cp, nil
+		}
+		return cp, fmt.Errorf("failed to read checkpoint file %s: %w", s.path, err)
+	}
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return cp, fmt.Errorf("failed to decode checkpoint file %s: %w", s.path, err)
+	}
+	if cp.Jobs == nil {
+		cp.Jobs = make(map[string]JobCheckpoint)
+	}
+	return cp, nil
+}
+
+// WithCheckpointStore enables checkpointing for the workflow. Previously
+// stored state is loaded from the store so that jobs can be reattached
+// with ResumeJob(). Afterwards each change in the task list of a tagged
+// job is persisted in the store. Jobs are identified by their tag, hence
+// only tagged jobs (see TagWith()) are checkpointed.
+//
+// Note that reattaching requires a backend which keeps track of jobs
+// beyond the lifetime of the application (like Docker, Kubernetes, or
+// the process context with PersistentJobStorage).
+func (w *Workflow) WithCheckpointStore(store CheckpointStore) *Workflow {
+	cp, err := store.Load()
+	if err != nil {
+		w.log.Errorf(context.Background(), "loading checkpoint failed: %v", err)
+		if w.workflowCreationError == nil {
+			w.workflowCreationError = err
+		}
+		return w
+	}
+	if w.ctx != nil && cp.SessionName != "" && cp.SessionName != w.ctx.JobSessionName {
+		err = fmt.Errorf("checkpoint belongs to job session %s but workflow uses %s",
+			cp.SessionName, w.ctx.JobSessionName)
+		w.log.Errorf(context.Background(), "%v", err)
+		if w.workflowCreationError == nil {
+			w.workflowCreationError = err
+		}
+		return w
+	}
+	w.checkpointMutex.Lock()
+	defer w.checkpointMutex.Unlock()
+	w.checkpointStore = store
+	w.checkpoint = cp
+	return w
+}
+
+// CheckpointedJobs returns the tags of all jobs found in the
+// checkpoint store.
+func (w *Workflow) CheckpointedJobs() []string {
+	w.checkpointMutex.Lock()
+	defer w.checkpointMutex.Unlock()
+	tags := make([]string, 0, len(w.checkpoint.Jobs))
+	for tag := range w.checkpoint.Jobs {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+// ResumeJob recreates the job with the given tag from the checkpoint
+// store. The tasks are reattached by looking them up in the job session
+// so that the job can be used for waiting, chaining, and retrying like
+// before the restart. Tasks which cannot be found anymore carry an error
+// which is reported by the methods accessing them. If the job is not
+// found in the checkpoint the error is available with LastError().
+func (w *Workflow) ResumeJob(tag string) *Job {
+	job := NewJob(w)
+	job.tag = tag
+
+	w.checkpointMutex.Lock()
+	jc, exists := w.checkpoint.Jobs[tag]
+	w.checkpointMutex.Unlock()
+
+	if !exists {
+		job.lastError = fmt.Errorf("job %s not found in checkpoint", tag)
+		return job
+	}
+	if err := job.checkCtx(); err != nil {
+		job.lastError = err
+		return job
+	}
+	if w.js == nil {
+		job.lastError = errors.New("JobSession is nil")
+		return job
+	}
+
+	jobs, err := w.js.GetJobs(drmaa2interface.CreateJobInfo())
+	if err != nil {
+		job.lastError = fmt.Errorf("failed to list jobs of job session: %w", err)
+		return job
+	}
+	jobsByID := make(map[string]drmaa2interface.Job, len(jobs))
+	for _, d2job := range jobs {
+		jobsByID[d2job.GetID()] = d2job
+	}
+
+	for _, tc := range jc.Tasks {
+		t := &task{
+			template:    tc.Template,
+			retry:       tc.Retry,
+			isJobArray:  tc.IsJobArray,
+			coordinates: tc.Coordinates,
+		}
+		switch {
+		case tc.SubmitError != "":
+			t.submitError = errors.New(tc.SubmitError)
+		case tc.IsJobArray:
+			t.jobArray, t.submitError = w.js.GetJobArray(tc.JobID)
+			if t.submitError == nil && len(tc.Members) > 0 {
+				t.members = resumeArrayMembers(t.jobArray, tc.Members, jobsByID)
+			}
+		default:
+			if d2job, found := jobsByID[tc.JobID]; found {
+				t.job = d2job
+			} else {
+				t.submitError = fmt.Errorf("job %s not found in job session", tc.JobID)
+			}
+		}
+		if t.submitError != nil {
+			job.warningf(job.ctx, "ResumeJob(%s): cannot reattach task %s: %v",
+				tag, tc.JobID, t.submitError)
+		}
+		job.tasklist = append(job.tasklist, t)
+	}
+	return job
+}
+
+func (w *Workflow) hasCheckpointStore() bool {
+	w.checkpointMutex.Lock()
+	defer w.checkpointMutex.Unlock()
+	return w.checkpointStore != nil
+}
+
+func (w *Workflow) saveJobCheckpoint(jc JobCheckpoint) error {
+	w.checkpointMutex.Lock()
+	defer w.checkpointMutex.Unlock()
+	if w.checkpointStore == nil {
+		return nil
+	}
+	if w.checkpoint.Jobs == nil {
+		w.checkpoint.Jobs = make(map[string]JobCheckpoint)
+	}
+	if w.ctx != nil {
+		w.checkpoint.SessionName = w.ctx.JobSessionName
+	}
+	w.checkpoint.Jobs[jc.Tag] = jc
+	return w.checkpointStore.Save(w.checkpoint)
+}
+
+// checkpoint persists the task list of the job when the workflow
+// has a checkpoint store and the job is tagged.
+func (j *Job) checkpoint() {
+	if j == nil || j.wfl == nil || j.tag == "" || !j.wfl.hasCheckpointStore() {
+		return
+	}
+	jc := JobCheckpoint{
+		Tag:   j.tag,
+		Tasks: make([]TaskCheckpoint, 0, len(j.tasklist)),
+	}
+	for _, t := range j.tasklist {
+		tc := taskCheckpoint(t)
+		for _, member := range t.members {
+			tc.Members = append(tc.Members, taskCheckpoint(member))
+		}
+		jc.Tasks = append(jc.Tasks, tc)
+	}
+	if err := j.wfl.saveJobCheckpoint(jc); err != nil {
+		j.errorf(j.ctx, "checkpoint of job %s failed: %v", j.tag, err)
+	}
+}
+
+func taskCheckpoint(t *task) TaskCheckpoint {
+	tc := TaskCheckpoint{
+		Template:    t.template,
+		Retry:       t.retry,
+		IsJobArray:  t.isJobArray,
+		Coordinates: t.coordinates,
+	}
+	if t.job != nil {
+		tc.JobID = t.job.GetID()
+	} else if t.jobArray != nil {
+		tc.JobID = t.jobArray.GetID()
+	}
+	if t.submitError != nil && tc.JobID == "" {
+		tc.SubmitError = t.submitError.Error()
+	}
+	return tc
+}
+
+// resumeArrayMembers reattaches the tasks of a job array including
+// the replacements of failed array tasks.
+func resumeArrayMembers(jobArray drmaa2interface.ArrayJob, members []TaskCheckpoint, jobsByID map[string]drmaa2interface.Job) []*task {
+	for _, d2job := range jobArray.GetJobs() {
+		if _, exists := jobsByID[d2job.GetID()]; !exists {
+			jobsByID[d2job.GetID()] = d2job
+		}
+	}
+	resumed := make([]*task, 0, len(members))
+	for _, mc := range members {
+		member := &task{template: mc.Template, retry: mc.Retry}
+		if mc.SubmitError != "" {
+			member.submitError = errors.New(mc.SubmitError)
+		} else if d2job, found := jobsByID[mc.JobID]; found {
+			member.job = d2job
+		} else {
+			member.submitError = fmt.Errorf("job %s not found in job session", mc.JobID)
+		}
+		resumed = append(resumed, member)
+	}
+	return resumed
+}
@@ -0,0 +1,194 @@
+package wfl_test
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/dgruber/drmaa2interface"
+	"github.com/dgruber/wfl"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Checkpoint", func() {
+
+	var (
+		tmpDir         string
+		checkpointFile string
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "wflcheckpoint")
+		Ω(err).Should(BeNil())
+		checkpointFile = filepath.Join(tmpDir, "checkpoint.json")
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(tmpDir)
+	})
+
+	Context("FileCheckpointStore", func() {
+
+		It("should return an empty checkpoint when the file does not exist", func() {
+			cp, err := wfl.NewFileCheckpointStore(checkpointFile).Load()
+			Ω(err).Should(BeNil())
+			Ω(cp.Jobs).Should(BeEmpty())
+		})
+
+		It("should save and load a checkpoint", func() {
+			store := wfl.NewFileCheckpointStore(checkpointFile)
+			err := store.Save(wfl.WorkflowCheckpoint{
+				SessionName: "session",
+				Jobs: map[string]wfl.JobCheckpoint{
+					"tag": {
+						Tag: "tag",
+						Tasks: []wfl.TaskCheckpoint{
+							{
+								JobID: "1",
+								Template: drmaa2interface.JobTemplate{
+									RemoteCommand: "sleep",
+									Args:          []string{"1"},
+								},
+								Retry: 2,
+							},
+						},
+					},
+				},
+			})
+			Ω(err).Should(BeNil())
+			cp, err := store.Load()
+			Ω(err).Should(BeNil())
+			Ω(cp.SessionName).Should(Equal("session"))
+			Ω(cp.Jobs["tag"].Tasks).Should(HaveLen(1))
+			Ω(cp.Jobs["tag"].Tasks[0].Template.Args).Should(Equal([]string{"1"}))
+			Ω(cp.Jobs["tag"].Tasks[0].Retry).Should(Equal(2))
+		})
+
+		It("should fail loading a corrupted checkpoint", func() {
+			Ω(os.WriteFile(checkpointFile, []byte("{"), 0600)).Should(BeNil())
+			_, err := wfl.NewFileCheckpointStore(checkpointFile).Load()
+			Ω(err).ShouldNot(BeNil())
+			flow := wfl.NewWorkflow(wfl.NewProcessContext()).
+				WithCheckpointStore(wfl.NewFileCheckpointStore(checkpointFile))
+			Ω(flow.HasError()).Should(BeTrue())
+		})
+
+	})
+
+	Context("Resuming jobs", func() {
+
+		var ctx *wfl.Context
+
+		BeforeEach(func() {
+			ctx = wfl.NewProcessContextByCfg(wfl.ProcessConfig{
+				DBFile:               filepath.Join(tmpDir, "session.db"),
+				PersistentJobStorage: true,
+				JobDBFile:            filepath.Join(tmpDir, "jobs.db"),
+			})
+			Ω(ctx.HasError()).Should(BeFalse())
+		})
+
+		It("should checkpoint tagged jobs only", func() {
+			flow := wfl.NewWorkflow(ctx).
+				WithCheckpointStore(wfl.NewFileCheckpointStore(checkpointFile))
+			flow.Run("sleep", "0").Wait()
+			flow.Run("sleep", "0").TagWith("tagged").Run("sleep", "0").Synchronize()
+
+			Ω(flow.CheckpointedJobs()).Should(Equal([]string{"tagged"}))
+			cp, err := wfl.NewFileCheckpointStore(checkpointFile).Load()
+			Ω(err).Should(BeNil())
+			Ω(cp.Jobs["tagged"].Tasks).Should(HaveLen(2))
+			Ω(cp.SessionName).Should(Equal("wfl"))
+		})
+
+		It("should reattach to running and finished tasks", func() {
+			flow := wfl.NewWorkflow(ctx).
+				WithCheckpointStore(wfl.NewFileCheckpointStore(checkpointFile))
+			job := flow.NewJob().TagWith("pipeline").
+				Run("sleep", "0").Wait().
+				Run("./test_scripts/exit.sh", "1").Wait().
+				Run("sleep", "1")
+			Ω(job.Errored()).Should(BeFalse())
+			ids := []string{}
+			for _, j := range job.ListAll() {
+				ids = append(ids, j.GetID())
+			}
+
+			// simulate a restart where all Job objects are lost by reloading
+			// the checkpoint; the job session stays open as the persistent
+			// job DB can only be opened once per process
+			restarted := flow.WithCheckpointStore(wfl.NewFileCheckpointStore(checkpointFile))
+			Ω(restarted.HasError()).Should(BeFalse())
+			resumed := restarted.ResumeJob("pipeline")
+			Ω(resumed.LastError()).Should(BeNil())
+			Ω(resumed.Tag()).Should(Equal("pipeline"))
+
+			resumedIDs := []string{}
+			for _, j := range resumed.ListAll() {
+				resumedIDs = append(resumedIDs, j.GetID())
+			}
+			Ω(resumedIDs).Should(Equal(ids))
+
+			Ω(resumed.Wait().Success()).Should(BeTrue())
+			Ω(resumed.HasAnyFailed()).Should(BeTrue())
+
+			// continue chaining after the restart
+			resumed.ThenRun("sleep", "0").Wait()
+			Ω(resumed.Success()).Should(BeTrue())
+			cp, err := wfl.NewFileCheckpointStore(checkpointFile).Load()
+			Ω(err).Should(BeNil())
+			Ω(cp.Jobs["pipeline"].Tasks).Should(HaveLen(4))
+		})
+
+		It("should track retries in the checkpoint", func() {
+			flow := wfl.NewWorkflow(ctx).
+				WithCheckpointStore(wfl.NewFileCheckpointStore(checkpointFile))
+			flow.NewJob().TagWith("retried").Run("./test_scripts/exit.sh", "1").RetryAnyFailed(2)
+
+			cp, err := wfl.NewFileCheckpointStore(checkpointFile).Load()
+			Ω(err).Should(BeNil())
+			Ω(cp.Jobs["retried"].Tasks).Should(HaveLen(1))
+			Ω(cp.Jobs["retried"].Tasks[0].Retry).Should(Equal(2))
+		})
+
+		It("should reattach resubmitted job array tasks", func() {
+			flow := wfl.NewWorkflow(ctx).
+				WithCheckpointStore(wfl.NewFileCheckpointStore(checkpointFile))
+			job := flow.NewJob().TagWith("array").
+				RunArray(1, 2, 1, 2, "/bin/bash", "-c", "exit $((TASK_ID - 1))").
+				RetryAnyFailed(1)
+			Ω(job.HasAnyFailed()).Should(BeTrue())
+			ids := []string{}
+			for _, j := range job.ListAll() {
+				ids = append(ids, j.GetID())
+			}
+
+			resumed := flow.WithCheckpointStore(wfl.NewFileCheckpointStore(checkpointFile)).
+				ResumeJob("array")
+			Ω(resumed.LastError()).Should(BeNil())
+			resumedIDs := []string{}
+			for _, j := range resumed.ListAll() {
+				resumedIDs = append(resumedIDs, j.GetID())
+			}
+			Ω(resumedIDs).Should(Equal(ids))
+			Ω(resumed.ListAllFailed()).Should(HaveLen(1))
+		})
+
+		It("should fail resuming an unknown job", func() {
+			flow := wfl.NewWorkflow(ctx).
+				WithCheckpointStore(wfl.NewFileCheckpointStore(checkpointFile))
+			Ω(flow.ResumeJob("unknown").LastError()).ShouldNot(BeNil())
+		})
+
+		It("should reject a checkpoint of a different job session", func() {
+			store := wfl.NewFileCheckpointStore(checkpointFile)
+			Ω(store.Save(wfl.WorkflowCheckpoint{SessionName: "other"})).Should(BeNil())
+			flow := wfl.NewWorkflow(ctx).WithCheckpointStore(store)
+			Ω(flow.HasError()).Should(BeTrue())
+		})
+
+	})
+
+})
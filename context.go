@@ -52,8 +52,43 @@ const (
 	GoogleBatchSessionManager
 	// MPIOperatorSessionManager manages jobs as MPI operator jobs on Kubernetes
 	MPIOperatorSessionManager
+	// FakeSessionManager simulates jobs in memory for testing workflows
+	FakeSessionManager
 )
 
+// String returns the name of the backend (like "process" or "kubernetes").
+func (t SessionManagerType) String() string {
+	switch t {
+	case DefaultSessionManager:
+		return "process"
+	case DockerSessionManager:
+		return "docker"
+	case CloudFoundrySessionManager:
+		return "cloudfoundry"
+	case KubernetesSessionManager:
+		return "kubernetes"
+	case SingularitySessionManager:
+		return "singularity"
+	case SlurmSessionManager:
+		return "slurm"
+	case LibDRMAASessionManager:
+		return "libdrmaa"
+	case PodmanSessionManager:
+		return "podman"
+	case RemoteSessionManager:
+		return "remote"
+	case ExternalSessionManager:
+		return "external"
+	case GoogleBatchSessionManager:
+		return "googlebatch"
+	case MPIOperatorSessionManager:
+		return "mpioperator"
+	case FakeSessionManager:
+		return "fake"
+	}
+	return fmt.Sprintf("unknown(%d)", int(t))
+}
+
 // Context contains a pointer to execution backend and configuration for it.
 type Context struct {
 	CtxCreationErr     error
@@ -61,22 +96,68 @@ type Context struct {
 	SMType             SessionManagerType
 	DefaultDockerImage string
 	// DefaultTemplate contains all default settings for job submission
-	// which are copied (if not set) to Run() or RunT() methods
+	// which are copied (if not set) to the job templates of the Run(),
+	// RunT(), RunArray(), and RunArrayT() methods. Bool fields are set
+	// if they are true in the default, maps like the JobEnvironment are
+	// merged, and default Email addresses are added. A default can be
+	// cleared for a job by listing the field in its UnsetExtension.
 	DefaultTemplate drmaa2interface.JobTemplate
 	// ContextTaskID is a number which is incremented for each submitted
 	// task. After incrementing and before submitting the task
-	// all occurencies of the "{{.ID}}" string in the job template
-	// are replaced by the current task ID. Following fields are
-	// evaluated: OuputPath, ErrorPath. The workflow can be started
-	// with an offset by setting the ContextTaskID to a value > 0.
+	// all occurencies of the "{{.ID}}" string in the string fields of
+	// the job template are replaced by the current task ID (see
+	// Placeholders). The workflow can be started with an offset by
+	// setting the ContextTaskID to a value > 0.
 	ContextTaskID int64
 	// Mutext is used for protecting the ContextTaskID
 	sync.Mutex
 	// JobSessionName is set to "wfl" by default. It can be changed
 	// to a custom name. The name is used to create a DRMAA2 session.
 	JobSessionName string
+	// OutputStreamer opens a live stream of the output of a job. It
+	// is set by contexts which can follow the logs of their jobs (like
+	// Docker and Kubernetes). If not set the file in the OutputPath of
+	// the job is followed.
+	OutputStreamer OutputStreamer
+	// Variables are custom values which can be used in the job
+	// templates as {{.Vars.name}} (see Placeholders).
+	Variables map[string]string
 }
 
+// WithVariable sets a custom variable which replaces the placeholder
+// {{.Vars.name}} in the job templates of all tasks submitted afterwards.
+func (c *Context) WithVariable(name, value string) *Context {
+	c.Lock()
+	defer c.Unlock()
+	if c.Variables == nil {
+		c.Variables = make(map[string]string)
+	}
+	c.Variables[name] = value
+	return c
+}
+
+// variables returns a copy of the custom variables.
+func (c *Context) variables() map[string]string {
+	c.Lock()
+	defer c.Unlock()
+	vars := make(map[string]string, len(c.Variables))
+	for k, v := range c.Variables {
+		vars[k] = v
+	}
+	return vars
+}
+
+// UnsetExtension is a key of the ExtensionList of a job template. Its
+// value is a comma separated list of job template fields (like
+// "QueueName,JobEnvironment") which are not taken from the
+// DefaultTemplate of the context. The key is removed before the job
+// is submitted.
+//
+// Example:
+//
+//	jt.ExtensionList = map[string]string{wfl.UnsetExtension: "QueueName"}
+const UnsetExtension = "wfl.unset"
+
 // WithSessionName set the JobSessionName in the context.
 // The name is used to create a DRMAA2 session.
 func (c *Context) WithSessionName(jobSessionName string) *Context {
@@ -183,8 +264,8 @@ func NewProcessContextByCfg(cfg ProcessConfig) *Context {
 	if cfg.DBFile == "" {
 		cfg.DBFile = TmpFile()
 	}
-	var jobDB string
-	if cfg.PersistentJobStorage && cfg.JobDBFile == "" {
+	jobDB := cfg.JobDBFile
+	if cfg.PersistentJobStorage && jobDB == "" {
 		// we need job state DB along with job session DB
 		jobDB = TmpFile()
 	}
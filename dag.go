@@ -0,0 +1,384 @@
+package wfl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/dgruber/drmaa2interface"
+	"github.com/mitchellh/copystructure"
+)
+
+// DAGTaskState is the life-cycle state of a named task in a DAG.
+type DAGTaskState int
+
+const (
+	// DAGTaskPending means the task waits for its dependencies.
+	DAGTaskPending DAGTaskState = iota
+	// DAGTaskRunning means the task was submitted to the backend.
+	DAGTaskRunning
+	// DAGTaskDone means the task finished successfully.
+	DAGTaskDone
+	// DAGTaskFailed means the task could not be submitted or
+	// finished with a state different than drmaa2interface.Done.
+	DAGTaskFailed
+	// DAGTaskSkipped means the task was not submitted since at
+	// least one of its dependencies did not finish successfully.
+	DAGTaskSkipped
+)
+
+func (s DAGTaskState) String() string {
+	switch s {
+	case DAGTaskPending:
+		return "Pending"
+	case DAGTaskRunning:
+		return "Running"
+	case DAGTaskDone:
+		return "Done"
+	case DAGTaskFailed:
+		return "Failed"
+	case DAGTaskSkipped:
+		return "Skipped"
+	}
+	return "Unknown"
+}
+
+// DAG is a set of named tasks with explicit dependencies between them.
+// When running the DAG each task is submitted as soon as all tasks it
+// depends on are finished successfully. Tasks without dependencies
+// between each other run in parallel. Each task is executed as a Job
+// of the Workflow so that the usual Job methods can be applied on it.
+type DAG struct {
+	sync.Mutex
+	wfl       *Workflow
+	ctx       context.Context
+	tasks     map[string]*DAGTask
+	order     []string
+	started   bool
+	wg        sync.WaitGroup
+	lastError error
+}
+
+// DAGTask is a named node in a DAG.
+type DAGTask struct {
+	dag       *DAG
+	name      string
+	template  drmaa2interface.JobTemplate
+	dependsOn []string
+	state     DAGTaskState
+	job       *Job
+	done      chan struct{}
+}
+
+// NewDAG creates an empty DAG for the workflow.
+func (w *Workflow) NewDAG() *DAG {
+	return &DAG{
+		wfl:   w,
+		tasks: make(map[string]*DAGTask),
+		order: make([]string, 0, 16),
+	}
+}
+
+// WithContext binds all tasks of the DAG to the given context. When the
+// context is cancelled, pending tasks are skipped and waits of running
+// tasks are aborted (see Job.WithContext()).
+func (d *DAG) WithContext(ctx context.Context) *DAG {
+	d.Lock()
+	defer d.Unlock()
+	d.ctx = ctx
+	return d
+}
+
+// Task registers a named task executing the given command with the
+// given arguments. See TaskT().
+func (d *DAG) Task(name string, cmd string, args ...string) *DAGTask {
+	return d.TaskT(name, drmaa2interface.JobTemplate{RemoteCommand: cmd, Args: args})
+}
+
+// TaskT registers a named task defined by the JobTemplate. The name
+// must be unique within the DAG. Dependencies are added by calling
+// DependsOn() on the returned DAGTask.
+//
+// Example:
+//
+//	dag := flow.NewDAG()
+//	dag.Task("fetch", "fetch.sh")
+//	dag.Task("left", "left.sh").DependsOn("fetch")
+//	dag.Task("right", "right.sh").DependsOn("fetch")
+//	dag.Task("merge", "merge.sh").DependsOn("left", "right")
+//	failed := dag.Run().HasAnyFailed()
+func (d *DAG) TaskT(name string, jt drmaa2interface.JobTemplate) *DAGTask {
+	d.Lock()
+	defer d.Unlock()
+	jtCopy, err := copystructure.Copy(jt)
+	if err == nil {
+		jt = jtCopy.(drmaa2interface.JobTemplate)
+	}
+	t := &DAGTask{
+		dag:      d,
+		name:     name,
+		template: jt,
+		state:    DAGTaskPending,
+		done:     make(chan struct{}),
+	}
+	if d.started {
+		d.lastError = fmt.Errorf("cannot add task %s: DAG is already running", name)
+		return t
+	}
+	if _, exists := d.tasks[name]; exists {
+		d.lastError = fmt.Errorf("task %s is already defined", name)
+		return t
+	}
+	d.tasks[name] = t
+	d.order = append(d.order, name)
+	return t
+}
+
+// DependsOn adds dependencies to the task. The task is only submitted
+// after all named tasks finished successfully.
+func (t *DAGTask) DependsOn(names ...string) *DAGTask {
+	t.dag.Lock()
+	defer t.dag.Unlock()
+	if t.dag.started {
+		t.dag.lastError = fmt.Errorf("cannot add dependencies to task %s: DAG is already running", t.name)
+		return t
+	}
+	t.dependsOn = append(t.dependsOn, names...)
+	return t
+}
+
+// Name returns the name of the task.
+func (t *DAGTask) Name() string {
+	return t.name
+}
+
+// State returns the current state of the task.
+func (t *DAGTask) State() DAGTaskState {
+	t.dag.Lock()
+	defer t.dag.Unlock()
+	return t.state
+}
+
+// Job returns the Job which executes the task. It is nil as long
+// as the task was not submitted.
+func (t *DAGTask) Job() *Job {
+	t.dag.Lock()
+	defer t.dag.Unlock()
+	return t.job
+}
+
+// Validate checks that all dependencies refer to registered tasks and
+// that the dependencies do not contain a cycle.
+func (d *DAG) Validate() error {
+	d.Lock()
+	defer d.Unlock()
+	if d.lastError != nil {
+		return d.lastError
+	}
+	_, err := d.topologicalOrder()
+	return err
+}
+
+// TopologicalOrder returns the task names in an order in which each
+// task comes after all of its dependencies.
+func (d *DAG) TopologicalOrder() ([]string, error) {
+	d.Lock()
+	defer d.Unlock()
+	return d.topologicalOrder()
+}
+
+func (d *DAG) topologicalOrder() ([]string, error) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	marks := make(map[string]int, len(d.tasks))
+	order := make([]string, 0, len(d.tasks))
+
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch marks[name] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("dependency cycle detected: %v", append(path, name))
+		}
+		marks[name] = visiting
+		for _, dep := range d.tasks[name].dependsOn {
+			if _, exists := d.tasks[dep]; !exists {
+				return fmt.Errorf("task %s depends on unknown task %s", name, dep)
+			}
+			if err := visit(dep, append(path, name)); err != nil {
+				return err
+			}
+		}
+		marks[name] = visited
+		order = append(order, name)
+		return nil
+	}
+
+	for _, name := range d.order {
+		if err := visit(name, nil); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// Run validates the DAG and starts executing it. It does not block.
+// Use Synchronize() for waiting until all tasks are processed. If the
+// validation fails no task is submitted and the error is available
+// with LastError().
+func (d *DAG) Run() *DAG {
+	d.Lock()
+	defer d.Unlock()
+	if d.started {
+		d.lastError = errors.New("DAG is already running")
+		return d
+	}
+	if d.lastError != nil {
+		return d
+	}
+	if d.wfl == nil {
+		d.lastError = errors.New("no workflow defined")
+		return d
+	}
+	if _, err := d.topologicalOrder(); err != nil {
+		d.lastError = err
+		return d
+	}
+	d.started = true
+	d.wg.Add(len(d.order))
+	for _, name := range d.order {
+		go d.execute(d.tasks[name])
+	}
+	return d
+}
+
+func (d *DAG) execute(t *DAGTask) {
+	defer d.wg.Done()
+	defer close(t.done)
+
+	for _, dep := range t.dependsOn {
+		depTask := d.tasks[dep]
+		<-depTask.done
+		if depTask.State() != DAGTaskDone {
+			d.setState(t, DAGTaskSkipped)
+			return
+		}
+	}
+
+	job := NewJob(d.wfl).TagWith(t.name)
+	d.Lock()
+	if d.ctx != nil {
+		if d.ctx.Err() != nil {
+			t.state = DAGTaskSkipped
+			d.Unlock()
+			return
+		}
+		job.WithContext(d.ctx)
+	}
+	t.job = job
+	t.state = DAGTaskRunning
+	d.Unlock()
+
+	job.RunT(t.template)
+	if job.Errored() {
+		job.errorf(job.ctx, "DAG task %s submission failed: %v", t.name, job.LastError())
+		d.setState(t, DAGTaskFailed)
+		return
+	}
+	if job.Wait().Success() {
+		d.setState(t, DAGTaskDone)
+		return
+	}
+	d.setState(t, DAGTaskFailed)
+}
+
+func (d *DAG) setState(t *DAGTask, state DAGTaskState) {
+	d.Lock()
+	defer d.Unlock()
+	t.state = state
+}
+
+// Synchronize blocks until all tasks of the DAG are either finished
+// or skipped.
+func (d *DAG) Synchronize() *DAG {
+	d.wg.Wait()
+	return d
+}
+
+// HasAnyFailed returns true if any task of the DAG failed or was skipped
+// because a dependency failed. Note that the function implicitly waits
+// until all tasks are processed.
+func (d *DAG) HasAnyFailed() bool {
+	return len(d.Synchronize().ListAllFailed()) > 0
+}
+
+// ListAllFailed returns the names of all tasks which failed or were
+// skipped, sorted by name. It does not wait for running tasks.
+func (d *DAG) ListAllFailed() []string {
+	d.Lock()
+	defer d.Unlock()
+	failed := make([]string, 0)
+	for name, t := range d.tasks {
+		if t.state == DAGTaskFailed || t.state == DAGTaskSkipped {
+			failed = append(failed, name)
+		}
+	}
+	sort.Strings(failed)
+	return failed
+}
+
+// GetTask returns the task with the given name or nil if there is no such task.
+func (d *DAG) GetTask(name string) *DAGTask {
+	d.Lock()
+	defer d.Unlock()
+	return d.tasks[name]
+}
+
+// State returns the state of the named task. For unknown tasks
+// DAGTaskPending is returned.
+func (d *DAG) State(name string) DAGTaskState {
+	if t := d.GetTask(name); t != nil {
+		return t.State()
+	}
+	return DAGTaskPending
+}
+
+// States returns the current state of each task of the DAG.
+func (d *DAG) States() map[string]DAGTaskState {
+	d.Lock()
+	defer d.Unlock()
+	states := make(map[string]DAGTaskState, len(d.tasks))
+	for name, t := range d.tasks {
+		states[name] = t.state
+	}
+	return states
+}
+
+// LastError returns the error which happened when defining or
+// starting the DAG.
+func (d *DAG) LastError() error {
+	d.Lock()
+	defer d.Unlock()
+	return d.lastError
+}
+
+// Errored returns true if an error happened when defining or
+// starting the DAG.
+func (d *DAG) Errored() bool {
+	return d.LastError() != nil
+}
+
+// OnError executes the given function if an error happened when
+// defining or starting the DAG.
+func (d *DAG) OnError(f func(err error)) *DAG {
+	if err := d.LastError(); err != nil {
+		f(err)
+	}
+	return d
+}
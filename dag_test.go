@@ -0,0 +1,121 @@
+package wfl_test
+
+import (
+	"time"
+
+	"github.com/dgruber/wfl"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DAG", func() {
+
+	var flow *wfl.Workflow
+
+	BeforeEach(func() {
+		flow = wfl.NewWorkflow(wfl.NewProcessContext())
+		Ω(flow.HasError()).Should(BeFalse())
+	})
+
+	Context("Validation", func() {
+
+		It("should detect a cycle", func() {
+			dag := flow.NewDAG()
+			dag.Task("a", "sleep", "0").DependsOn("c")
+			dag.Task("b", "sleep", "0").DependsOn("a")
+			dag.Task("c", "sleep", "0").DependsOn("b")
+			err := dag.Validate()
+			Ω(err).ShouldNot(BeNil())
+			Ω(err.Error()).Should(ContainSubstring("cycle"))
+			Ω(dag.Run().Errored()).Should(BeTrue())
+			Ω(dag.State("a")).Should(Equal(wfl.DAGTaskPending))
+		})
+
+		It("should detect unknown dependencies", func() {
+			dag := flow.NewDAG()
+			dag.Task("a", "sleep", "0").DependsOn("unknown")
+			err := dag.Validate()
+			Ω(err).ShouldNot(BeNil())
+			Ω(err.Error()).Should(ContainSubstring("unknown"))
+		})
+
+		It("should reject duplicate task names", func() {
+			dag := flow.NewDAG()
+			dag.Task("a", "sleep", "0")
+			dag.Task("a", "sleep", "0")
+			Ω(dag.Validate()).ShouldNot(BeNil())
+		})
+
+		It("should return a topological order", func() {
+			dag := flow.NewDAG()
+			dag.Task("merge", "sleep", "0").DependsOn("left", "right")
+			dag.Task("left", "sleep", "0").DependsOn("fetch")
+			dag.Task("right", "sleep", "0").DependsOn("fetch")
+			dag.Task("fetch", "sleep", "0")
+			order, err := dag.TopologicalOrder()
+			Ω(err).Should(BeNil())
+			Ω(order).Should(HaveLen(4))
+			Ω(order[0]).Should(Equal("fetch"))
+			Ω(order[3]).Should(Equal("merge"))
+		})
+
+	})
+
+	Context("Execution", func() {
+
+		It("should run independent tasks in parallel", func() {
+			dag := flow.NewDAG()
+			dag.Task("fetch", "sleep", "0")
+			dag.Task("left", "sleep", "1").DependsOn("fetch")
+			dag.Task("right", "sleep", "1").DependsOn("fetch")
+			dag.Task("merge", "sleep", "0").DependsOn("left", "right")
+
+			start := time.Now()
+			Ω(dag.Run().Errored()).Should(BeFalse())
+			Ω(dag.HasAnyFailed()).Should(BeFalse())
+			Ω(time.Since(start)).Should(BeNumerically("<", 2*time.Second))
+
+			for name, state := range dag.States() {
+				Ω(state).Should(Equal(wfl.DAGTaskDone), name)
+			}
+			Ω(dag.GetTask("merge").Job().JobID()).ShouldNot(Equal(""))
+		})
+
+		It("should skip tasks depending on a failed task", func() {
+			dag := flow.NewDAG()
+			dag.Task("first", "sleep", "0")
+			dag.Task("failing", "./test_scripts/exit.sh", "1").DependsOn("first")
+			dag.Task("after", "sleep", "0").DependsOn("failing")
+			dag.Task("independent", "sleep", "0").DependsOn("first")
+
+			Ω(dag.Run().HasAnyFailed()).Should(BeTrue())
+			Ω(dag.State("first")).Should(Equal(wfl.DAGTaskDone))
+			Ω(dag.State("failing")).Should(Equal(wfl.DAGTaskFailed))
+			Ω(dag.State("after")).Should(Equal(wfl.DAGTaskSkipped))
+			Ω(dag.State("independent")).Should(Equal(wfl.DAGTaskDone))
+			Ω(dag.ListAllFailed()).Should(Equal([]string{"after", "failing"}))
+			Ω(dag.GetTask("after").Job()).Should(BeNil())
+		})
+
+		It("should mark tasks as failed when the submission fails", func() {
+			dag := flow.NewDAG()
+			dag.Task("broken", "thiscommanddoesnotexist")
+			dag.Task("after", "sleep", "0").DependsOn("broken")
+			Ω(dag.Run().HasAnyFailed()).Should(BeTrue())
+			Ω(dag.State("broken")).Should(Equal(wfl.DAGTaskFailed))
+			Ω(dag.State("after")).Should(Equal(wfl.DAGTaskSkipped))
+		})
+
+		It("should not allow adding tasks after the DAG started", func() {
+			dag := flow.NewDAG()
+			dag.Task("a", "sleep", "0")
+			dag.Run()
+			dag.Task("b", "sleep", "0")
+			Ω(dag.Errored()).Should(BeTrue())
+			dag.Synchronize()
+		})
+
+	})
+
+})
@@ -0,0 +1,427 @@
+package wfl
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/dgruber/drmaa2interface"
+)
+
+// EventType is the kind of a lifecycle transition of a task.
+type EventType string
+
+const (
+	EventSubmitted EventType = "submitted"
+	EventQueued    EventType = "queued"
+	EventRunning   EventType = "running"
+	EventSuspended EventType = "suspended"
+	EventDone      EventType = "done"
+	EventFailed    EventType = "failed"
+	// EventRetried is emitted instead of EventSubmitted when a failed
+	// task is resubmitted (like by Retry() or RetryAnyFailed()).
+	EventRetried EventType = "retried"
+	EventReaped  EventType = "reaped"
+)
+
+// DefaultEventPollInterval is the interval in which the states of the
+// tasks are checked when events are subscribed.
+const DefaultEventPollInterval = 500 * time.Millisecond
+
+// Event is a lifecycle transition of a task of the workflow.
+type Event struct {
+	Type EventType
+	Time time.Time
+	// JobID is the ID of the job in the backend.
+	JobID string
+	// ArrayJobID is set for tasks of a job array.
+	ArrayJobID string
+	// PreviousJobID is the ID of the failed job for EventRetried.
+	PreviousJobID string
+	// Tag of the wfl job the task belongs to.
+	Tag   string
+	Retry int
+	State drmaa2interface.JobState
+	// JobInfo is set for EventDone and EventFailed.
+	JobInfo *drmaa2interface.JobInfo
+
+	job   drmaa2interface.Job
+	owner *Job
+	// ctx is the context of the owner when the event was created
+	ctx context.Context
+	// submitError is only set for the events of ObserveAsync()
+	submitError error
+}
+
+// subscriber receives the events in its own goroutine so that a slow
+// receiver does not block the workflow or other subscribers.
+type subscriber struct {
+	mu     sync.Mutex
+	queue  []Event
+	wakeup chan struct{}
+	done   chan struct{}
+	exited chan struct{}
+}
+
+// watchedJob is a job whose state is checked by the poller.
+type watchedJob struct {
+	job   drmaa2interface.Job
+	event Event
+	state drmaa2interface.JobState
+}
+
+// eventBus publishes the events of a workflow. The state transitions
+// of all tasks are detected by a single poller goroutine which runs
+// only while there are subscribers and unfinished tasks.
+type eventBus struct {
+	mu          sync.Mutex
+	interval    time.Duration
+	subscribers map[int]*subscriber
+	nextID      int
+	watched     []*watchedJob
+	polling     bool
+	// session is the job session of the workflow whose jobs are
+	// listed by the poller
+	session drmaa2interface.JobSession
+}
+
+// WithEventPollInterval sets the interval in which the poller checks the
+// states of the tasks for Events() and Subscribe(). The default is
+// DefaultEventPollInterval. State transitions which are shorter than the
+// interval can be missed, the final state of a task is always reported.
+func (w *Workflow) WithEventPollInterval(interval time.Duration) *Workflow {
+	w.events.mu.Lock()
+	defer w.events.mu.Unlock()
+	w.events.interval = interval
+	return w
+}
+
+// Subscribe calls f for each lifecycle event of the tasks of the workflow
+// which are submitted after the call. The events are delivered in order
+// from a separate goroutine. The returned function ends the subscription;
+// events which were already received are still delivered.
+//
+// Example:
+//
+//	unsubscribe := flow.Subscribe(func(e wfl.Event) {
+//		fmt.Printf("%s %s %s\n", e.Time.Format(time.RFC3339), e.JobID, e.Type)
+//	})
+//	defer unsubscribe()
+func (w *Workflow) Subscribe(f func(e Event)) (unsubscribe func()) {
+	id := w.events.subscribe(f)
+	return func() {
+		w.events.unsubscribe(id)
+	}
+}
+
+// Events returns a channel which receives the lifecycle events of the
+// tasks of the workflow which are submitted after the call. The channel
+// is closed when the context is done. See also Subscribe().
+//
+// Example:
+//
+//	ctx, cancel := context.WithCancel(context.Background())
+//	defer cancel()
+//	for e := range flow.Events(ctx) {
+//		if e.Type == wfl.EventFailed {
+//			fmt.Printf("job %s failed\n", e.JobID)
+//		}
+//	}
+func (w *Workflow) Events(ctx context.Context) <-chan Event {
+	events := make(chan Event)
+	id := w.events.subscribe(func(e Event) {
+		select {
+		case events <- e:
+		case <-ctx.Done():
+		}
+	})
+	go func() {
+		<-ctx.Done()
+		<-w.events.unsubscribe(id)
+		close(events)
+	}()
+	return events
+}
+
+func (b *eventBus) subscribe(f func(e Event)) int {
+	s := &subscriber{
+		wakeup: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+		exited: make(chan struct{}),
+	}
+	go s.deliver(f)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.subscribers == nil {
+		b.subscribers = make(map[int]*subscriber)
+	}
+	b.nextID++
+	b.subscribers[b.nextID] = s
+	return b.nextID
+}
+
+// unsubscribe removes the subscriber. The returned channel is closed
+// when its goroutine has exited.
+func (b *eventBus) unsubscribe(id int) <-chan struct{} {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s, exists := b.subscribers[id]
+	if !exists {
+		exited := make(chan struct{})
+		close(exited)
+		return exited
+	}
+	delete(b.subscribers, id)
+	close(s.done)
+	return s.exited
+}
+
+// deliver calls f for the queued events until the subscriber is
+// removed. The remaining events are delivered before it returns.
+func (s *subscriber) deliver(f func(e Event)) {
+	defer close(s.exited)
+	for {
+		var done bool
+		select {
+		case <-s.done:
+			done = true
+		case <-s.wakeup:
+		}
+		s.mu.Lock()
+		queue := s.queue
+		s.queue = nil
+		s.mu.Unlock()
+		for _, e := range queue {
+			f(e)
+		}
+		if done {
+			return
+		}
+	}
+}
+
+func (s *subscriber) enqueue(e Event) {
+	s.mu.Lock()
+	s.queue = append(s.queue, e)
+	s.mu.Unlock()
+	select {
+	case s.wakeup <- struct{}{}:
+	default:
+	}
+}
+
+// enqueue passes the event only to the given subscriber.
+func (b *eventBus) enqueue(id int, e Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if s, exists := b.subscribers[id]; exists {
+		s.enqueue(e)
+	}
+}
+
+// subscribed returns true if there is at least one subscriber.
+func (b *eventBus) subscribed() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subscribers) > 0
+}
+
+func (b *eventBus) publish(e Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, s := range b.subscribers {
+		s.enqueue(e)
+	}
+}
+
+// watch publishes the submission of the job and lets the poller report
+// its state transitions.
+func (b *eventBus) watch(job drmaa2interface.Job, e Event) {
+	e.JobID = job.GetID()
+	e.Time = time.Now()
+	e.State = drmaa2interface.Undetermined
+	e.job = job
+	b.publish(e)
+	b.add(job, e)
+}
+
+// watchExisting lets the poller report the state of a job which was
+// submitted before.
+func (b *eventBus) watchExisting(job drmaa2interface.Job, e Event) {
+	e.JobID = job.GetID()
+	e.job = job
+	b.add(job, e)
+}
+
+func (b *eventBus) add(job drmaa2interface.Job, e Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, watched := range b.watched {
+		if watched.event.JobID == e.JobID {
+			return
+		}
+	}
+	b.watched = append(b.watched,
+		&watchedJob{job: job, event: e, state: drmaa2interface.Undetermined})
+	if !b.polling {
+		b.polling = true
+		go b.poll()
+	}
+}
+
+// reaped publishes that the job was reaped and stops watching it.
+func (b *eventBus) reaped(job drmaa2interface.Job, e Event) {
+	id := job.GetID()
+	b.mu.Lock()
+	for i, watched := range b.watched {
+		if watched.event.JobID == id {
+			b.watched = append(b.watched[:i], b.watched[i+1:]...)
+			break
+		}
+	}
+	b.mu.Unlock()
+	e.Type = EventReaped
+	e.Time = time.Now()
+	e.JobID = id
+	e.job = job
+	e.State = drmaa2interface.Undetermined
+	b.publish(e)
+}
+
+// poll checks the states of the watched jobs until all of them are
+// finished or no one is subscribed anymore.
+func (b *eventBus) poll() {
+	for {
+		b.mu.Lock()
+		if len(b.watched) == 0 || len(b.subscribers) == 0 {
+			b.watched = nil
+			b.polling = false
+			b.mu.Unlock()
+			return
+		}
+		interval := b.interval
+		if interval <= 0 {
+			interval = DefaultEventPollInterval
+		}
+		watched := make([]*watchedJob, len(b.watched))
+		copy(watched, b.watched)
+		b.mu.Unlock()
+
+		states := b.states(watched)
+		for _, w := range watched {
+			b.check(w, states[w])
+		}
+		time.Sleep(interval)
+	}
+}
+
+// states returns the current states of the watched jobs. The jobs of
+// the job session are listed once per poll; only jobs which are not
+// listed anymore (like reaped jobs) are requested individually.
+func (b *eventBus) states(watched []*watchedJob) map[*watchedJob]drmaa2interface.JobState {
+	listed := make(map[string]drmaa2interface.Job)
+	if b.session != nil {
+		if jobs, err := b.session.GetJobs(drmaa2interface.CreateJobInfo()); err == nil {
+			for _, job := range jobs {
+				listed[job.GetID()] = job
+			}
+		}
+	}
+	states := make(map[*watchedJob]drmaa2interface.JobState, len(watched))
+	for _, w := range watched {
+		job, exists := listed[w.event.JobID]
+		if !exists {
+			job = w.job
+		}
+		states[w] = job.GetState()
+	}
+	return states
+}
+
+// check publishes the transition of the job when its state changed.
+func (b *eventBus) check(w *watchedJob, state drmaa2interface.JobState) {
+	if state == w.state {
+		return
+	}
+	w.state = state
+	e := w.event
+	e.Type = eventType(state)
+	e.Time = time.Now()
+	e.State = state
+	if isTerminated(state) {
+		if ji, err := w.job.GetJobInfo(); err == nil {
+			e.JobInfo = &ji
+		}
+		b.mu.Lock()
+		for i, watched := range b.watched {
+			if watched == w {
+				b.watched = append(b.watched[:i], b.watched[i+1:]...)
+				break
+			}
+		}
+		b.mu.Unlock()
+	}
+	if e.Type != "" {
+		b.publish(e)
+	}
+}
+
+// eventType maps the DRMAA2 job state to the type of the event.
+func eventType(state drmaa2interface.JobState) EventType {
+	switch state {
+	case drmaa2interface.Queued, drmaa2interface.QueuedHeld,
+		drmaa2interface.Requeued, drmaa2interface.RequeuedHeld:
+		return EventQueued
+	case drmaa2interface.Running:
+		return EventRunning
+	case drmaa2interface.Suspended:
+		return EventSuspended
+	case drmaa2interface.Done:
+		return EventDone
+	case drmaa2interface.Failed:
+		return EventFailed
+	}
+	return ""
+}
+
+// publishSubmitted publishes the submission of the task. For resubmitted
+// tasks the ID of the failed job is given. Submission errors are passed
+// to the asynchronous observers of the job.
+func (j *Job) publishSubmitted(t *task, previousJobID string) {
+	if !j.wfl.events.subscribed() {
+		return
+	}
+	if t.job == nil && t.jobArray == nil {
+		if t.submitError != nil {
+			j.publishSubmitError(t.submitError)
+		}
+		return
+	}
+	e := Event{Type: EventSubmitted, Tag: j.tag, Retry: t.retry, owner: j, ctx: j.ctx}
+	if previousJobID != "" {
+		e.Type = EventRetried
+		e.PreviousJobID = previousJobID
+	}
+	if t.job != nil {
+		j.wfl.events.watch(t.job, e)
+		return
+	}
+	if t.jobArray != nil {
+		e.ArrayJobID = t.jobArray.GetID()
+		for _, job := range t.jobArray.GetJobs() {
+			j.wfl.events.watch(job, e)
+		}
+	}
+}
+
+// publishReaped publishes that the job of the task was reaped.
+func (j *Job) publishReaped(t *task, job drmaa2interface.Job) {
+	if !j.wfl.events.subscribed() {
+		return
+	}
+	e := Event{Tag: j.tag, Retry: t.retry, owner: j, ctx: j.ctx}
+	if t.jobArray != nil {
+		e.ArrayJobID = t.jobArray.GetID()
+	}
+	j.wfl.events.reaped(job, e)
+}
@@ -0,0 +1,57 @@
+package wfl
+
+import (
+	"github.com/dgruber/drmaa2interface"
+	g "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// stateJob is a job which counts the requests of its state.
+type stateJob struct {
+	drmaa2interface.Job
+	id       string
+	state    drmaa2interface.JobState
+	requests int
+}
+
+func (j *stateJob) GetID() string { return j.id }
+
+func (j *stateJob) GetState() drmaa2interface.JobState {
+	j.requests++
+	return j.state
+}
+
+// listingSession is a job session which counts the listings of its jobs.
+type listingSession struct {
+	drmaa2interface.JobSession
+	jobs     []drmaa2interface.Job
+	listings int
+}
+
+func (s *listingSession) GetJobs(filter drmaa2interface.JobInfo) ([]drmaa2interface.Job, error) {
+	s.listings++
+	return s.jobs, nil
+}
+
+var _ = g.Describe("Event polling", func() {
+
+	g.It("should list the jobs of the session once per poll", func() {
+		listed := &stateJob{id: "1", state: drmaa2interface.Running}
+		reaped := &stateJob{id: "2", state: drmaa2interface.Done}
+		js := &listingSession{jobs: []drmaa2interface.Job{listed}}
+		b := &eventBus{session: js}
+
+		watched := []*watchedJob{
+			{job: &stateJob{id: "1"}, event: Event{JobID: "1"}},
+			{job: reaped, event: Event{JobID: "2"}},
+		}
+		states := b.states(watched)
+		Ω(js.listings).Should(Equal(1))
+		Ω(states[watched[0]]).Should(Equal(drmaa2interface.Running))
+		Ω(watched[0].job.(*stateJob).requests).Should(Equal(0))
+		// jobs which are not listed anymore are requested individually
+		Ω(states[watched[1]]).Should(Equal(drmaa2interface.Done))
+		Ω(reaped.requests).Should(Equal(1))
+	})
+
+})
@@ -0,0 +1,149 @@
+package wfl_test
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/dgruber/drmaa2interface"
+	"github.com/dgruber/wfl"
+	"github.com/dgruber/wfl/pkg/context/fake"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Events", func() {
+
+	var (
+		sm     *fake.SessionManager
+		flow   *wfl.Workflow
+		ctx    context.Context
+		cancel context.CancelFunc
+	)
+
+	// receiveUntil collects events until one of the given type
+	// for the job was received.
+	receiveUntil := func(events <-chan wfl.Event, eventType wfl.EventType, jobID string) []wfl.Event {
+		var received []wfl.Event
+		for {
+			var e wfl.Event
+			Eventually(events).Should(Receive(&e))
+			received = append(received, e)
+			if e.Type == eventType && e.JobID == jobID {
+				return received
+			}
+		}
+	}
+
+	typesOf := func(events []wfl.Event, jobID string) []wfl.EventType {
+		var types []wfl.EventType
+		for _, e := range events {
+			if e.JobID == jobID {
+				types = append(types, e.Type)
+			}
+		}
+		return types
+	}
+
+	BeforeEach(func() {
+		sm = fake.NewSessionManager().WithManualClock().
+			OnCommand("sleep", fake.Outcome{Duration: time.Minute}).
+			OnCommand("fail", fake.Outcome{ExitStatus: 1})
+		flow = wfl.NewWorkflow(fake.NewFakeContextByCfg(fake.Config{SessionManager: sm})).
+			WithEventPollInterval(time.Millisecond)
+		ctx, cancel = context.WithCancel(context.Background())
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	It("should emit the state transitions of a task", func() {
+		events := flow.Events(ctx)
+		job := flow.Run("sleep").TagWith("training")
+		Ω(job.LastError()).Should(BeNil())
+
+		received := receiveUntil(events, wfl.EventRunning, job.JobID())
+		Ω(typesOf(received, job.JobID())).Should(Equal(
+			[]wfl.EventType{wfl.EventSubmitted, wfl.EventRunning}))
+
+		sm.Clock().Advance(time.Minute)
+		received = receiveUntil(events, wfl.EventDone, job.JobID())
+		done := received[len(received)-1]
+		Ω(done.State).Should(Equal(drmaa2interface.Done))
+		Ω(done.JobInfo).ShouldNot(BeNil())
+		Ω(done.JobInfo.ExitStatus).Should(Equal(0))
+		Ω(done.Time.IsZero()).Should(BeFalse())
+
+		job.ReapAll()
+		received = receiveUntil(events, wfl.EventReaped, job.JobID())
+		Ω(typesOf(received, job.JobID())).Should(Equal([]wfl.EventType{wfl.EventReaped}))
+	})
+
+	It("should emit failed and retried events", func() {
+		events := flow.Events(ctx)
+		job := flow.Run("fail")
+		failedID := job.JobID()
+		job.Retry(1)
+		retriedID := job.JobID()
+		Ω(retriedID).ShouldNot(Equal(failedID))
+
+		received := receiveUntil(events, wfl.EventFailed, retriedID)
+		Ω(typesOf(received, failedID)).Should(ContainElements(
+			wfl.EventSubmitted, wfl.EventFailed))
+		Ω(typesOf(received, retriedID)).Should(Equal(
+			[]wfl.EventType{wfl.EventRetried, wfl.EventFailed}))
+		for _, e := range received {
+			if e.Type == wfl.EventRetried {
+				Ω(e.PreviousJobID).Should(Equal(failedID))
+				Ω(e.Retry).Should(Equal(1))
+			}
+		}
+	})
+
+	It("should emit events for each task of a job array", func() {
+		events := flow.Events(ctx)
+		flow.RunArrayJob(1, 3, 1, 3, "true")
+
+		submitted := map[string]string{}
+		for len(submitted) < 3 {
+			var e wfl.Event
+			Eventually(events).Should(Receive(&e))
+			if e.Type == wfl.EventSubmitted {
+				submitted[e.JobID] = e.ArrayJobID
+			}
+		}
+		for _, arrayJobID := range submitted {
+			Ω(arrayJobID).ShouldNot(BeEmpty())
+		}
+	})
+
+	It("should call subscribers until they unsubscribe", func() {
+		var mu sync.Mutex
+		var received []wfl.Event
+		unsubscribe := flow.Subscribe(func(e wfl.Event) {
+			mu.Lock()
+			defer mu.Unlock()
+			received = append(received, e)
+		})
+		count := func() int {
+			mu.Lock()
+			defer mu.Unlock()
+			return len(received)
+		}
+
+		flow.Run("true")
+		Eventually(count).Should(Equal(2))
+		unsubscribe()
+		flow.Run("true")
+		Consistently(count, "50ms").Should(Equal(2))
+	})
+
+	It("should close the channel when the context is done", func() {
+		events := flow.Events(ctx)
+		cancel()
+		Eventually(events).Should(BeClosed())
+	})
+
+})
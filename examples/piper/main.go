@@ -16,6 +16,28 @@ func main() {
 	now := time.Now()
 	filePipeExample()
 	fmt.Printf("file based pipe took %s\n", time.Now().Sub(now).String())
+
+	// The same with the builtin Pipe() which connects the processes
+	// with a named pipe.
+	now = time.Now()
+	nativePipeExample()
+	fmt.Printf("native pipe took %s\n", time.Now().Sub(now).String())
+}
+
+func nativePipeExample() {
+	flow := wfl.NewWorkflow(wfl.NewProcessContext())
+
+	job := flow.Pipe(
+		drmaa2interface.JobTemplate{
+			RemoteCommand: "cat",
+			Args:          []string{"/etc/services"},
+		},
+		drmaa2interface.JobTemplate{
+			RemoteCommand: "sort",
+			OutputPath:    "/dev/stdout",
+		},
+	)
+	fmt.Printf("exit status of the stages: %v\n", job.PipeStatus())
 }
 
 func filePipeExample() {
@@ -27,6 +27,30 @@ type task struct {
 	waitForEndStateCollectedJobInfo bool
 	isJobArray                      bool
 	jobArray                        drmaa2interface.ArrayJob
+	history                         []RetryAttempt
+	// members contains the tasks of a job array after
+	// failed array tasks were resubmitted individually
+	members    []*task
+	arrayBegin int
+	arrayStep  int
+	// outputDone is closed when an OnOutputLine() function
+	// processed the complete output of the task
+	outputDone []chan struct{}
+	// coordinates contains the value of each replacement pattern
+	// when the task was submitted by a matrix run
+	coordinates map[string]string
+	// source is the job template before the placeholders were
+	// replaced; it is used for resubmitting the task
+	source *drmaa2interface.JobTemplate
+}
+
+// sourceTemplate returns the job template of the task before the
+// placeholders were replaced.
+func (t *task) sourceTemplate() drmaa2interface.JobTemplate {
+	if t.source != nil {
+		return *t.source
+	}
+	return t.template
 }
 
 // Job defines methods for job life-cycle management. A job is
@@ -39,16 +63,34 @@ type Job struct {
 	tasklist  []*task
 	tag       string
 	lastError error
-	ctx       context.Context // logging
+	ctx       context.Context // logging and cancellation
+	// terminateOnCancel terminates unfinished tasks when the
+	// context is cancelled
+	terminateOnCancel bool
+	retryPolicy       RetryPolicy
+	// limiter bounds the tasks of the job in flight
+	limiter *limiter
+	// inputs and outputs are the artifacts of the next task
+	inputs  []artifactInput
+	outputs []artifactOutput
+	// pipe contains the stages of the last Pipe()
+	pipe []*task
+	// observations are the asynchronous observers of the job
+	observationsMutex sync.Mutex
+	observations      []*Observation
 }
 
 // NewJob creates the initial empty job with the given workflow.
 func NewJob(wfl *Workflow) *Job {
-	return &Job{
+	job := &Job{
 		wfl:      wfl,
 		tasklist: make([]*task, 0, 32),
 		ctx:      context.Background(),
 	}
+	if wfl != nil {
+		wfl.registerJob(job)
+	}
+	return job
 }
 
 // EmptyJob creates an empty job.
@@ -62,6 +104,7 @@ func EmptyJob() *Job {
 func (j *Job) TagWith(tag string) *Job {
 	j.begin(j.ctx, fmt.Sprintf("TagWith(%s)", tag))
 	j.tag = tag
+	j.checkpoint()
 	return j
 }
 
@@ -103,7 +146,7 @@ func (j *Job) State() drmaa2interface.JobState {
 		task.jobinfoError == nil {
 		return task.jobinfo.State
 	}
-	job, jobArray, err := j.jobCheck()
+	job, _, err := j.jobCheck()
 	if err != nil {
 		j.lastError = err
 		return drmaa2interface.Undetermined
@@ -111,7 +154,7 @@ func (j *Job) State() drmaa2interface.JobState {
 	if job != nil {
 		return job.GetState()
 	}
-	return jobArrayState(jobArray, false)
+	return jobsState(j.ctx, task.arrayJobs(), false)
 }
 
 // JobID returns the job ID of the previously submitted job.
@@ -195,6 +238,12 @@ func (j *Job) Run(cmd string, args ...string) *Job {
 
 // RunT submits a task given specified with the JobTemplate.
 func (j *Job) RunT(t drmaa2interface.JobTemplate) *Job {
+	return j.runT(t, nil)
+}
+
+// runT submits a task. The coordinates are set for tasks of
+// matrix runs.
+func (j *Job) runT(t drmaa2interface.JobTemplate, coordinates map[string]string) *Job {
 	jobTemplateCopy, err := copystructure.Copy(t)
 	if err != nil {
 		j.errorf(j.ctx, "could not copy job template: %v", err)
@@ -206,26 +255,31 @@ func (j *Job) RunT(t drmaa2interface.JobTemplate) *Job {
 		j.lastError = err
 		return j
 	}
-	// merging only specific job template parameters
-	jt = mergeJobTemplateWithDefaultTemplate(jt, j.wfl.ctx.DefaultTemplate)
-
-	// replace placeholders in job template
-	jt.OutputPath = replaceNextContextID(jt.OutputPath, j.wfl.ctx)
-	jt.ErrorPath = replaceContextID(jt.ErrorPath, j.wfl.ctx)
-
-	// JobCategory overrides all at the moment...
-	if jt.JobCategory == "" {
-		jt.JobCategory = j.wfl.ctx.DefaultDockerImage
-	}
 	if j.wfl.js == nil {
 		j.lastError = errors.New("JobSession is nil")
 		return j
 	}
+	source, produced, err := j.prepareArtifacts(j.withDefaults(jt))
+	if err != nil {
+		j.errorf(j.ctx, "RunT(): preparing artifacts failed: %v", err)
+		j.lastError = err
+		return j
+	}
+	// replace placeholders in job template
+	jt = j.expand(source, 0)
+
 	jobTemplate, _ := copystructure.Copy(jt)
-	job, err := j.wfl.js.RunJob(jt)
+	job, err := j.submit(jt)
 	j.lastError = err
-	j.tasklist = append(j.tasklist, &task{job: job, submitError: err,
-		template: jobTemplate.(drmaa2interface.JobTemplate)})
+	newTask := &task{job: job, submitError: err,
+		template:    jobTemplate.(drmaa2interface.JobTemplate),
+		source:      &source,
+		coordinates: coordinates}
+	newTask.recordAttempt(0)
+	j.tasklist = append(j.tasklist, newTask)
+	j.registerArtifacts(newTask, produced)
+	j.publishSubmitted(newTask, "")
+	j.checkpoint()
 	return j
 }
 
@@ -243,21 +297,8 @@ func (j *Job) RunArray(begin, end, step, maxParallel int, cmd string, args ...st
 		j.lastError = err
 		return j
 	}
-	jt := drmaa2interface.JobTemplate{RemoteCommand: cmd, Args: args}
-	job, err := j.wfl.js.RunBulkJobs(jt, begin, end, step, maxParallel)
-	j.lastError = err
-	jobTemplate, copyErr := copystructure.Copy(jt)
-	if copyErr != nil {
-		j.tasklist = append(j.tasklist, &task{jobArray: job, isJobArray: true,
-			submitError: err,
-			template:    jobTemplate.(drmaa2interface.JobTemplate)})
-		j.errorf(j.ctx, "could not copy job template: %v", copyErr)
-		return j
-	}
-	j.tasklist = append(j.tasklist, &task{jobArray: job, isJobArray: true,
-		submitError: err,
-		template:    jobTemplate.(drmaa2interface.JobTemplate)})
-	return j
+	return j.runArray(begin, end, step, maxParallel,
+		drmaa2interface.JobTemplate{RemoteCommand: cmd, Args: args})
 }
 
 // RunArrayT executes the job defined in a JobTemplate multiple times. See also
@@ -269,12 +310,36 @@ func (j *Job) RunArrayT(begin, end, step, maxParallel int, jt drmaa2interface.Jo
 		j.lastError = err
 		return j
 	}
+	return j.runArray(begin, end, step, maxParallel, jt)
+}
+
+func (j *Job) runArray(begin, end, step, maxParallel int, jt drmaa2interface.JobTemplate) *Job {
+	if len(j.outputs) > 0 {
+		j.inputs, j.outputs = nil, nil
+		j.lastError = errors.New("job arrays cannot produce artifacts")
+		j.errorf(j.ctx, "RunArrayT(): %v", j.lastError)
+		return j
+	}
+	source, _, err := j.prepareArtifacts(j.withDefaults(jt))
+	if err != nil {
+		j.errorf(j.ctx, "RunArrayT(): preparing artifacts failed: %v", err)
+		j.lastError = err
+		return j
+	}
+	// the index of the array tasks is set by the backend
+	jt = j.expandWith(source, j.placeholders(0, "${"+arrayTaskIDEnv+"}"))
 	job, err := j.wfl.js.RunBulkJobs(jt, begin, end, step, maxParallel)
 	j.lastError = err
-	jobTemplate, _ := copystructure.Copy(jt)
-	j.tasklist = append(j.tasklist, &task{jobArray: job, isJobArray: true,
-		submitError: err,
-		template:    jobTemplate.(drmaa2interface.JobTemplate)})
+	jobTemplate, copyErr := copystructure.Copy(jt)
+	if copyErr != nil {
+		j.errorf(j.ctx, "could not copy job template: %v", copyErr)
+		jobTemplate = jt
+	}
+	t := newArrayTask(job, err, jobTemplate.(drmaa2interface.JobTemplate), begin, step)
+	t.source = &source
+	j.tasklist = append(j.tasklist, t)
+	j.publishSubmitted(t, "")
+	j.checkpoint()
 	return j
 }
 
@@ -303,7 +368,7 @@ const (
 	Priority          JobTemplateField = "Priority"
 	CandidateMachines JobTemplateField = "CandidateMachines"
 	MinPhysMemory     JobTemplateField = "MinPhysMemory"
-	MachineOS         JobTemplateField = "MachineOS"
+	MachineOS         JobTemplateField = "MachineOs"
 	MachineArch       JobTemplateField = "MachineArch"
 	StartTime         JobTemplateField = "StartTime"
 	DeadlineTime      JobTemplateField = "DeadlineTime"
@@ -311,6 +376,10 @@ const (
 	StageOutFiles     JobTemplateField = "StageOutFiles"
 	ResourceLimits    JobTemplateField = "ResourceLimits"
 	AccountingID      JobTemplateField = "AccountingID"
+	ExtensionList     JobTemplateField = "ExtensionList"
+	// AllStrings replaces the pattern in all fields which are strings,
+	// string slices, or string maps.
+	AllStrings JobTemplateField = "allStrings"
 )
 
 // Replacement defines the fields and the values to be replaced in the
@@ -322,6 +391,10 @@ type Replacement struct {
 	// - allStrings - all fields which are strings, string slices,
 	// or string maps are going to be searched for the pattern
 	// which is then replaced by one of the replacements.
+	// Non-string fields are set to the parsed replacement: bool
+	// and int fields to its value and time fields (StartTime and
+	// DeadlineTime) to the RFC 3339 time, Unix timestamp, or the
+	// duration relative to now (like "+2h").
 	Fields []JobTemplateField
 	// Pattern defines a string in the job template which is going to be
 	// replaced by the value of the replacement string.
@@ -330,6 +403,21 @@ type Replacement struct {
 	// in the job template. For each replacement a new job template is
 	// created and submitted.
 	Replacements []string
+	// Group zips replacements in RunMatrixNT(): all replacements with
+	// the same group are advanced together, like the pairs of Go's
+	// for i := range a { a[i], b[i] }, instead of being combined.
+	Group string
+	// Template is a Go text/template which computes the value the
+	// Pattern is replaced with. It can access the values of all
+	// patterns of the combination by their names, i.e. the pattern
+	// "{{epochs}}" as {{.epochs}}, and use the functions add, sub, mul,
+	// and div. A replacement with Template but without Replacements is
+	// not a dimension of the matrix; its value is computed for each
+	// combination of the other replacements.
+	//
+	// Example: Template: "{{mul .epochs 100}}" replaces the Pattern
+	// "{{steps}}" by 300 in the combination with epochs 3.
+	Template string
 }
 
 // RunMatrixT executes the job defined in a JobTemplate exactly
@@ -368,16 +456,16 @@ func (j *Job) RunMatrixT(jt drmaa2interface.JobTemplate, x, y Replacement) *Job
 		return j
 	}
 	jobTemplate := jtCopy.(drmaa2interface.JobTemplate)
-	jts, err := getJobTemplatesForMatrix(jobTemplate, x, y)
+	points, err := getMatrixPointsForXY(jobTemplate, x, y)
 	if err != nil {
 		j.errorf(j.ctx, "creating job templates failed: %v", err)
 		j.lastError = err
 		return j
 	}
 	// submit jobs for all job templates
-	for _, jt := range jts {
-		j.infof(j.ctx, "submitting job template: %v", jt)
-		j = j.RunT(jt)
+	for _, point := range points {
+		j.infof(j.ctx, "submitting job template: %v", point.template)
+		j = j.runT(point.template, point.values)
 		if j.Errored() {
 			err = j.lastError
 			j.errorf(j.ctx, "submitting job template failed: %v", err)
@@ -402,7 +490,7 @@ func (j *Job) Do(f func(job drmaa2interface.Job)) *Job {
 		f(job)
 	} else if err == nil && arrayjob != nil {
 		// execute function on each job array task
-		for _, arrayjobtask := range arrayjob.GetJobs() {
+		for _, arrayjobtask := range j.lastJob().arrayJobs() {
 			f(arrayjobtask)
 		}
 	} else {
@@ -471,18 +559,69 @@ func (j *Job) LastError() error {
 	return j.lastError
 }
 
-func rerunTask(j *Job, e *task) {
-	job, err := j.wfl.js.RunJob(e.template)
+// rerunTask submits a new task with the job template the task was created
+// from. The placeholders of the job template are replaced again.
+func rerunTask(j *Job, e *task, source drmaa2interface.JobTemplate, backoff time.Duration) {
+	jt := j.expand(source, e.retry+1)
+	job, err := j.submit(jt)
 	j.lastError = err
-	if err == nil {
-		jobTemplate, _ := copystructure.Copy(e.template)
-		j.tasklist = append(j.tasklist, &task{job: job, submitError: err,
-			template: jobTemplate.(drmaa2interface.JobTemplate)})
+	if err != nil {
+		j.publishSubmitError(err)
+		return
+	}
+	jobTemplate, _ := copystructure.Copy(jt)
+	t := &task{job: job, submitError: err,
+		template:    jobTemplate.(drmaa2interface.JobTemplate),
+		source:      &source,
+		retry:       e.retry + 1,
+		history:     append([]RetryAttempt(nil), e.history...),
+		coordinates: e.coordinates}
+	t.recordAttempt(backoff)
+	j.tasklist = append(j.tasklist, t)
+	j.resubmitted(e, t)
+	j.publishSubmitted(t, previousJobID(e))
+	j.checkpoint()
+}
+
+// replaceTask resubmits the task in place with the job template the
+// task was created from. When the submission fails the task keeps the
+// failed job so that it is still reported as failed.
+func replaceTask(j *Job, e *task, source drmaa2interface.JobTemplate, backoff time.Duration) {
+	jt := j.expand(source, e.retry+1)
+	previous := previousJobID(e)
+	job, err := j.submit(jt)
+	e.submitError = err
+	e.retry++
+	if err != nil {
+		if e.job == nil {
+			e.template = jt
+			e.source = &source
+		}
+		e.recordAttempt(backoff)
+		j.publishSubmitError(err)
+		j.checkpoint()
+		return
 	}
+	e.job = job
+	e.template = jt
+	e.source = &source
+	e.terminated = false
+	e.waitForEndStateCollectedJobInfo = false
+	e.recordAttempt(backoff)
+	j.resubmitted(e, e)
+	j.publishSubmitted(e, previous)
+	j.checkpoint()
 }
 
-func replaceTask(j *Job, e *task) {
-	e.job, e.submitError = j.wfl.js.RunJob(e.template)
+// previousJobID returns the ID of the job of a task which is resubmitted.
+func previousJobID(t *task) string {
+	if t.job != nil {
+		return t.job.GetID()
+	}
+	if len(t.history) > 0 {
+		return t.history[len(t.history)-1].JobID
+	}
+	return ""
 }
 
 // Resubmit starts the previously submitted task n-times. All tasks are
@@ -491,7 +630,7 @@ func (j *Job) Resubmit(r int) *Job {
 	j.begin(j.ctx, fmt.Sprintf("Resubmit(%d)", r))
 	for i := 0; i < r || r == -1; i++ {
 		if t := j.lastJob(); t != nil && !t.isJobArray {
-			rerunTask(j, t)
+			rerunTask(j, t, t.sourceTemplate(), 0)
 		} else {
 			j.errorf(
 				j.ctx,
@@ -509,11 +648,11 @@ func (j *Job) AnyFailed() bool {
 	j.begin(j.ctx, "AnyFailed()")
 	for _, task := range j.tasklist {
 		if !task.isJobArray {
-			if task.job.GetState() == drmaa2interface.Failed {
+			if task.job != nil && task.job.GetState() == drmaa2interface.Failed {
 				return true
 			}
 		} else {
-			if jobArrayState(task.jobArray, false) == drmaa2interface.Failed {
+			if jobsState(j.ctx, task.arrayJobs(), false) == drmaa2interface.Failed {
 				return true
 			}
 		}
@@ -537,7 +676,7 @@ func (j *Job) RunEvery(d time.Duration, end time.Time, cmd string, args ...strin
 // RunEveryT submits a job every d time.Duration regardless if the previously
 // job is still running or finished or failed. The method only aborts and returns
 // an error if an error during job submission happened and the job could not
-// be submitted.
+// be submitted, or if the context of the job is cancelled.
 func (j *Job) RunEveryT(d time.Duration, end time.Time, jt drmaa2interface.JobTemplate) error {
 	j.begin(j.ctx, fmt.Sprintf("RunEvery(%s %s %s %s)",
 		d.String(),
@@ -545,7 +684,16 @@ func (j *Job) RunEveryT(d time.Duration, end time.Time, jt drmaa2interface.JobTe
 		jt.RemoteCommand,
 		jt.Args),
 	)
-	for range time.NewTicker(d).C {
+	ticker := time.NewTicker(d)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-j.Context().Done():
+			j.cancelled()
+			j.infof(j.ctx, "RunEveryT() context cancelled")
+			return j.lastError
+		case <-ticker.C:
+		}
 		if time.Now().After(end) {
 			j.infof(j.ctx, "RunEveryT() end time reached")
 			break
@@ -566,13 +714,18 @@ func (j *Job) RunEveryT(d time.Duration, end time.Time, jt drmaa2interface.JobTe
 }
 
 // After blocks the given duration and continues by returning the same job.
+// It returns earlier when the context of the job is cancelled.
 func (j *Job) After(d time.Duration) *Job {
 	j.infof(j.ctx, "After()")
-	<-time.After(d)
+	select {
+	case <-time.After(d):
+	case <-j.Context().Done():
+		j.cancelled()
+	}
 	return j
 }
 
-func wait(task *task, timeout time.Duration) error {
+func wait(ctx context.Context, task *task, timeout time.Duration) error {
 	if task.terminated {
 		return nil
 	}
@@ -580,7 +733,10 @@ func wait(task *task, timeout time.Duration) error {
 		if task.jobArray == nil {
 			return nil
 		}
-		task.terminationError = waitArrayJobTerminated(task.jobArray, timeout)
+		task.terminationError = waitJobsTerminated(ctx, task.arrayJobs(), timeout)
+		if task.terminationError != nil && ctx != nil && ctx.Err() != nil {
+			return ctx.Err()
+		}
 		task.terminated = true
 		// TODO cache job info
 		if task.terminationError != nil &&
@@ -589,14 +745,23 @@ func wait(task *task, timeout time.Duration) error {
 		}
 		return nil
 	}
-	task.terminationError = task.job.WaitTerminated(timeout)
+	task.terminationError = waitTerminated(ctx, task.job, timeout)
+	if task.terminationError != nil && ctx != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
 	state := task.job.GetState()
 	if state == drmaa2interface.Done ||
 		state == drmaa2interface.Failed {
 		task.terminated = true
 		// cache the jobinfo
 		task.jobinfo, task.jobinfoError = task.job.GetJobInfo()
+		if task.jobinfoError == nil && !isTerminated(task.jobinfo.State) {
+			// the job info of some backends lags behind the job
+			// state (like for terminated processes)
+			task.jobinfo.State = state
+		}
 		task.waitForEndStateCollectedJobInfo = true
+		waitForOutput(ctx, task)
 		return nil
 	}
 	return errors.New("timeout")
@@ -621,8 +786,10 @@ func (j *Job) WaitWithTimeout(timeout time.Duration) *Job {
 		if task.waitForEndStateCollectedJobInfo {
 			return j
 		}
-		err := wait(task, timeout)
-		if err != nil {
+		err := wait(j.ctx, task, timeout)
+		if err != nil && j.cancelled() {
+			j.errorf(j.ctx, "WaitWithTimeout() cancelled: %v", err)
+		} else if err != nil {
 			j.errorf(
 				j.ctx,
 				"WaitWithTimeout() has timed out",
@@ -647,7 +814,8 @@ func (j *Job) Wait() *Job {
 }
 
 // Retry waits until the last task in chain (not for the previous ones) is finished.
-// When it failed it resubmits it and waits again for a successful end.
+// When it failed it resubmits it and waits again for a successful end. The
+// resubmission follows the RetryPolicy of the job (see WithRetryPolicy()).
 func (j *Job) Retry(r int) *Job {
 	j.infof(j.ctx, "Retry()")
 	for ; r > 0; r-- {
@@ -655,8 +823,21 @@ func (j *Job) Retry(r int) *Job {
 			j.infof(j.ctx, "Retry(): Last task run successfully. No restart required.")
 			return j
 		}
+		if j.cancelled() {
+			return j
+		}
+		t := j.lastJob()
+		if t == nil || t.isJobArray {
+			j.errorf(j.ctx, "Retry(): Could not find any job in order to re-run it.")
+			j.lastError = errors.New("job not available")
+			return j
+		}
+		jt, backoff, retry := j.prepareRetry(t)
+		if !retry {
+			return j
+		}
 		j.warningf(j.ctx, "Retry(): Last task failed. Resubmitting task %s.", j.JobID())
-		j.Resubmit(1)
+		rerunTask(j, t, jt, backoff)
 	}
 	return j
 }
@@ -671,7 +852,10 @@ func (j *Job) Synchronize() *Job {
 			continue
 		}
 		j.infof(j.ctx, fmt.Sprintf("Synchronize() wait for job %s", task.job.GetID()))
-		wait(task, drmaa2interface.InfiniteTime)
+		if err := wait(j.ctx, task, drmaa2interface.InfiniteTime); err != nil && j.cancelled() {
+			j.errorf(j.ctx, "Synchronize() cancelled: %v", j.lastError)
+			return j
+		}
 	}
 	return j
 }
@@ -682,27 +866,29 @@ func (j *Job) ListAllFailed() []drmaa2interface.Job {
 	j.begin(j.ctx, "ListAllFailed()")
 	failed := make([]drmaa2interface.Job, 0, len(j.tasklist))
 	for _, task := range j.tasklist {
-		if task.job == nil {
+		if task.job == nil && task.jobArray == nil {
 			continue
 		}
-		wait(task, drmaa2interface.InfiniteTime)
-		if task.job.GetState() == drmaa2interface.Failed {
-			failed = append(failed, task.job)
+		if err := wait(j.ctx, task, drmaa2interface.InfiniteTime); err != nil && j.cancelled() {
+			return failed
+		}
+		for _, job := range task.jobs() {
+			if job.GetState() == drmaa2interface.Failed {
+				failed = append(failed, job)
+			}
 		}
 	}
 	return failed
 }
 
-// ListAll returns all tasks as slice of DRMAA2 jobs. If there
-// is no task the function returns an empty slice.
+// ListAll returns all tasks as slice of DRMAA2 jobs. Job arrays are
+// represented by their array tasks. If there is no task the function
+// returns an empty slice.
 func (j *Job) ListAll() []drmaa2interface.Job {
 	j.begin(j.ctx, "ListAll()")
 	all := make([]drmaa2interface.Job, 0, len(j.tasklist))
 	for _, task := range j.tasklist {
-		if task.job == nil {
-			continue
-		}
-		all = append(all, task.job)
+		all = append(all, task.jobs()...)
 	}
 	return all
 }
@@ -715,6 +901,9 @@ func (j *Job) ListAll() []drmaa2interface.Job {
 // or nil. The the iteration stops when all reachable tasks are processed
 // or the user defined function returns an error for one task.
 //
+// Job arrays are processed task by task. Array tasks which were resubmitted
+// by RetryAnyFailed() are represented by their replacement.
+//
 // ForEach processes all tasks of the job/flow iteratively. ForAll processes
 // all tasks of the job/flow in parallel and waits until all tasks are finished.
 //
@@ -731,13 +920,12 @@ func (j *Job) ListAll() []drmaa2interface.Job {
 func (j *Job) ForEach(f func(drmaa2interface.Job, interface{}) error, params interface{}) error {
 	j.begin(j.ctx, "ForAll()")
 	for _, task := range j.tasklist {
-		if task.job == nil {
-			continue
-		}
-		ferr := f(task.job, params)
-		if ferr != nil {
-			j.warningf(j.ctx, "ForAll(): aborting - user defined function errored: %v", ferr)
-			return ferr
+		for _, job := range task.jobs() {
+			ferr := f(job, params)
+			if ferr != nil {
+				j.warningf(j.ctx, "ForAll(): aborting - user defined function errored: %v", ferr)
+				return ferr
+			}
 		}
 	}
 	return nil
@@ -763,16 +951,12 @@ func (j *Job) ForAll(f func(drmaa2interface.Job, interface{}) error, params inte
 
 	// wait for all Goroutines to finish
 	wg := sync.WaitGroup{}
-	wg.Add(len(j.tasklist))
 
-	for _, task := range j.tasklist {
-		if task.job == nil {
-			wg.Done()
-			continue
-		}
-		localTask := task
+	for _, job := range j.ListAll() {
+		wg.Add(1)
+		localJob := job
 		go func() {
-			ferr := f(localTask.job, params)
+			ferr := f(localJob, params)
 			if ferr != nil {
 				j.warningf(j.ctx, "ForAll(): aborting - user defined function errored: %v", ferr)
 			}
@@ -792,30 +976,66 @@ func (j *Job) HasAnyFailed() bool {
 }
 
 // RetryAnyFailed reruns any failed tasks and replaces them
-// with a new task incarnation.
+// with a new task incarnation. The resubmission follows the
+// RetryPolicy of the job (see WithRetryPolicy()).
 func (j *Job) RetryAnyFailed(amount int) *Job {
 	j.begin(j.ctx, fmt.Sprintf("RetryAnyFailed(%d)", amount))
 	for i := 0; i < amount || amount == -1; i++ {
+		resubmitted := false
 		for _, task := range j.tasklist {
-			wait(task, drmaa2interface.InfiniteTime)
-			if task.job != nil && task.job.GetState() == drmaa2interface.Failed {
-				failedJobID := task.job.GetID()
-				replaceTask(j, task)
-				j.warningf(j.ctx,
-					"RetryAnyFailed(%d)): Task %s failed. Retry task (%s).",
-					amount, failedJobID, task.job.GetID())
+			if err := wait(j.ctx, task, drmaa2interface.InfiniteTime); err != nil && j.cancelled() {
+				return j
+			}
+			if task.jobArray == nil && task.failed() {
+				jt, backoff, retry := j.prepareRetry(task)
+				if j.cancelled() {
+					return j
+				}
+				if retry {
+					failed := task.history[len(task.history)-1]
+					replaceTask(j, task, jt, backoff)
+					resubmitted = true
+					if task.submitError == nil {
+						j.warningf(j.ctx,
+							"RetryAnyFailed(%d)): Task %s failed. Retry task (%s).",
+							amount, failed.JobID, task.job.GetID())
+					} else {
+						j.warningf(j.ctx,
+							"RetryAnyFailed(%d)): Task %s failed. Retry failed: %v",
+							amount, failed.JobID, task.submitError)
+					}
+				}
 			}
 			if task.jobArray != nil {
-				for _, job := range task.jobArray.GetJobs() {
-					if job.GetState() == drmaa2interface.Failed {
+				for _, member := range task.arrayMembers() {
+					if !member.failed() {
+						continue
+					}
+					jt, backoff, retry := j.prepareRetry(member)
+					if j.cancelled() {
+						return j
+					}
+					if !retry {
+						continue
+					}
+					failedJobID := member.job.GetID()
+					replaceTask(j, member, jt, backoff)
+					task.terminated = false
+					task.waitForEndStateCollectedJobInfo = false
+					resubmitted = true
+					if member.submitError == nil {
 						j.warningf(j.ctx,
-							"cannot retry failed job array task %s\n",
-							job.GetID())
+							"RetryAnyFailed(%d)): Job array task %s failed. Retry task (%s).",
+							amount, failedJobID, member.job.GetID())
+					} else {
+						j.warningf(j.ctx,
+							"RetryAnyFailed(%d)): Job array task %s failed. Retry failed: %v",
+							amount, failedJobID, member.submitError)
 					}
 				}
 			}
 		}
-		if !j.HasAnyFailed() {
+		if !resubmitted || !j.anyTaskFailed() || j.cancelled() {
 			break
 		}
 	}
@@ -831,10 +1051,18 @@ func (j *Job) ReapAll() *Job {
 	for _, task := range j.tasklist {
 		if task.job != nil {
 			task.job.Reap()
+			j.publishReaped(task, task.job)
 		}
 		if task.jobArray != nil {
 			for _, job := range task.jobArray.GetJobs() {
 				job.Reap()
+				j.publishReaped(task, job)
+			}
+			for _, member := range task.members {
+				if member.job != nil && member.retry > 0 {
+					member.job.Reap()
+					j.publishReaped(task, member.job)
+				}
 			}
 		}
 	}
@@ -881,7 +1109,10 @@ func (j *Job) Then(f func(job drmaa2interface.Job)) *Job {
 		runtime.FuncForPC(reflect.ValueOf(f).Pointer()).Name()))
 	j.lastError = nil
 	if task := j.lastJob(); task != nil && task.job != nil {
-		task.terminationError = task.job.WaitTerminated(drmaa2interface.InfiniteTime)
+		task.terminationError = waitTerminated(j.ctx, task.job, drmaa2interface.InfiniteTime)
+		if task.terminationError != nil && j.cancelled() {
+			return j
+		}
 		task.terminated = true
 		task.jobinfo, task.jobinfoError = task.job.GetJobInfo()
 		f(task.job)
@@ -953,7 +1184,7 @@ func (j *Job) OnFailure(f func(job drmaa2interface.Job)) *Job {
 	j.begin(j.ctx, fmt.Sprintf("OnFailure(%s)",
 		runtime.FuncForPC(reflect.ValueOf(f).Pointer()).Name()),
 	)
-	if waitForJobEndAndState(j) != drmaa2interface.Done {
+	if waitForJobEndAndState(j) != drmaa2interface.Done && !j.cancelled() {
 		j.infof(j.ctx, "OnFailure(%s): Previous task failed. Executing function.",
 			runtime.FuncForPC(reflect.ValueOf(f).Pointer()).Name())
 		j.Then(f)
@@ -973,7 +1204,7 @@ func (j *Job) OnFailureRun(cmd string, args ...string) *Job {
 // different than drmaa2interface.Done.
 func (j *Job) OnFailureRunT(jt drmaa2interface.JobTemplate) *Job {
 	j.begin(j.ctx, "OnFailureRunT()")
-	if waitForJobEndAndState(j) != drmaa2interface.Done {
+	if waitForJobEndAndState(j) != drmaa2interface.Done && !j.cancelled() {
 		j.RunT(jt)
 	}
 	return j
@@ -1005,19 +1236,17 @@ func (j *Job) OnErrorPanic() *Job {
 // if jobIDs is nil. Otherwise only the output for the given job IDs
 // is returned.
 //
-// Only supported for the default session manager, docker session manager
-// and kubernetes session manager.
+// Only supported for backends which can provide the output of jobs, see
+// Context.SupportsOutput().
 func (j *Job) OutputsForJobIDs(jobIDs []string) map[string]string {
 
 	j.infof(j.ctx, "OutputsForJobIDs()")
 
-	if j.wfl.ctx.SMType != DefaultSessionManager &&
-		j.wfl.ctx.SMType != DockerSessionManager &&
-		j.wfl.ctx.SMType != KubernetesSessionManager {
+	if !j.wfl.ctx.SupportsOutput() {
 		j.errorf(j.ctx,
 			"OutputsForJobIDs(): not supported for backend %s",
 			j.wfl.ctx.SMType)
-		j.lastError = errors.New("ouput not supported for this backend")
+		j.lastError = ErrOutputNotSupported
 		return nil
 	}
 
@@ -1028,7 +1257,7 @@ func (j *Job) OutputsForJobIDs(jobIDs []string) map[string]string {
 		if jobIDs != nil && !slices.Contains(jobIDs, jobID) {
 			return nil
 		}
-		output, err := getJobOutpuForJob(j.wfl.ctx.SMType, job)
+		output, err := j.jobOutput(job)
 		if err != nil {
 			(*outputs)[jobID] = ""
 			j.errorf(j.ctx,
@@ -1053,16 +1282,17 @@ func (j *Job) OutputsForJobIDs(jobIDs []string) map[string]string {
 // overwritten. This can be achieved by having the {{.ID}} placeholder in the
 // output path (check: OutputPath: wfl.RandomFileNameInTempDir())
 //
-// Currently only supported for the default OS session manager, Docker session
-// manager and Kubernetes session manager.
+// Backends which write the output into files (like the OS process, Docker,
+// Singularity, Podman, libdrmaa, and Slurm backends) require the OutputPath
+// of the JobTemplate to be set. Kubernetes and the remote backend provide
+// the output in the JobInfo. If the backend has no way to provide the output
+// LastError() returns ErrOutputNotSupported (see Context.SupportsOutput()).
 func (j *Job) Output() string {
 	j.infof(j.ctx, "Output()")
 
-	if j.wfl.ctx.SMType != DefaultSessionManager &&
-		j.wfl.ctx.SMType != DockerSessionManager &&
-		j.wfl.ctx.SMType != KubernetesSessionManager {
+	if !j.wfl.ctx.SupportsOutput() {
 		j.errorf(j.ctx, "Output(): not supported for backend %s", j.wfl.ctx.SMType)
-		j.lastError = errors.New("ouput not supported for this backend")
+		j.lastError = ErrOutputNotSupported
 		return ""
 	}
 
@@ -1073,7 +1303,7 @@ func (j *Job) Output() string {
 		return ""
 	}
 
-	output, err := getJobOutpuForJob(j.wfl.ctx.SMType, task.job)
+	output, err := j.jobOutput(task.job)
 	if err != nil {
 		j.errorf(j.ctx, "Output(): %s", err)
 		j.lastError = err
@@ -1083,15 +1313,18 @@ func (j *Job) Output() string {
 	return output
 }
 
+// OutputError returns the error output (stderr) of the last task if it is
+// in an end state. Like Output() it waits until the task is finished. For
+// backends which write into files it is read from the file specified in
+// JobTemplate.ErrorPath. For Kubernetes the pod log is returned which
+// contains stdout and stderr.
 func (j *Job) OutputError() string {
 	j.infof(j.ctx, "OutputError()")
 
-	if j.wfl.ctx.SMType != DefaultSessionManager &&
-		j.wfl.ctx.SMType != DockerSessionManager &&
-		j.wfl.ctx.SMType != KubernetesSessionManager {
+	if !j.wfl.ctx.SupportsOutput() {
 		j.errorf(j.ctx, "OutputError(): not supported for backend %s",
 			j.wfl.ctx.SMType)
-		j.lastError = errors.New("ouput not supported for this backend")
+		j.lastError = ErrOutputNotSupported
 		return ""
 	}
 
@@ -1102,7 +1335,7 @@ func (j *Job) OutputError() string {
 		return ""
 	}
 
-	output, err := getJobOutpuForJob(j.wfl.ctx.SMType, task.job)
+	output, err := j.jobErrorOutput(task.job)
 	if err != nil {
 		j.errorf(j.ctx, "OutputError(): %s", err)
 		j.lastError = err
@@ -1111,3 +1344,42 @@ func (j *Job) OutputError() string {
 
 	return output
 }
+
+// TaskOutput contains the output and error output of a task.
+type TaskOutput struct {
+	JobID  string
+	Stdout string
+	Stderr string
+	// StdoutError is set when the output could not be retrieved.
+	StdoutError error
+	// StderrError is set when the error output could not be retrieved.
+	StderrError error
+}
+
+// Outputs waits until all tasks are finished and returns the output
+// and error output of each task in submission order. Job arrays are
+// represented by their array tasks. See Output() and OutputError()
+// for the requirements of the backends.
+func (j *Job) Outputs() []TaskOutput {
+	j.infof(j.ctx, "Outputs()")
+
+	if !j.wfl.ctx.SupportsOutput() {
+		j.errorf(j.ctx, "Outputs(): not supported for backend %s",
+			j.wfl.ctx.SMType)
+		j.lastError = ErrOutputNotSupported
+		return nil
+	}
+
+	jobs := j.ListAll()
+	outputs := make([]TaskOutput, 0, len(jobs))
+	for _, job := range jobs {
+		output := TaskOutput{JobID: job.GetID()}
+		output.Stdout, output.StdoutError = j.jobOutput(job)
+		output.Stderr, output.StderrError = j.jobErrorOutput(job)
+		if j.cancelled() {
+			return outputs
+		}
+		outputs = append(outputs, output)
+	}
+	return outputs
+}
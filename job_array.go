@@ -0,0 +1,118 @@
+package wfl
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/dgruber/drmaa2interface"
+	"github.com/mitchellh/copystructure"
+)
+
+// arrayTaskIDEnv is the environment variable which contains the
+// task ID of a job array task.
+const arrayTaskIDEnv = "TASK_ID"
+
+// arrayJobs returns the jobs of a job array task. Failed array tasks
+// which were resubmitted are represented by their replacement jobs.
+func (t *task) arrayJobs() []drmaa2interface.Job {
+	if t.jobArray == nil {
+		return nil
+	}
+	if t.members == nil {
+		return t.jobArray.GetJobs()
+	}
+	jobs := make([]drmaa2interface.Job, 0, len(t.members))
+	for _, member := range t.members {
+		if member.job != nil {
+			jobs = append(jobs, member.job)
+		}
+	}
+	return jobs
+}
+
+// arrayMembers returns the tasks of a job array as separate tasks
+// so that they can be resubmitted individually. Each member keeps
+// the job template of the array with its task ID set in the job
+// environment.
+func (t *task) arrayMembers() []*task {
+	if t.jobArray == nil || t.members != nil {
+		return t.members
+	}
+	jobs := t.jobArray.GetJobs()
+	t.members = make([]*task, 0, len(jobs))
+	for i, job := range jobs {
+		jt := t.template
+		if jtCopy, err := copystructure.Copy(t.template); err == nil {
+			jt = jtCopy.(drmaa2interface.JobTemplate)
+		}
+		if jt.JobEnvironment == nil {
+			jt.JobEnvironment = make(map[string]string, 1)
+		}
+		jt.JobEnvironment[arrayTaskIDEnv] = t.arrayTaskID(i, job)
+		member := &task{job: job, template: jt}
+		if t.source != nil {
+			source := *t.source
+			if sourceCopy, err := copystructure.Copy(source); err == nil {
+				source = sourceCopy.(drmaa2interface.JobTemplate)
+			}
+			if source.JobEnvironment == nil {
+				source.JobEnvironment = make(map[string]string, 1)
+			}
+			source.JobEnvironment[arrayTaskIDEnv] = jt.JobEnvironment[arrayTaskIDEnv]
+			member.source = &source
+		}
+		member.recordAttempt(0)
+		if len(t.history) > 0 {
+			member.history[0].SubmissionTime = t.history[0].SubmissionTime
+		}
+		t.members = append(t.members, member)
+	}
+	return t.members
+}
+
+// arrayTaskID returns the task ID of the i-th job of the job array.
+// It is taken from the job environment if the backend sets it there,
+// from the job ID when it has the form <arrayjobid>.<taskid>, or
+// calculated from the array definition.
+func (t *task) arrayTaskID(i int, job drmaa2interface.Job) string {
+	if jt, err := job.GetJobTemplate(); err == nil && jt.JobEnvironment != nil {
+		if id, exists := jt.JobEnvironment[arrayTaskIDEnv]; exists {
+			return id
+		}
+	}
+	if idx := strings.LastIndex(job.GetID(), "."); idx >= 0 {
+		if _, err := strconv.Atoi(job.GetID()[idx+1:]); err == nil {
+			return job.GetID()[idx+1:]
+		}
+	}
+	step := t.arrayStep
+	if step <= 0 {
+		step = 1
+	}
+	return strconv.Itoa(t.arrayBegin + i*step)
+}
+
+func newArrayTask(jobArray drmaa2interface.ArrayJob, err error, jt drmaa2interface.JobTemplate, begin, step int) *task {
+	t := &task{
+		jobArray:    jobArray,
+		isJobArray:  true,
+		submitError: err,
+		template:    jt,
+		arrayBegin:  begin,
+		arrayStep:   step,
+	}
+	t.recordAttempt(0)
+	return t
+}
+
+// jobs returns the DRMAA2 jobs of the task. For a job array these are
+// the jobs of the array tasks (or their replacements).
+func (t *task) jobs() []drmaa2interface.Job {
+	if t.jobArray != nil {
+		return t.arrayJobs()
+	}
+	if t.job != nil {
+		return []drmaa2interface.Job{t.job}
+	}
+	return nil
+}
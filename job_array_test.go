@@ -0,0 +1,126 @@
+package wfl_test
+
+import (
+	"os"
+	"sort"
+
+	"github.com/dgruber/drmaa2interface"
+	"github.com/dgruber/wfl"
+	"github.com/dgruber/wfl/pkg/context/fake"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("JobArray", func() {
+
+	var (
+		flow   *wfl.Workflow
+		tmpDir string
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "wfljobarray")
+		Ω(err).Should(BeNil())
+		flow = wfl.NewWorkflow(wfl.NewProcessContext())
+		Ω(flow.HasError()).Should(BeFalse())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(tmpDir)
+	})
+
+	// odd array tasks fail on their first run; the marker file
+	// is named after the task ID so that a retry succeeds only
+	// when the TASK_ID is preserved
+	failOddOnce := func() drmaa2interface.JobTemplate {
+		return drmaa2interface.JobTemplate{
+			RemoteCommand: "/bin/bash",
+			Args: []string{"-c",
+				`test -f "$DIR/$TASK_ID" && exit 0; touch "$DIR/$TASK_ID"; exit $((TASK_ID % 2))`},
+			JobEnvironment: map[string]string{"DIR": tmpDir},
+		}
+	}
+
+	It("should retry failed job array tasks individually", func() {
+		job := flow.NewJob().RunArrayT(1, 4, 1, 4, failOddOnce())
+		Ω(job.LastError()).Should(BeNil())
+		Ω(job.HasAnyFailed()).Should(BeTrue())
+		failed := job.ListAllFailed()
+		Ω(failed).Should(HaveLen(2))
+		failedIDs := []string{failed[0].GetID(), failed[1].GetID()}
+
+		job.RetryAnyFailed(1)
+		Ω(job.HasAnyFailed()).Should(BeFalse())
+		Ω(job.ListAllFailed()).Should(BeEmpty())
+		Ω(job.Wait().State()).Should(Equal(drmaa2interface.Done))
+
+		all := job.ListAll()
+		Ω(all).Should(HaveLen(4))
+		ids := []string{}
+		for _, j := range all {
+			ids = append(ids, j.GetID())
+		}
+		Ω(ids).ShouldNot(ContainElements(failedIDs))
+
+		var visited []string
+		err := job.ForEach(func(j drmaa2interface.Job, i interface{}) error {
+			*(i.(*[]string)) = append(*(i.(*[]string)), j.GetID())
+			return nil
+		}, &visited)
+		Ω(err).Should(BeNil())
+		Ω(visited).Should(Equal(ids))
+
+		for _, id := range failedIDs {
+			history := job.RetryHistory(id)
+			Ω(history).Should(HaveLen(2))
+			Ω(history[0].State).Should(Equal(drmaa2interface.Failed))
+			Ω(history[1].State).Should(Equal(drmaa2interface.Done))
+		}
+
+		markers, err := os.ReadDir(tmpDir)
+		Ω(err).Should(BeNil())
+		names := []string{}
+		for _, m := range markers {
+			names = append(names, m.Name())
+		}
+		sort.Strings(names)
+		Ω(names).Should(Equal([]string{"1", "2", "3", "4"}))
+	})
+
+	It("should respect the retry policy for job array tasks", func() {
+		job := flow.NewJob().WithRetryPolicy(wfl.RetryPolicy{
+			RetryOnExitCodes: []int{2},
+		}).RunArrayT(1, 4, 1, 4, failOddOnce())
+		job.RetryAnyFailed(1)
+		Ω(job.ListAllFailed()).Should(HaveLen(2))
+	})
+
+	It("should apply the default template to job arrays", func() {
+		sm := fake.NewSessionManager()
+		ctx := fake.NewFakeContextByCfg(fake.Config{
+			SessionManager: sm,
+			DefaultTemplate: drmaa2interface.JobTemplate{
+				QueueName:      "batch.q",
+				JobEnvironment: map[string]string{"MODE": "test"},
+			},
+		})
+		job := wfl.NewWorkflow(ctx).NewJob().
+			RunArray(1, 2, 1, 2, "echo", "a").
+			RunArrayT(1, 1, 1, 1, drmaa2interface.JobTemplate{
+				RemoteCommand: "echo",
+				QueueName:     "short.q",
+			})
+		Ω(job.LastError()).Should(BeNil())
+		job.Synchronize()
+
+		submitted := sm.Submitted()
+		Ω(submitted).Should(HaveLen(3))
+		Ω(submitted[0].QueueName).Should(Equal("batch.q"))
+		Ω(submitted[1].JobEnvironment["MODE"]).Should(Equal("test"))
+		Ω(submitted[2].QueueName).Should(Equal("short.q"))
+		Ω(submitted[2].JobEnvironment["MODE"]).Should(Equal("test"))
+	})
+
+})
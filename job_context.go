@@ -0,0 +1,137 @@
+package wfl
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/dgruber/drmaa2interface"
+)
+
+// contextPollInterval defines how often a cancellable wait checks
+// the context while waiting for the end of a task.
+const contextPollInterval = 100 * time.Millisecond
+
+// WithContext binds the job to the given context. When the context is
+// cancelled or its deadline is exceeded, blocking methods like Wait(),
+// Synchronize(), OnSuccess(), RetryAnyFailed(), or RunEveryT() return
+// early and LastError() returns ctx.Err(). Tasks keep running unless
+// TerminateOnCancel() was called.
+//
+// Example:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+//	defer cancel()
+//	job := flow.NewJob().WithContext(ctx).TerminateOnCancel().
+//		Run("sleep", "3600").Wait()
+//	if errors.Is(job.LastError(), context.DeadlineExceeded) { ... }
+func (j *Job) WithContext(ctx context.Context) *Job {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	j.ctx = ctx
+	return j
+}
+
+// Context returns the context the job is bound to.
+func (j *Job) Context() context.Context {
+	if j.ctx == nil {
+		return context.Background()
+	}
+	return j.ctx
+}
+
+// TerminateOnCancel terminates all unfinished tasks of the job when
+// a blocking method detects that the context of the job is cancelled.
+func (j *Job) TerminateOnCancel() *Job {
+	j.terminateOnCancel = true
+	return j
+}
+
+// cancelled returns true when the context of the job is cancelled
+// or its deadline is exceeded. The context error is stored as last
+// error and unfinished tasks are terminated if requested.
+func (j *Job) cancelled() bool {
+	if j.ctx == nil || j.ctx.Err() == nil {
+		return false
+	}
+	j.lastError = j.ctx.Err()
+	if j.terminateOnCancel {
+		j.terminateUnfinished()
+	}
+	return true
+}
+
+func (j *Job) terminateUnfinished() {
+	for _, task := range j.tasklist {
+		if task.terminated {
+			continue
+		}
+		if task.job != nil && !isTerminated(task.job.GetState()) {
+			j.warningf(j.ctx, "context cancelled: terminating task %s", task.job.GetID())
+			if err := task.job.Terminate(); err != nil {
+				j.errorf(j.ctx, "terminating task %s failed: %v", task.job.GetID(), err)
+			}
+		}
+		if task.jobArray != nil {
+			j.warningf(j.ctx, "context cancelled: terminating job array %s", task.jobArray.GetID())
+			if err := task.jobArray.Terminate(); err != nil {
+				j.errorf(j.ctx, "terminating job array %s failed: %v", task.jobArray.GetID(), err)
+			}
+		}
+	}
+}
+
+func isTerminated(state drmaa2interface.JobState) bool {
+	return state == drmaa2interface.Done || state == drmaa2interface.Failed
+}
+
+// waitTerminated waits until the job is terminated, the timeout is
+// reached, or the context is done. Contexts which can't be cancelled
+// result in a plain WaitTerminated() call.
+func waitTerminated(ctx context.Context, job drmaa2interface.Job, timeout time.Duration) error {
+	if ctx == nil || ctx.Done() == nil {
+		return job.WaitTerminated(timeout)
+	}
+	var deadline time.Time
+	if timeout != drmaa2interface.InfiniteTime {
+		deadline = time.Now().Add(timeout)
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		interval := contextPollInterval
+		if !deadline.IsZero() {
+			remaining := time.Until(deadline)
+			if remaining <= 0 {
+				return errors.New("timeout while waiting for job state")
+			}
+			if remaining < interval {
+				interval = remaining
+			}
+		}
+		err := job.WaitTerminated(interval)
+		if err == nil || isTerminated(job.GetState()) {
+			return nil
+		}
+		if !isTimeoutError(err) {
+			return err
+		}
+	}
+}
+
+// errTrackerTimeout is the message of the error which the job trackers
+// waiting with the drmaa2os helper.WaitForState() (like the Docker,
+// Kubernetes, Slurm, and remote tracker) return on timeout. It is not
+// a drmaa2interface.Error and not exported.
+const errTrackerTimeout = "timeout while waiting for job state"
+
+// isTimeoutError returns true if waiting for the job timed out.
+func isTimeoutError(err error) bool {
+	var d2err drmaa2interface.Error
+	if errors.As(err, &d2err) {
+		return d2err.ID == drmaa2interface.Timeout
+	}
+	return err.Error() == errTrackerTimeout
+}
@@ -0,0 +1,86 @@
+package wfl_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/dgruber/drmaa2interface"
+	"github.com/dgruber/wfl"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("JobContext", func() {
+
+	var flow *wfl.Workflow
+
+	BeforeEach(func() {
+		flow = wfl.NewWorkflow(wfl.NewProcessContext())
+		Ω(flow.HasError()).Should(BeFalse())
+	})
+
+	It("should abort Wait() when the deadline is exceeded", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		job := flow.NewJob().WithContext(ctx).Run("sleep", "10").Wait()
+		Ω(time.Since(start)).Should(BeNumerically("<", 3*time.Second))
+		Ω(errors.Is(job.LastError(), context.DeadlineExceeded)).Should(BeTrue())
+		job.Kill()
+	})
+
+	It("should terminate running tasks on cancel when requested", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		job := flow.NewJob().WithContext(ctx).TerminateOnCancel().Run("sleep", "10")
+		go func() {
+			time.Sleep(200 * time.Millisecond)
+			cancel()
+		}()
+		job.Wait()
+		Ω(errors.Is(job.LastError(), context.Canceled)).Should(BeTrue())
+		Eventually(func() drmaa2interface.JobState {
+			return job.State()
+		}, 5*time.Second).Should(Equal(drmaa2interface.Failed))
+	})
+
+	It("should abort Synchronize() and not call OnFailure() on cancel", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+		defer cancel()
+		called := false
+		job := flow.NewJob().WithContext(ctx).TerminateOnCancel().
+			Run("sleep", "10").Run("sleep", "10").Synchronize().
+			OnFailure(func(e drmaa2interface.Job) { called = true })
+		Ω(errors.Is(job.LastError(), context.DeadlineExceeded)).Should(BeTrue())
+		Ω(called).Should(BeFalse())
+	})
+
+	It("should stop RunEveryT() when the context is cancelled", func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+		defer cancel()
+		err := flow.NewJob().WithContext(ctx).RunEveryT(100*time.Millisecond, time.Now().Add(time.Hour),
+			drmaa2interface.JobTemplate{RemoteCommand: "sleep", Args: []string{"0"}})
+		Ω(errors.Is(err, context.DeadlineExceeded)).Should(BeTrue())
+	})
+
+	It("should return early from After() when the context is cancelled", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		start := time.Now()
+		flow.NewJob().WithContext(ctx).After(10 * time.Second)
+		Ω(time.Since(start)).Should(BeNumerically("<", time.Second))
+	})
+
+	It("should skip DAG tasks when the context is cancelled", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		dag := flow.NewDAG().WithContext(ctx)
+		dag.Task("a", "sleep", "0")
+		dag.Task("b", "sleep", "0").DependsOn("a")
+		dag.Run().Synchronize()
+		Ω(dag.State("a")).Should(Equal(wfl.DAGTaskSkipped))
+		Ω(dag.State("b")).Should(Equal(wfl.DAGTaskSkipped))
+	})
+
+})
@@ -0,0 +1,34 @@
+package wfl
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dgruber/drmaa2interface"
+	g "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = g.Describe("Timeout errors", func() {
+
+	g.It("should detect the timeout errors of the job trackers", func() {
+		Ω(isTimeoutError(drmaa2interface.Error{
+			ID:      drmaa2interface.Timeout,
+			Message: "Timeout occurred while waiting for job state",
+		})).Should(BeTrue())
+		Ω(isTimeoutError(fmt.Errorf("wait: %w", drmaa2interface.Error{
+			ID: drmaa2interface.Timeout,
+		}))).Should(BeTrue())
+		Ω(isTimeoutError(errors.New("timeout while waiting for job state"))).Should(BeTrue())
+	})
+
+	g.It("should not treat other errors mentioning a timeout as timeout", func() {
+		Ω(isTimeoutError(drmaa2interface.Error{
+			ID:      drmaa2interface.Internal,
+			Message: "connection timeout",
+		})).Should(BeFalse())
+		Ω(isTimeoutError(errors.New("dial tcp: i/o timeout"))).Should(BeFalse())
+		Ω(isTimeoutError(errors.New("job state is TIMEOUT"))).Should(BeFalse())
+	})
+
+})
@@ -1,96 +1,168 @@
 package wfl
 
 import (
-	"bytes"
 	"context"
 	"errors"
 	"fmt"
-	"text/template"
+	"strings"
 	"time"
 
 	"github.com/dgruber/drmaa2interface"
+	"github.com/dgruber/wfl/pkg/log"
 	"github.com/dgruber/wfl/pkg/matrix"
 	"github.com/mitchellh/copystructure"
 )
 
-func replaceID(input string, id int64) string {
-	type Input struct {
-		ID int64
-	}
-	tmpl, err := template.New("replace").Parse(input)
-	if err != nil {
-		return input
-	}
-	out := bytes.NewBufferString("")
-	err = tmpl.Execute(out, Input{ID: id})
-	if err != nil {
-		return input
-	}
-	return out.String()
-}
-
-func replaceContextID(input string, ctx *Context) string {
-	return replaceID(input, ctx.ContextTaskID)
-}
-
-func replaceNextContextID(input string, ctx *Context) string {
-	return replaceID(input, ctx.GetNextContextTaskID())
-}
-
-// mergeJobTemplateWithDefaultTemplate adds requests from _def_ into _req_
-// if specified in req
+// mergeJobTemplateWithDefaultTemplate adds the settings of _def_ which
+// are not set in _req_. The policy depends on the field:
 //
-// Note there is no "unset" convention yet for the job template. It will
-// only take actually implemented (in drmaa2os job tracker) settings into
-// account.
+//   - strings, numbers, times, Args: the default is used if the field
+//     is not set in the request (override)
+//   - bools: the field is true if it is true in the request or in the
+//     default
+//   - JobEnvironment, StageInFiles, StageOutFiles, ResourceLimits: the
+//     maps are merged; entries of the request win (merge)
+//   - Email: the addresses of the default are added (append)
+//   - CandidateMachines, ExtensionList: the default is used if the
+//     field is not set in the request (override)
+//
+// Fields listed in the UnsetExtension of the request are not taken
+// from the default. The UnsetExtension is removed from the result.
 func mergeJobTemplateWithDefaultTemplate(req, def drmaa2interface.JobTemplate) drmaa2interface.JobTemplate {
-	if req.JobCategory == "" {
-		req.JobCategory = def.JobCategory
-	}
-	if req.InputPath == "" {
-		req.InputPath = def.InputPath
+	if c, err := copystructure.Copy(req); err == nil {
+		req = c.(drmaa2interface.JobTemplate)
 	}
-	if req.OutputPath == "" {
-		req.OutputPath = def.OutputPath
+	unset := unsetFields(&req)
+	use := func(field JobTemplateField) bool {
+		return !unset[field]
 	}
-	if req.ErrorPath == "" {
-		req.ErrorPath = def.ErrorPath
+	mergeString := func(field JobTemplateField, dst *string, src string) {
+		if *dst == "" && use(field) {
+			*dst = src
+		}
 	}
-	if req.AccountingID == "" {
-		req.AccountingID = def.AccountingID
+	mergeInt := func(field JobTemplateField, dst *int64, src int64) {
+		if *dst == 0 && use(field) {
+			*dst = src
+		}
 	}
-	if req.JobName == "" {
-		req.JobName = def.JobName
+	mergeTime := func(field JobTemplateField, dst *time.Time, src time.Time) {
+		if dst.IsZero() && use(field) {
+			*dst = src
+		}
 	}
-	if req.WorkingDirectory == "" {
-		req.WorkingDirectory = def.WorkingDirectory
+	mergeBool := func(field JobTemplateField, dst *bool, src bool) {
+		if src && use(field) {
+			*dst = true
+		}
 	}
+
+	mergeString(RemoteCommand, &req.RemoteCommand, def.RemoteCommand)
+	if req.Args == nil && def.Args != nil && use(Args) {
+		req.Args = append([]string{}, def.Args...)
+	}
+	mergeString(JobCategory, &req.JobCategory, def.JobCategory)
+	mergeString(InputPath, &req.InputPath, def.InputPath)
+	mergeString(OutputPath, &req.OutputPath, def.OutputPath)
+	mergeString(ErrorPath, &req.ErrorPath, def.ErrorPath)
+	mergeString(AccountingID, &req.AccountingID, def.AccountingID)
+	mergeString(JobName, &req.JobName, def.JobName)
+	mergeString(WorkingDirectory, &req.WorkingDirectory, def.WorkingDirectory)
+	mergeString(ReservationID, &req.ReservationID, def.ReservationID)
+	mergeString(QueueName, &req.QueueName, def.QueueName)
+	mergeString(MachineOS, &req.MachineOs, def.MachineOs)
+	mergeString(MachineArch, &req.MachineArch, def.MachineArch)
+
+	mergeInt(MinSlots, &req.MinSlots, def.MinSlots)
+	mergeInt(MaxSlots, &req.MaxSlots, def.MaxSlots)
+	mergeInt(Priority, &req.Priority, def.Priority)
+	mergeInt(MinPhysMemory, &req.MinPhysMemory, def.MinPhysMemory)
+
+	mergeTime(StartTime, &req.StartTime, def.StartTime)
+	mergeTime(DeadlineTime, &req.DeadlineTime, def.DeadlineTime)
+
+	mergeBool(SubmitAsHold, &req.SubmitAsHold, def.SubmitAsHold)
+	mergeBool(ReRunnable, &req.ReRunnable, def.ReRunnable)
+	mergeBool(EmailOnStarted, &req.EmailOnStarted, def.EmailOnStarted)
+	mergeBool(EmailOnTerminated, &req.EmailOnTerminated, def.EmailOnTerminated)
+	mergeBool(JoinFiles, &req.JoinFiles, def.JoinFiles)
+
 	// replaces destination machines
-	if req.CandidateMachines == nil && def.CandidateMachines != nil {
-		if cm, err := copystructure.Copy(def.CandidateMachines); err == nil {
-			req.CandidateMachines = cm.([]string)
-		}
+	if req.CandidateMachines == nil && def.CandidateMachines != nil && use(CandidateMachines) {
+		req.CandidateMachines = append([]string{}, def.CandidateMachines...)
 	}
 	// replace extensions
-	if req.ExtensionList == nil && def.ExtensionList != nil {
+	if req.ExtensionList == nil && def.ExtensionList != nil && use(ExtensionList) {
 		if el, err := copystructure.Copy(def.ExtensionList); err == nil {
 			req.ExtensionList = el.(map[string]string)
+			delete(req.ExtensionList, UnsetExtension)
 		}
 	}
-	// join files to stage
-	req.StageInFiles = mergeStringMap(req.StageInFiles, def.StageInFiles)
-	// join environment variables
-	req.JobEnvironment = mergeStringMap(req.JobEnvironment, def.JobEnvironment)
-	if def.MinSlots > 0 && req.MinSlots == 0 {
-		req.MinSlots = def.MinSlots
+	// add mail recipients
+	if use(Email) {
+		for _, address := range def.Email {
+			if !containsString(req.Email, address) {
+				req.Email = append(req.Email, address)
+			}
+		}
+	}
+	if use(StageInFiles) {
+		// join files to stage
+		req.StageInFiles = mergeStringMap(req.StageInFiles, def.StageInFiles)
+	}
+	if use(StageOutFiles) {
+		req.StageOutFiles = mergeStringMap(req.StageOutFiles, def.StageOutFiles)
+	}
+	if use(JobEnvironment) {
+		// join environment variables
+		req.JobEnvironment = mergeStringMap(req.JobEnvironment, def.JobEnvironment)
 	}
-	if def.MaxSlots > 0 && req.MaxSlots == 0 {
-		req.MaxSlots = def.MaxSlots
+	if use(ResourceLimits) {
+		req.ResourceLimits = mergeStringMap(req.ResourceLimits, def.ResourceLimits)
 	}
-	// TODO implement more when required
 	return req
 }
 
+// withDefaults applies the DefaultTemplate and the DefaultDockerImage
+// of the context to the job template.
+func (j *Job) withDefaults(jt drmaa2interface.JobTemplate) drmaa2interface.JobTemplate {
+	jt = mergeJobTemplateWithDefaultTemplate(jt, j.wfl.ctx.DefaultTemplate)
+	// JobCategory overrides all at the moment...
+	if jt.JobCategory == "" {
+		jt.JobCategory = j.wfl.ctx.DefaultDockerImage
+	}
+	return jt
+}
+
+// unsetFields removes the UnsetExtension from the job template and
+// returns the fields it lists.
+func unsetFields(jt *drmaa2interface.JobTemplate) map[JobTemplateField]bool {
+	unset := map[JobTemplateField]bool{}
+	list, exists := jt.ExtensionList[UnsetExtension]
+	if !exists {
+		return unset
+	}
+	delete(jt.ExtensionList, UnsetExtension)
+	if len(jt.ExtensionList) == 0 {
+		jt.ExtensionList = nil
+	}
+	for _, field := range strings.Split(list, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			unset[JobTemplateField(field)] = true
+		}
+	}
+	return unset
+}
+
+func containsString(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
 func mergeStringMap(dst, src map[string]string) map[string]string {
 	if src != nil {
 		if dst == nil {
@@ -109,29 +181,36 @@ func waitForJobEndAndState(j *Job) drmaa2interface.JobState {
 	if j == nil {
 		return drmaa2interface.Undetermined
 	}
-	job, jobArray, err := j.jobCheck()
+	job, _, err := j.jobCheck()
 	if err != nil {
 		return drmaa2interface.Undetermined
 	}
 	if job != nil {
-		lastError := job.WaitTerminated(drmaa2interface.InfiniteTime)
+		lastError := waitTerminated(j.ctx, job, drmaa2interface.InfiniteTime)
 		if lastError != nil {
+			j.cancelled()
 			return drmaa2interface.Undetermined
 		}
 		return job.GetState()
 	}
-	return jobArrayState(jobArray, true)
+	state := jobsState(j.ctx, j.lastJob().arrayJobs(), true)
+	j.cancelled()
+	return state
 }
 
-func jobArrayState(jobArray drmaa2interface.ArrayJob, wait bool) drmaa2interface.JobState {
+func jobArrayState(ctx context.Context, jobArray drmaa2interface.ArrayJob, wait bool) drmaa2interface.JobState {
+	return jobsState(ctx, jobArray.GetJobs(), wait)
+}
+
+func jobsState(ctx context.Context, jobs []drmaa2interface.Job, wait bool) drmaa2interface.JobState {
 	// it is a job array - waiting for each single task
 	// if one of the tasks failed - the whole job array failed
 	// if one of the tasks is undetermined and the rest is done, the array
 	// is undetermined.
 	jobArrayState := drmaa2interface.Done
-	for _, job := range jobArray.GetJobs() {
+	for _, job := range jobs {
 		if wait {
-			lastError := job.WaitTerminated(drmaa2interface.InfiniteTime)
+			lastError := waitTerminated(ctx, job, drmaa2interface.InfiniteTime)
 			if lastError != nil {
 				return drmaa2interface.Undetermined
 			}
@@ -154,12 +233,20 @@ func jobArrayState(jobArray drmaa2interface.ArrayJob, wait bool) drmaa2interface
 
 // waitArrayJobTerminated waits for all jobs in the array to be terminated
 // drmaa2interface.InfiniteTime can be used for waiting forever
-func waitArrayJobTerminated(jobArray drmaa2interface.ArrayJob, timeout time.Duration) error {
+func waitArrayJobTerminated(ctx context.Context, jobArray drmaa2interface.ArrayJob, timeout time.Duration) error {
+	return waitJobsTerminated(ctx, jobArray.GetJobs(), timeout)
+}
+
+// waitJobsTerminated waits for all given jobs to be terminated
+func waitJobsTerminated(ctx context.Context, jobs []drmaa2interface.Job, timeout time.Duration) error {
 	var lastErr error
 	start := time.Now()
-	for _, job := range jobArray.GetJobs() {
-		err := job.WaitTerminated(timeout)
+	for _, job := range jobs {
+		err := waitTerminated(ctx, job, timeout)
 		if err != nil {
+			if ctx != nil && ctx.Err() != nil {
+				return err
+			}
 			lastErr = err
 		}
 		if timeout != drmaa2interface.InfiniteTime {
@@ -205,31 +292,70 @@ func (j *Job) begin(ctx context.Context, f string) {
 	if j == nil || j.wfl == nil || j.wfl.log == nil {
 		return
 	}
-	j.wfl.log.Begin(ctx, f)
+	log.Structured(j.wfl.log).BeginWith(ctx, f, j.logFields()...)
 }
 
 func (j *Job) infof(ctx context.Context, s string, args ...interface{}) {
 	if j == nil || j.wfl == nil || j.wfl.log == nil {
 		return
 	}
-	j.wfl.log.Infof(ctx, s, args...)
+	log.Structured(j.wfl.log).Info(ctx, fmt.Sprintf(s, args...), j.logFields()...)
 }
 func (j *Job) warningf(ctx context.Context, s string, args ...interface{}) {
 	if j == nil || j.wfl == nil || j.wfl.log == nil {
 		return
 	}
-	j.wfl.log.Warningf(ctx, s, args...)
+	log.Structured(j.wfl.log).Warning(ctx, fmt.Sprintf(s, args...), j.logFields()...)
 }
 
 func (j *Job) errorf(ctx context.Context, s string, args ...interface{}) {
 	if j == nil || j.wfl == nil || j.wfl.log == nil {
 		return
 	}
-	j.wfl.log.Errorf(ctx, s, args...)
+	log.Structured(j.wfl.log).Error(ctx, fmt.Sprintf(s, args...), j.logFields()...)
+}
+
+// logFields returns the fields of the log messages of the job: the job
+// session, the tag, and the ID, command, and attempt of the last task.
+func (j *Job) logFields() []log.Field {
+	var fields []log.Field
+	if j.wfl.ctx != nil && j.wfl.ctx.JobSessionName != "" {
+		fields = append(fields, log.F(log.SessionKey, j.wfl.ctx.JobSessionName))
+	}
+	if j.tag != "" {
+		fields = append(fields, log.F(log.TagKey, j.tag))
+	}
+	t := j.lastJob()
+	if t == nil {
+		return fields
+	}
+	if t.job != nil {
+		fields = append(fields, log.F(log.JobIDKey, t.job.GetID()))
+	} else if t.jobArray != nil {
+		fields = append(fields, log.F(log.JobIDKey, t.jobArray.GetID()))
+	}
+	if t.template.RemoteCommand != "" {
+		fields = append(fields, log.F(log.CommandKey, t.template.RemoteCommand))
+	}
+	return append(fields, log.F(log.AttemptKey, t.retry+1))
 }
 
 func getJobTemplatesForMatrix(jt drmaa2interface.JobTemplate, x, y Replacement) ([]drmaa2interface.JobTemplate, error) {
-	finalJobTemplates := make([]drmaa2interface.JobTemplate, 0)
+	points, err := getMatrixPointsForXY(jt, x, y)
+	if err != nil || points == nil {
+		return nil, err
+	}
+	jobTemplates := make([]drmaa2interface.JobTemplate, 0, len(points))
+	for _, point := range points {
+		jobTemplates = append(jobTemplates, point.template)
+	}
+	return jobTemplates, nil
+}
+
+// getMatrixPointsForXY returns the job templates for all combinations
+// of x and y together with the replacement values used.
+func getMatrixPointsForXY(jt drmaa2interface.JobTemplate, x, y Replacement) ([]matrixPoint, error) {
+	points := make([]matrixPoint, 0)
 	lx := len(x.Replacements) - 1
 	ly := len(y.Replacements) - 1
 	if (lx == -1) && (ly == -1) {
@@ -246,12 +372,15 @@ func getJobTemplatesForMatrix(jt drmaa2interface.JobTemplate, x, y Replacement)
 	for {
 		var replacementX string
 		var replacementY string
+		values := map[string]string{}
 
 		if position[0] < len(x.Replacements) {
 			replacementX = x.Replacements[position[0]]
+			values[x.Pattern] = replacementX
 		}
 		if position[1] < len(y.Replacements) {
 			replacementY = y.Replacements[position[1]]
+			values[y.Pattern] = replacementY
 		}
 
 		newJT, err := matrix.CopyJobTemplate(jt)
@@ -260,6 +389,12 @@ func getJobTemplatesForMatrix(jt drmaa2interface.JobTemplate, x, y Replacement)
 		}
 		var errRepl error
 
+		if replacementX, errRepl = replacementValue(x, values); errRepl != nil {
+			return nil, errRepl
+		}
+		if replacementY, errRepl = replacementValue(y, values); errRepl != nil {
+			return nil, errRepl
+		}
 		for _, field := range x.Fields {
 			newJT, errRepl = matrix.ReplaceInField(newJT, string(field), x.Pattern, replacementX)
 			if errRepl != nil {
@@ -273,7 +408,7 @@ func getJobTemplatesForMatrix(jt drmaa2interface.JobTemplate, x, y Replacement)
 			}
 		}
 		// submit new job
-		finalJobTemplates = append(finalJobTemplates, newJT)
+		points = append(points, matrixPoint{values: values, template: newJT})
 
 		position, err = matrix.GetNextValue([]int{lx, ly}, position)
 		if err != nil {
@@ -281,5 +416,5 @@ func getJobTemplatesForMatrix(jt drmaa2interface.JobTemplate, x, y Replacement)
 		}
 	}
 
-	return finalJobTemplates, nil
+	return points, nil
 }
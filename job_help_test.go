@@ -1,6 +1,9 @@
 package wfl
 
 import (
+	"context"
+	"time"
+
 	g "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
@@ -166,6 +169,60 @@ var _ = g.Describe("JobHelp", func() {
 			// candidate machines should be completely overridden
 			Ω(jt.CandidateMachines).ShouldNot(ContainElement("the Dø"))
 		})
+
+		g.It("should merge all job template fields", func() {
+			deadline := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+			def := drmaa2interface.JobTemplate{
+				QueueName:      "gpu.q",
+				Priority:       5,
+				MinPhysMemory:  1024,
+				DeadlineTime:   deadline,
+				MachineOs:      "linux",
+				JoinFiles:      true,
+				Email:          []string{"ops@example.com"},
+				StageOutFiles:  map[string]string{"/out": "/results"},
+				ResourceLimits: map[string]string{"h_rt": "3600", "h_vmem": "4G"},
+			}
+			req := drmaa2interface.JobTemplate{
+				Priority:       1,
+				Email:          []string{"me@example.com", "ops@example.com"},
+				ResourceLimits: map[string]string{"h_rt": "60"},
+			}
+			jt := mergeJobTemplateWithDefaultTemplate(req, def)
+
+			Ω(jt.QueueName).Should(Equal("gpu.q"))
+			Ω(jt.Priority).Should(BeNumerically("==", 1))
+			Ω(jt.MinPhysMemory).Should(BeNumerically("==", 1024))
+			Ω(jt.DeadlineTime).Should(Equal(deadline))
+			Ω(jt.MachineOs).Should(Equal("linux"))
+			Ω(jt.JoinFiles).Should(BeTrue())
+			Ω(jt.Email).Should(Equal([]string{"me@example.com", "ops@example.com"}))
+			Ω(jt.StageOutFiles).Should(Equal(map[string]string{"/out": "/results"}))
+			Ω(jt.ResourceLimits).Should(Equal(map[string]string{"h_rt": "60", "h_vmem": "4G"}))
+			// the request is not modified
+			Ω(req.ResourceLimits).Should(HaveLen(1))
+		})
+
+		g.It("should not apply defaults of unset fields", func() {
+			def := drmaa2interface.JobTemplate{
+				QueueName:      "gpu.q",
+				JoinFiles:      true,
+				JobEnvironment: map[string]string{"A": "B"},
+			}
+			def.ExtensionList = map[string]string{"privileged": "true"}
+			req := drmaa2interface.JobTemplate{}
+			req.ExtensionList = map[string]string{
+				UnsetExtension: "QueueName, JoinFiles,JobEnvironment",
+			}
+			jt := mergeJobTemplateWithDefaultTemplate(req, def)
+
+			Ω(jt.QueueName).Should(Equal(""))
+			Ω(jt.JoinFiles).Should(BeFalse())
+			Ω(jt.JobEnvironment).Should(BeNil())
+			// the unset key itself is no extension
+			Ω(jt.ExtensionList).Should(Equal(map[string]string{"privileged": "true"}))
+			Ω(req.ExtensionList).Should(HaveKey(UnsetExtension))
+		})
 	})
 
 	g.Context("Job related", func() {
@@ -195,28 +252,28 @@ var _ = g.Describe("JobHelp", func() {
 			task := job.lastJob()
 			Ω(task.isJobArray).Should(BeTrue())
 			Ω(task.jobArray).ShouldNot(BeNil())
-			Ω(jobArrayState(task.jobArray, true).String()).Should(Equal(drmaa2interface.Done.String()))
+			Ω(jobArrayState(context.Background(), task.jobArray, true).String()).Should(Equal(drmaa2interface.Done.String()))
 
 			// one failed ($TASK_ID is set for each job array task)
 			job.RunArray(1, 2, 1, 2, "/bin/bash", "-c", "exit $((TASK_ID - 1))")
 			task = job.lastJob()
 			Ω(task.isJobArray).Should(BeTrue())
 			Ω(task.jobArray).ShouldNot(BeNil())
-			Ω(jobArrayState(task.jobArray, true).String()).Should(Equal(drmaa2interface.Failed.String()))
+			Ω(jobArrayState(context.Background(), task.jobArray, true).String()).Should(Equal(drmaa2interface.Failed.String()))
 
 			// one done but exit code 0 / wait after submission
 			job.RunArray(1, 1, 1, 1, "/bin/bash", "-c", "exit $((TASK_ID - 1))").Wait()
 			task = job.lastJob()
 			Ω(task.isJobArray).Should(BeTrue())
 			Ω(task.jobArray).ShouldNot(BeNil())
-			Ω(jobArrayState(task.jobArray, false).String()).Should(Equal(drmaa2interface.Done.String()))
+			Ω(jobArrayState(context.Background(), task.jobArray, false).String()).Should(Equal(drmaa2interface.Done.String()))
 
 			// one failed with exit code 1 / wait after submission
 			job.RunArray(1, 2, 2, 1, "/bin/bash", "-c", "exit $((TASK_ID - 1))").Wait()
 			task = job.lastJob()
 			Ω(task.isJobArray).Should(BeTrue())
 			Ω(task.jobArray).ShouldNot(BeNil())
-			Ω(jobArrayState(task.jobArray, false).String()).Should(Equal(drmaa2interface.Done.String()))
+			Ω(jobArrayState(context.Background(), task.jobArray, false).String()).Should(Equal(drmaa2interface.Done.String()))
 
 		})
 
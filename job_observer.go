@@ -2,18 +2,24 @@ package wfl
 
 import (
 	"fmt"
-	"github.com/dgruber/drmaa2interface"
 	"os"
+	"sync"
+
+	"github.com/dgruber/drmaa2interface"
 )
 
 // Observer is a collection of functions which implements
 // behavior which should be executed when a task submission
 // failed, when the task failed, or when then the job was
-// running successfully.
+// running successfully. StartedHandler and StateChangeHandler
+// are only called by ObserveAsync(). Handlers which are not
+// set are ignored.
 type Observer struct {
-	ErrorHandler   func(error)
-	FailedHandler  func(drmaa2interface.Job)
-	SuccessHandler func(drmaa2interface.Job)
+	ErrorHandler       func(error)
+	FailedHandler      func(drmaa2interface.Job)
+	SuccessHandler     func(drmaa2interface.Job)
+	StartedHandler     func(drmaa2interface.Job)
+	StateChangeHandler func(drmaa2interface.Job, drmaa2interface.JobState)
 }
 
 // NewDefaultObserver returns an Observer which panics when
@@ -37,9 +43,153 @@ func NewDefaultObserver() Observer {
 // Observe executes the functions defined in the Observer
 // when task submission errors, the task failed, and
 // when the job finished successfully. Note that this is
-// a blocking call.
+// a blocking call which only observes the last task. See
+// ObserveAsync() for observing all tasks of the job.
 func (j *Job) Observe(o Observer) *Job {
 	return j.OnError(o.ErrorHandler).
 		OnFailure(o.FailedHandler).
 		OnSuccess(o.SuccessHandler)
 }
+
+// Observation is an asynchronous observer of a job created
+// by ObserveAsync().
+type Observation struct {
+	job      *Job
+	observer Observer
+	id       int
+	// started contains the IDs of the jobs for which the
+	// StartedHandler was called
+	started map[string]bool
+	once    sync.Once
+}
+
+// ObserveAsync registers the functions of the Observer for all tasks of
+// the job, including the tasks of job arrays and the tasks which are
+// submitted later, and returns immediately. The functions are called
+// one after the other from a separate goroutine: ErrorHandler when a
+// submission failed, StartedHandler when a task started running,
+// StateChangeHandler for each state transition, and SuccessHandler or
+// FailedHandler when a task finished. The state transitions are detected
+// by the event poller of the workflow (see WithEventPollInterval()).
+//
+// Close() must be called for stopping the observation. Note that the
+// default observer exits the application when a task failed.
+//
+// Example:
+//
+//	observation := job.ObserveAsync(wfl.Observer{
+//		FailedHandler: func(j drmaa2interface.Job) {
+//			fmt.Printf("task %s failed\n", j.GetID())
+//		},
+//	})
+//	defer observation.Close()
+func (j *Job) ObserveAsync(o Observer) *Observation {
+	j.begin(j.ctx, "ObserveAsync()")
+	observation := &Observation{
+		job:      j,
+		observer: o,
+		started:  make(map[string]bool),
+	}
+	if j.wfl == nil {
+		return observation
+	}
+	observation.id = j.wfl.events.subscribe(observation.handle)
+	j.observationsMutex.Lock()
+	j.observations = append(j.observations, observation)
+	j.observationsMutex.Unlock()
+
+	for _, t := range j.tasklist {
+		if t.job == nil && t.jobArray == nil {
+			if t.submitError != nil {
+				j.wfl.events.enqueue(observation.id,
+					Event{owner: j, submitError: t.submitError})
+			}
+			continue
+		}
+		e := Event{Tag: j.tag, Retry: t.retry, owner: j}
+		if t.jobArray != nil {
+			e.ArrayJobID = t.jobArray.GetID()
+		}
+		for _, job := range t.jobs() {
+			j.wfl.events.watchExisting(job, e)
+		}
+	}
+	return observation
+}
+
+// Close stops the observation and waits until the functions for the
+// events received so far are finished. It must not be called from
+// within a function of the Observer.
+func (o *Observation) Close() {
+	o.once.Do(func() {
+		if o.job.wfl == nil {
+			return
+		}
+		o.job.observationsMutex.Lock()
+		for i, observation := range o.job.observations {
+			if observation == o {
+				o.job.observations = append(o.job.observations[:i],
+					o.job.observations[i+1:]...)
+				break
+			}
+		}
+		o.job.observationsMutex.Unlock()
+		<-o.job.wfl.events.unsubscribe(o.id)
+	})
+}
+
+// handle calls the functions of the Observer for the events of
+// the tasks of the observed job.
+func (o *Observation) handle(e Event) {
+	if e.owner != o.job {
+		return
+	}
+	if e.submitError != nil {
+		if o.observer.ErrorHandler != nil {
+			o.observer.ErrorHandler(e.submitError)
+		}
+		return
+	}
+	switch e.Type {
+	case EventSubmitted, EventRetried, EventReaped, "":
+		return
+	}
+	if e.Type == EventRunning || (isTerminated(e.State) &&
+		e.JobInfo != nil && !e.JobInfo.DispatchTime.IsZero()) {
+		o.taskStarted(e.job)
+	}
+	if o.observer.StateChangeHandler != nil {
+		o.observer.StateChangeHandler(e.job, e.State)
+	}
+	switch {
+	case e.Type == EventDone && o.observer.SuccessHandler != nil:
+		o.observer.SuccessHandler(e.job)
+	case e.Type == EventFailed && o.observer.FailedHandler != nil:
+		o.observer.FailedHandler(e.job)
+	}
+}
+
+// taskStarted calls the StartedHandler once per job. It is also
+// called for finished tasks whose running state was not observed.
+func (o *Observation) taskStarted(job drmaa2interface.Job) {
+	if o.started[job.GetID()] {
+		return
+	}
+	o.started[job.GetID()] = true
+	if o.observer.StartedHandler != nil {
+		o.observer.StartedHandler(job)
+	}
+}
+
+// publishSubmitError passes the submission error to the
+// asynchronous observers and the metrics of the job.
+func (j *Job) publishSubmitError(err error) {
+	if m := j.wfl.metricsCollector(); m != nil {
+		m.submissionError(j.wfl.ctx.SMType.String(), j.tag)
+	}
+	j.observationsMutex.Lock()
+	defer j.observationsMutex.Unlock()
+	for _, o := range j.observations {
+		j.wfl.events.enqueue(o.id, Event{owner: j, submitError: err})
+	}
+}
@@ -2,10 +2,13 @@ package wfl_test
 
 import (
 	"github.com/dgruber/wfl"
+	"github.com/dgruber/wfl/pkg/context/fake"
 
 	"github.com/dgruber/drmaa2interface"
 
 	"errors"
+	"sync"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -51,4 +54,100 @@ var _ = Describe("JobObserver", func() {
 
 	})
 
+	Context("Asynchronous Observer", func() {
+
+		var (
+			sm   *fake.SessionManager
+			flow *wfl.Workflow
+
+			mu       sync.Mutex
+			recorded []string
+		)
+
+		record := func(kind string) func(drmaa2interface.Job) {
+			return func(j drmaa2interface.Job) {
+				mu.Lock()
+				defer mu.Unlock()
+				recorded = append(recorded, kind+":"+j.GetID())
+			}
+		}
+
+		calls := func() []string {
+			mu.Lock()
+			defer mu.Unlock()
+			return append([]string(nil), recorded...)
+		}
+
+		BeforeEach(func() {
+			recorded = nil
+			sm = fake.NewSessionManager().WithManualClock().
+				OnCommand("sleep", fake.Outcome{Duration: time.Minute}).
+				OnCommand("fail", fake.Outcome{ExitStatus: 1}).
+				OnCommand("broken", fake.Outcome{SubmitError: errors.New("broken")})
+			flow = wfl.NewWorkflow(fake.NewFakeContextByCfg(fake.Config{SessionManager: sm})).
+				WithEventPollInterval(time.Millisecond)
+		})
+
+		It("should observe all tasks of the job without blocking", func() {
+			job := flow.Run("true")
+			first := job.JobID()
+
+			var states []drmaa2interface.JobState
+			observation := job.ObserveAsync(wfl.Observer{
+				ErrorHandler: func(e error) {
+					mu.Lock()
+					defer mu.Unlock()
+					recorded = append(recorded, "error:"+e.Error())
+				},
+				StartedHandler: record("started"),
+				SuccessHandler: record("success"),
+				FailedHandler:  record("failed"),
+				StateChangeHandler: func(j drmaa2interface.Job, state drmaa2interface.JobState) {
+					mu.Lock()
+					defer mu.Unlock()
+					states = append(states, state)
+				},
+			})
+			job.Run("sleep")
+			running := job.JobID()
+			job.Run("fail")
+			failed := job.JobID()
+			job.Run("broken")
+
+			Eventually(calls).Should(ContainElements(
+				"started:"+first, "success:"+first,
+				"started:"+running,
+				"started:"+failed, "failed:"+failed,
+				"error:broken"))
+			Ω(calls()).ShouldNot(ContainElement("success:" + running))
+
+			sm.Clock().Advance(time.Minute)
+			Eventually(calls).Should(ContainElement("success:" + running))
+			observation.Close()
+
+			Ω(calls()).Should(HaveLen(7))
+			mu.Lock()
+			Ω(states).Should(ContainElements(drmaa2interface.Running,
+				drmaa2interface.Done, drmaa2interface.Failed))
+			mu.Unlock()
+		})
+
+		It("should observe the tasks of job arrays", func() {
+			job := flow.NewJob()
+			observation := job.ObserveAsync(wfl.Observer{SuccessHandler: record("success")})
+			job.RunArray(1, 3, 1, 3, "true")
+			Eventually(calls).Should(HaveLen(3))
+			observation.Close()
+		})
+
+		It("should not call the handlers after Close()", func() {
+			job := flow.NewJob()
+			observation := job.ObserveAsync(wfl.Observer{SuccessHandler: record("success")})
+			observation.Close()
+			job.Run("true")
+			Consistently(calls, "50ms").Should(BeEmpty())
+		})
+
+	})
+
 })
@@ -0,0 +1,146 @@
+package wfl
+
+import (
+	"fmt"
+
+	"github.com/dgruber/drmaa2interface"
+)
+
+// limiter bounds the amount of tasks which are in flight, i.e. which
+// are submitted but not yet finished.
+type limiter struct {
+	slots chan struct{}
+}
+
+func newLimiter(max int) *limiter {
+	if max <= 0 {
+		return nil
+	}
+	return &limiter{slots: make(chan struct{}, max)}
+}
+
+// WithConcurrencyLimit limits the amount of tasks of all jobs of the
+// workflow which are in flight at the same time. When the limit is
+// reached, RunT(), RunMatrixT(), Resubmit(), and the retry methods block
+// until a task of the workflow finished or the context of the job is
+// cancelled. Job arrays are submitted as a whole and are not limited;
+// their maxParallel parameter can be used instead. A limit <= 0 removes
+// the limit.
+//
+// Example:
+//
+//	flow := wfl.NewWorkflow(wfl.NewProcessContext()).WithConcurrencyLimit(4)
+//	// runs at most 4 processes at a time
+//	flow.RunMatrixT(jt, x, y).Synchronize()
+func (w *Workflow) WithConcurrencyLimit(max int) *Workflow {
+	w.limitsMutex.Lock()
+	defer w.limitsMutex.Unlock()
+	w.limiter = newLimiter(max)
+	return w
+}
+
+// WithTagConcurrencyLimit limits the amount of tasks in flight of all
+// jobs with the given tag (see TagWith()). It applies in addition to the
+// limit of the workflow. A limit <= 0 removes the limit.
+func (w *Workflow) WithTagConcurrencyLimit(tag string, max int) *Workflow {
+	w.limitsMutex.Lock()
+	defer w.limitsMutex.Unlock()
+	if w.tagLimiters == nil {
+		w.tagLimiters = make(map[string]*limiter)
+	}
+	if max <= 0 {
+		delete(w.tagLimiters, tag)
+		return w
+	}
+	w.tagLimiters[tag] = newLimiter(max)
+	return w
+}
+
+// WithConcurrencyLimit limits the amount of tasks of the job which are
+// in flight at the same time. It applies in addition to the limits of
+// the workflow. See Workflow.WithConcurrencyLimit() for details.
+func (j *Job) WithConcurrencyLimit(max int) *Job {
+	j.limiter = newLimiter(max)
+	return j
+}
+
+// limiters returns all limiters applying to the next task of the job.
+func (j *Job) limiters() []*limiter {
+	limiters := []*limiter{}
+	if j.limiter != nil {
+		limiters = append(limiters, j.limiter)
+	}
+	if j.wfl == nil {
+		return limiters
+	}
+	j.wfl.limitsMutex.Lock()
+	defer j.wfl.limitsMutex.Unlock()
+	if j.wfl.limiter != nil {
+		limiters = append(limiters, j.wfl.limiter)
+	}
+	if l, exists := j.wfl.tagLimiters[j.tag]; exists && j.tag != "" {
+		limiters = append(limiters, l)
+	}
+	return limiters
+}
+
+// acquireSlots blocks until a slot of each given limiter is available
+// or the context of the job is done.
+func (j *Job) acquireSlots(limiters []*limiter) error {
+	ctx := j.Context()
+	for i, l := range limiters {
+		select {
+		case l.slots <- struct{}{}:
+		default:
+			j.infof(ctx, "concurrency limit of %d tasks reached: waiting for a free slot",
+				cap(l.slots))
+			select {
+			case l.slots <- struct{}{}:
+			case <-ctx.Done():
+				releaseSlots(limiters[:i])
+				return fmt.Errorf("waiting for a free slot: %w", ctx.Err())
+			}
+		}
+	}
+	return nil
+}
+
+func releaseSlots(limiters []*limiter) {
+	for _, l := range limiters {
+		<-l.slots
+	}
+}
+
+// submit submits a task when the concurrency limits allow it. The slots
+// are released when the task is finished.
+func (j *Job) submit(jt drmaa2interface.JobTemplate) (drmaa2interface.Job, error) {
+	span, submitSpan := j.wfl.tracing.startTask(j, jt)
+	job, err := j.submitWithinLimits(jt)
+	j.wfl.tracing.submitted(span, submitSpan, job, err)
+	return job, err
+}
+
+func (j *Job) submitWithinLimits(jt drmaa2interface.JobTemplate) (drmaa2interface.Job, error) {
+	limiters := j.limiters()
+	if len(limiters) == 0 {
+		return j.wfl.js.RunJob(jt)
+	}
+	if err := j.acquireSlots(limiters); err != nil {
+		return nil, err
+	}
+	job, err := j.wfl.js.RunJob(jt)
+	if err != nil {
+		releaseSlots(limiters)
+		return nil, err
+	}
+	go func() {
+		defer releaseSlots(limiters)
+		for {
+			err := job.WaitTerminated(drmaa2interface.InfiniteTime)
+			if err == nil || isTerminated(job.GetState()) || !isTimeoutError(err) {
+				return
+			}
+		}
+	}()
+	return job, nil
+}
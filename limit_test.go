@@ -0,0 +1,100 @@
+package wfl_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/dgruber/drmaa2interface"
+	"github.com/dgruber/wfl"
+	"github.com/dgruber/wfl/pkg/context/fake"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ConcurrencyLimit", func() {
+
+	var (
+		sm   *fake.SessionManager
+		flow *wfl.Workflow
+	)
+
+	BeforeEach(func() {
+		// tasks run for an hour of virtual time which only
+		// passes when the test advances the clock
+		sm = fake.NewSessionManager().WithManualClock().
+			WithDefaultOutcome(fake.Outcome{Duration: time.Hour})
+		flow = wfl.NewWorkflow(fake.NewFakeContextByCfg(fake.Config{SessionManager: sm}))
+		Ω(flow.HasError()).Should(BeFalse())
+	})
+
+	submitted := func() int {
+		return len(sm.Submitted())
+	}
+
+	It("should queue tasks of the workflow exceeding the limit", func() {
+		flow.WithConcurrencyLimit(2)
+		done := make(chan *wfl.Job)
+		go func() {
+			done <- flow.RunMatrixNT(drmaa2interface.JobTemplate{
+				RemoteCommand: "train.sh",
+				Args:          []string{"{{i}}"},
+			}, wfl.Replacement{
+				Fields:       []wfl.JobTemplateField{wfl.Args},
+				Pattern:      "{{i}}",
+				Replacements: []string{"1", "2", "3", "4", "5"},
+			})
+		}()
+		Eventually(submitted).Should(Equal(2))
+		Consistently(submitted, "200ms").Should(Equal(2))
+
+		sm.Clock().Advance(time.Hour)
+		Eventually(submitted).Should(Equal(4))
+		Consistently(submitted, "200ms").Should(Equal(4))
+
+		sm.Clock().Advance(time.Hour)
+		job := <-done
+		Ω(job.LastError()).Should(BeNil())
+		Ω(submitted()).Should(Equal(5))
+	})
+
+	It("should limit the tasks of a job and of a tag", func() {
+		flow.WithTagConcurrencyLimit("gpu", 1)
+		go func() {
+			flow.NewJob().WithConcurrencyLimit(2).Run("a").Run("b").Run("c")
+		}()
+		go func() {
+			flow.NewJob().TagWith("gpu").Run("gpu").Resubmit(2)
+		}()
+		Eventually(submitted).Should(Equal(3))
+		Consistently(submitted, "200ms").Should(Equal(3))
+		sm.Clock().Advance(time.Hour)
+		Eventually(submitted).Should(Equal(5))
+		sm.Clock().Advance(time.Hour)
+		Eventually(submitted).Should(Equal(6))
+	})
+
+	It("should stop waiting for a slot when the context is cancelled", func() {
+		flow.WithConcurrencyLimit(1)
+		ctx, cancel := context.WithCancel(context.Background())
+		job := flow.NewJob().WithContext(ctx).Run("first")
+		Ω(job.LastError()).Should(BeNil())
+		go func() {
+			time.Sleep(100 * time.Millisecond)
+			cancel()
+		}()
+		job.Run("second")
+		Ω(errors.Is(job.LastError(), context.Canceled)).Should(BeTrue())
+		Ω(submitted()).Should(Equal(1))
+	})
+
+	It("should free the slot when the submission fails", func() {
+		sm.OnCommand("broken", fake.Outcome{SubmitError: errors.New("failed")})
+		flow.WithConcurrencyLimit(1)
+		job := flow.Run("broken")
+		Ω(job.LastError()).ShouldNot(BeNil())
+		Ω(job.Run("working").LastError()).Should(BeNil())
+	})
+
+})
@@ -0,0 +1,345 @@
+package wfl
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/dgruber/drmaa2interface"
+	"github.com/dgruber/wfl/pkg/matrix"
+)
+
+// Sampling defines which combinations of a matrix are submitted.
+type Sampling int
+
+const (
+	// FullGrid submits all combinations of the matrix.
+	FullGrid Sampling = iota
+	// RandomSampling submits MatrixOptions.Samples combinations
+	// which are drawn randomly without repetition.
+	RandomSampling
+	// LatinHypercubeSampling submits MatrixOptions.Samples combinations
+	// which are spread over the range of each dimension: each dimension
+	// is split into Samples strata and each stratum is hit exactly once.
+	LatinHypercubeSampling
+)
+
+// MatrixOptions configures which combinations of the replacements
+// RunMatrixOptsT() submits.
+type MatrixOptions struct {
+	// Exclude skips all combinations for which it returns true. The
+	// map contains the value of each replacement pattern.
+	Exclude func(values map[string]string) bool
+	// Sampling selects the combinations. The default is the full grid.
+	Sampling Sampling
+	// Samples is the amount of combinations which are submitted when
+	// sampling. Excluded combinations are not replaced, hence less tasks
+	// might be submitted.
+	Samples int
+	// Seed of the random generator used for sampling. If 0 the current
+	// time is used as seed.
+	Seed int64
+}
+
+// matrixDimension is a dimension of the matrix. It consists of one
+// replacement or of all zipped replacements sharing the same Group.
+type matrixDimension struct {
+	replacements []Replacement
+	length       int
+}
+
+// matrixPoint is one combination of replacement values and the job
+// template generated for it.
+type matrixPoint struct {
+	values   map[string]string
+	template drmaa2interface.JobTemplate
+}
+
+// RunMatrixNT works like RunMatrixT() but for any number of
+// replacements. It submits one task for each combination of the
+// replacements. Replacements with the same (non-empty) Group are
+// zipped: they are advanced together and need to have the same amount
+// of values.
+//
+// Example: Submit 6 tasks (2 images * 3 zipped lr/epochs pairs).
+//
+//	j.RunMatrixNT(drmaa2interface.JobTemplate{
+//		JobCategory:   "{{image}}",
+//		RemoteCommand: "train.sh",
+//		Args:          []string{"{{lr}}", "{{epochs}}"},
+//	}, wfl.Replacement{
+//		Fields:       []wfl.JobTemplateField{wfl.JobCategory},
+//		Pattern:      "{{image}}",
+//		Replacements: []string{"model:v1", "model:v2"},
+//	}, wfl.Replacement{
+//		Fields:       []wfl.JobTemplateField{wfl.Args},
+//		Pattern:      "{{lr}}",
+//		Replacements: []string{"0.1", "0.01", "0.001"},
+//		Group:        "schedule",
+//	}, wfl.Replacement{
+//		Fields:       []wfl.JobTemplateField{wfl.Args},
+//		Pattern:      "{{epochs}}",
+//		Replacements: []string{"10", "20", "30"},
+//		Group:        "schedule",
+//	}).WaitAll()
+func (j *Job) RunMatrixNT(jt drmaa2interface.JobTemplate, replacements ...Replacement) *Job {
+	return j.RunMatrixOptsT(jt, MatrixOptions{}, replacements...)
+}
+
+// RunMatrixOptsT works like RunMatrixNT() but allows to exclude
+// combinations and to submit only a random or Latin hypercube sample
+// of the combinations.
+//
+// Example: Submit 10 out of 1000 combinations which are spread over
+// all learning rates, batch sizes, and epochs.
+//
+//	j.RunMatrixOptsT(jt, wfl.MatrixOptions{
+//		Sampling: wfl.LatinHypercubeSampling,
+//		Samples:  10,
+//	}, learningRates, batchSizes, epochs)
+func (j *Job) RunMatrixOptsT(jt drmaa2interface.JobTemplate, opts MatrixOptions, replacements ...Replacement) *Job {
+	j.begin(j.ctx, fmt.Sprintf("RunMatrixOptsT(%v, %v)", jt, replacements))
+	if err := j.checkCtx(); err != nil {
+		j.errorf(j.ctx, "RunMatrixOptsT context check failed: %v", err)
+		j.lastError = err
+		return j
+	}
+	points, err := getMatrixPoints(jt, opts, replacements)
+	if err != nil {
+		j.errorf(j.ctx, "creating job templates failed: %v", err)
+		j.lastError = err
+		return j
+	}
+	for _, point := range points {
+		j.infof(j.ctx, "submitting job template: %v", point.template)
+		j = j.runT(point.template, point.values)
+		if j.Errored() {
+			err = j.lastError
+			j.errorf(j.ctx, "submitting job template failed: %v", err)
+			j.lastError = err
+			return j
+		}
+	}
+	return j
+}
+
+// getMatrixPoints returns the combinations of the replacements
+// selected by the options. The full grid is returned in order, the
+// first dimension changing slowest.
+func getMatrixPoints(jt drmaa2interface.JobTemplate, opts MatrixOptions, replacements []Replacement) ([]matrixPoint, error) {
+	dimensions, err := getMatrixDimensions(replacements)
+	if err != nil {
+		return nil, err
+	}
+	var positions [][]int
+	switch opts.Sampling {
+	case FullGrid:
+		positions = gridPositions(dimensions)
+	case RandomSampling, LatinHypercubeSampling:
+		if opts.Samples <= 0 {
+			return nil, errors.New("amount of samples must be > 0")
+		}
+		seed := opts.Seed
+		if seed == 0 {
+			seed = time.Now().UnixNano()
+		}
+		rng := rand.New(rand.NewSource(seed))
+		if opts.Sampling == RandomSampling {
+			positions = randomPositions(dimensions, opts.Samples, rng)
+		} else {
+			positions = latinHypercubePositions(dimensions, opts.Samples, rng)
+		}
+	default:
+		return nil, fmt.Errorf("unknown sampling %d", opts.Sampling)
+	}
+
+	points := make([]matrixPoint, 0, len(positions))
+	seen := make(map[string]bool, len(positions))
+	for _, position := range positions {
+		key := fmt.Sprint(position)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		values := matrixValues(dimensions, position)
+		if opts.Exclude != nil && opts.Exclude(values) {
+			continue
+		}
+		template, err := matrixTemplate(jt, replacements, values)
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, matrixPoint{values: values, template: template})
+	}
+	return points, nil
+}
+
+// getMatrixDimensions groups the replacements into dimensions in
+// the order of their first appearance.
+func getMatrixDimensions(replacements []Replacement) ([]matrixDimension, error) {
+	if len(replacements) == 0 {
+		return nil, errors.New("no replacements given")
+	}
+	dimensions := []matrixDimension{}
+	groups := map[string]int{}
+	for _, r := range replacements {
+		if len(r.Replacements) == 0 && r.Template != "" {
+			continue
+		}
+		if len(r.Replacements) == 0 {
+			return nil, fmt.Errorf("no values for pattern %s", r.Pattern)
+		}
+		if r.Group == "" {
+			dimensions = append(dimensions, matrixDimension{
+				replacements: []Replacement{r},
+				length:       len(r.Replacements),
+			})
+			continue
+		}
+		i, exists := groups[r.Group]
+		if !exists {
+			groups[r.Group] = len(dimensions)
+			dimensions = append(dimensions, matrixDimension{
+				replacements: []Replacement{r},
+				length:       len(r.Replacements),
+			})
+			continue
+		}
+		if dimensions[i].length != len(r.Replacements) {
+			return nil, fmt.Errorf("zipped patterns of group %s have a different amount of values (%d != %d)",
+				r.Group, dimensions[i].length, len(r.Replacements))
+		}
+		dimensions[i].replacements = append(dimensions[i].replacements, r)
+	}
+	return dimensions, nil
+}
+
+func gridPositions(dimensions []matrixDimension) [][]int {
+	maxPosition := make([]int, len(dimensions))
+	for i, d := range dimensions {
+		maxPosition[i] = d.length - 1
+	}
+	positions := [][]int{}
+	position := make([]int, len(dimensions))
+	for {
+		positions = append(positions, append([]int{}, position...))
+		var err error
+		position, err = matrix.GetNextValue(maxPosition, position)
+		if err != nil {
+			return positions
+		}
+	}
+}
+
+// gridSize returns the amount of combinations or math.MaxInt
+// if the amount does not fit into an int.
+func gridSize(dimensions []matrixDimension) int {
+	size := 1
+	for _, d := range dimensions {
+		if size > math.MaxInt/d.length {
+			return math.MaxInt
+		}
+		size *= d.length
+	}
+	return size
+}
+
+// randomPositions draws samples positions without repetition.
+func randomPositions(dimensions []matrixDimension, samples int, rng *rand.Rand) [][]int {
+	size := gridSize(dimensions)
+	if samples >= size {
+		positions := gridPositions(dimensions)
+		rng.Shuffle(len(positions), func(a, b int) {
+			positions[a], positions[b] = positions[b], positions[a]
+		})
+		return positions
+	}
+	positions := make([][]int, 0, samples)
+	seen := map[string]bool{}
+	for len(positions) < samples {
+		position := make([]int, len(dimensions))
+		for i, d := range dimensions {
+			position[i] = rng.Intn(d.length)
+		}
+		key := fmt.Sprint(position)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		positions = append(positions, position)
+	}
+	return positions
+}
+
+// latinHypercubePositions splits each dimension into samples strata
+// and picks a value from each stratum exactly once. Positions occurring
+// more than once (when a dimension has less values than samples) are
+// removed later.
+func latinHypercubePositions(dimensions []matrixDimension, samples int, rng *rand.Rand) [][]int {
+	positions := make([][]int, samples)
+	for i := range positions {
+		positions[i] = make([]int, len(dimensions))
+	}
+	for d, dimension := range dimensions {
+		strata := rng.Perm(samples)
+		for i := range positions {
+			value := (float64(strata[i]) + rng.Float64()) / float64(samples)
+			index := int(value * float64(dimension.length))
+			if index >= dimension.length {
+				index = dimension.length - 1
+			}
+			positions[i][d] = index
+		}
+	}
+	return positions
+}
+
+// matrixValues returns the value of each pattern at the position.
+func matrixValues(dimensions []matrixDimension, position []int) map[string]string {
+	values := map[string]string{}
+	for i, d := range dimensions {
+		for _, r := range d.replacements {
+			values[r.Pattern] = r.Replacements[position[i]]
+		}
+	}
+	return values
+}
+
+// matrixTemplate replaces the patterns of all replacements with their
+// values. Values of replacements with a Template are computed first.
+func matrixTemplate(jt drmaa2interface.JobTemplate, replacements []Replacement, values map[string]string) (drmaa2interface.JobTemplate, error) {
+	template, err := matrix.CopyJobTemplate(jt)
+	if err != nil {
+		return jt, fmt.Errorf("error copying job template: %s", err)
+	}
+	for _, r := range replacements {
+		value, err := replacementValue(r, values)
+		if err != nil {
+			return jt, err
+		}
+		for _, field := range r.Fields {
+			template, err = matrix.ReplaceInField(template, string(field),
+				r.Pattern, value)
+			if err != nil {
+				return jt, fmt.Errorf("replacing %s in field %s failed: %w",
+					r.Pattern, field, err)
+			}
+		}
+	}
+	return template, nil
+}
+
+// replacementValue returns the value the pattern of the replacement is
+// replaced with in the combination of the given values.
+func replacementValue(r Replacement, values map[string]string) (string, error) {
+	if r.Template == "" {
+		return values[r.Pattern], nil
+	}
+	value, err := matrix.ExecuteTemplate(r.Template, values)
+	if err != nil {
+		return "", fmt.Errorf("computing value of %s failed: %w", r.Pattern, err)
+	}
+	return value, nil
+}
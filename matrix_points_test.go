@@ -0,0 +1,179 @@
+package wfl
+
+import (
+	"strings"
+
+	g "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/dgruber/drmaa2interface"
+)
+
+var _ = g.Describe("MatrixPoints", func() {
+
+	jt := drmaa2interface.JobTemplate{
+		RemoteCommand: "train.sh",
+		Args:          []string{"{{a}}", "{{b}}", "{{c}}"},
+	}
+
+	axis := func(pattern string, group string, values ...string) Replacement {
+		return Replacement{
+			Fields:       []JobTemplateField{Args},
+			Pattern:      pattern,
+			Replacements: values,
+			Group:        group,
+		}
+	}
+
+	args := func(points []matrixPoint) []string {
+		out := []string{}
+		for _, p := range points {
+			out = append(out, strings.Join(p.template.Args, ""))
+		}
+		return out
+	}
+
+	g.It("should create all combinations of three dimensions", func() {
+		points, err := getMatrixPoints(jt, MatrixOptions{},
+			[]Replacement{axis("{{a}}", "", "1", "2"), axis("{{b}}", "", "x", "y"),
+				axis("{{c}}", "", "-", "+")})
+		Ω(err).Should(BeNil())
+		Ω(args(points)).Should(Equal([]string{
+			"1x-", "1x+", "1y-", "1y+", "2x-", "2x+", "2y-", "2y+"}))
+		Ω(points[5].values).Should(Equal(map[string]string{
+			"{{a}}": "2", "{{b}}": "x", "{{c}}": "+"}))
+	})
+
+	g.It("should zip replacements of the same group", func() {
+		points, err := getMatrixPoints(jt, MatrixOptions{},
+			[]Replacement{axis("{{a}}", "pair", "1", "2"), axis("{{b}}", "", "x", "y"),
+				axis("{{c}}", "pair", "-", "+")})
+		Ω(err).Should(BeNil())
+		Ω(args(points)).Should(Equal([]string{"1x-", "1y-", "2x+", "2y+"}))
+
+		_, err = getMatrixPoints(jt, MatrixOptions{},
+			[]Replacement{axis("{{a}}", "pair", "1", "2"), axis("{{c}}", "pair", "-")})
+		Ω(err).ShouldNot(BeNil())
+	})
+
+	g.It("should skip excluded combinations", func() {
+		points, err := getMatrixPoints(jt, MatrixOptions{
+			Exclude: func(values map[string]string) bool {
+				return values["{{a}}"] == "2" && values["{{b}}"] == "y"
+			},
+		}, []Replacement{axis("{{a}}", "", "1", "2"), axis("{{b}}", "", "x", "y")})
+		Ω(err).Should(BeNil())
+		Ω(args(points)).Should(Equal([]string{"1x{{c}}", "1y{{c}}", "2x{{c}}"}))
+	})
+
+	g.It("should fail without replacement values", func() {
+		_, err := getMatrixPoints(jt, MatrixOptions{}, nil)
+		Ω(err).ShouldNot(BeNil())
+		_, err = getMatrixPoints(jt, MatrixOptions{}, []Replacement{axis("{{a}}", "")})
+		Ω(err).ShouldNot(BeNil())
+		_, err = getMatrixPoints(jt, MatrixOptions{Sampling: RandomSampling},
+			[]Replacement{axis("{{a}}", "", "1")})
+		Ω(err).ShouldNot(BeNil())
+	})
+
+	values := func(n int) []string {
+		out := []string{}
+		for i := 0; i < n; i++ {
+			out = append(out, string(rune('a'+i)))
+		}
+		return out
+	}
+
+	g.It("should draw random samples without repetition", func() {
+		opts := MatrixOptions{Sampling: RandomSampling, Samples: 20, Seed: 7}
+		replacements := []Replacement{axis("{{a}}", "", values(10)...),
+			axis("{{b}}", "", values(10)...), axis("{{c}}", "", values(10)...)}
+		points, err := getMatrixPoints(jt, opts, replacements)
+		Ω(err).Should(BeNil())
+		Ω(points).Should(HaveLen(20))
+		seen := map[string]bool{}
+		for _, a := range args(points) {
+			Ω(seen[a]).Should(BeFalse())
+			seen[a] = true
+		}
+		// same seed, same samples
+		again, err := getMatrixPoints(jt, opts, replacements)
+		Ω(err).Should(BeNil())
+		Ω(args(again)).Should(Equal(args(points)))
+
+		// more samples than combinations returns the grid
+		opts.Samples = 100
+		points, err = getMatrixPoints(jt, opts, []Replacement{axis("{{a}}", "", "1", "2")})
+		Ω(err).Should(BeNil())
+		Ω(args(points)).Should(ConsistOf("1{{b}}{{c}}", "2{{b}}{{c}}"))
+	})
+
+	g.It("should hit each stratum once with Latin hypercube sampling", func() {
+		points, err := getMatrixPoints(jt, MatrixOptions{
+			Sampling: LatinHypercubeSampling, Samples: 10, Seed: 3,
+		}, []Replacement{axis("{{a}}", "", values(10)...), axis("{{b}}", "", values(20)...)})
+		Ω(err).Should(BeNil())
+		Ω(points).Should(HaveLen(10))
+		// each value of the first dimension is used exactly once
+		used := map[string]int{}
+		for _, p := range points {
+			used[p.values["{{a}}"]]++
+		}
+		Ω(used).Should(HaveLen(10))
+		// each half of the second dimension is hit evenly
+		low := 0
+		for _, p := range points {
+			if p.values["{{b}}"] < "k" {
+				low++
+			}
+		}
+		Ω(low).Should(Equal(5))
+	})
+
+	g.It("should compute values with templates", func() {
+		points, err := getMatrixPoints(jt, MatrixOptions{}, []Replacement{
+			axis("{{a}}", "", "1", "2"),
+			{
+				Fields:       []JobTemplateField{Args},
+				Pattern:      "{{b}}",
+				Replacements: []string{"x"},
+				Template:     "{{.b}}{{.a}}",
+			},
+			{
+				Fields:   []JobTemplateField{Args, MinSlots},
+				Pattern:  "{{c}}",
+				Template: "{{mul .a 4}}",
+			},
+		})
+		Ω(err).Should(BeNil())
+		Ω(args(points)).Should(Equal([]string{"1x14", "2x28"}))
+		Ω(points[1].template.MinSlots).Should(BeNumerically("==", 8))
+		// computed replacements are no coordinates
+		Ω(points[1].values).Should(Equal(map[string]string{"{{a}}": "2", "{{b}}": "x"}))
+	})
+
+	g.It("should replace in every job template field", func() {
+		fields := map[JobTemplateField]string{
+			RemoteCommand: "x", Args: "x", SubmitAsHold: "true",
+			ReRunnable: "true", JobEnvironment: "x", WorkingDirectory: "x",
+			JobCategory: "x", Email: "x", EmailOnStarted: "true",
+			EmailOnTerminated: "true", JobName: "x", InputPath: "x",
+			OutputPath: "x", ErrorPath: "x", JoinFiles: "true",
+			ReservationID: "x", QueueName: "x", MinSlots: "1", MaxSlots: "1",
+			Priority: "1", CandidateMachines: "x", MinPhysMemory: "1",
+			MachineOS: "x", MachineArch: "x", StartTime: "+1h",
+			DeadlineTime: "2030-01-01T00:00:00Z", StageInFiles: "x",
+			StageOutFiles: "x", ResourceLimits: "x", AccountingID: "x",
+			ExtensionList: "x", AllStrings: "x",
+		}
+		for field, value := range fields {
+			_, err := matrixTemplate(jt, []Replacement{{
+				Fields:       []JobTemplateField{field},
+				Pattern:      "{{a}}",
+				Replacements: []string{value},
+			}}, map[string]string{"{{a}}": value})
+			Ω(err).Should(BeNil(), string(field))
+		}
+	})
+
+})
@@ -0,0 +1,161 @@
+package wfl
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"github.com/dgruber/drmaa2interface"
+)
+
+// MatrixResult is the result of a task submitted by RunMatrixT(),
+// RunMatrixNT(), or RunMatrixOptsT().
+type MatrixResult struct {
+	// Coordinates contains the value of each replacement pattern
+	// the task was submitted with.
+	Coordinates map[string]string
+	JobID       string
+	State       drmaa2interface.JobState
+	ExitStatus  int
+	// Output of the task if the backend supports it.
+	Output string
+	// OutputError is set if the output could not be retrieved.
+	OutputError error
+}
+
+// MatrixResults are the results of all tasks of matrix runs of a job.
+type MatrixResults struct {
+	// Patterns are the replacement patterns of all results in
+	// alphabetical order.
+	Patterns []string
+	Results  []MatrixResult
+}
+
+// MatrixResults waits until all tasks of the job which were submitted
+// by a matrix run are finished and returns their exit status and output
+// indexed by the replacement values. Tasks which were not submitted by
+// a matrix run are skipped. For resubmitted tasks the last run is used.
+//
+// Example:
+//
+//	results := flow.RunMatrixNT(jt, learningRates, batchSizes).MatrixResults()
+//	best, _ := results.Best(func(r wfl.MatrixResult) (float64, bool) {
+//		accuracy, err := strconv.ParseFloat(r.Output, 64)
+//		return accuracy, err == nil
+//	})
+//	fmt.Println(best.Coordinates)
+func (j *Job) MatrixResults() MatrixResults {
+	j.begin(j.ctx, "MatrixResults()")
+	results := MatrixResults{Patterns: []string{}, Results: []MatrixResult{}}
+	patterns := map[string]bool{}
+	for _, t := range j.tasklist {
+		if t.coordinates == nil {
+			continue
+		}
+		for pattern := range t.coordinates {
+			if !patterns[pattern] {
+				patterns[pattern] = true
+				results.Patterns = append(results.Patterns, pattern)
+			}
+		}
+		result := MatrixResult{
+			Coordinates: t.coordinates,
+			State:       drmaa2interface.Undetermined,
+			ExitStatus:  -1,
+		}
+		if t.job == nil {
+			result.OutputError = t.submitError
+		} else {
+			result.JobID = t.job.GetID()
+			result.Output, result.OutputError = j.jobOutput(t.job)
+			result.State = t.job.GetState()
+			if ji, err := t.job.GetJobInfo(); err == nil && isTerminated(result.State) {
+				result.ExitStatus = ji.ExitStatus
+			}
+		}
+		results.add(result)
+	}
+	sort.Strings(results.Patterns)
+	return results
+}
+
+// add adds the result or replaces the result with the same coordinates
+// so that only the last run of resubmitted tasks is kept.
+func (r *MatrixResults) add(result MatrixResult) {
+	for i := range r.Results {
+		if sameCoordinates(r.Results[i].Coordinates, result.Coordinates) {
+			r.Results[i] = result
+			return
+		}
+	}
+	r.Results = append(r.Results, result)
+}
+
+// Lookup returns the result of the task submitted with the given
+// replacement values.
+func (r MatrixResults) Lookup(coordinates map[string]string) (MatrixResult, bool) {
+	for _, result := range r.Results {
+		if sameCoordinates(result.Coordinates, coordinates) {
+			return result, true
+		}
+	}
+	return MatrixResult{}, false
+}
+
+func sameCoordinates(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if value, exists := b[k]; !exists || value != v {
+			return false
+		}
+	}
+	return true
+}
+
+// Best returns the result with the highest score. Results for which
+// the score function returns false (like failed tasks or unparsable
+// output) are ignored. If no result has a score false is returned.
+func (r MatrixResults) Best(score func(result MatrixResult) (float64, bool)) (MatrixResult, bool) {
+	var best MatrixResult
+	var bestScore float64
+	found := false
+	for _, result := range r.Results {
+		s, ok := score(result)
+		if !ok {
+			continue
+		}
+		if !found || s > bestScore {
+			best, bestScore, found = result, s, true
+		}
+	}
+	return best, found
+}
+
+// WriteCSV writes the results as CSV with a header line. There is a
+// column for each replacement pattern followed by the job ID, state,
+// exit status, and output of the task.
+func (r MatrixResults) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	header := append(append([]string{}, r.Patterns...),
+		"job_id", "state", "exit_status", "output")
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("failed writing CSV header: %w", err)
+	}
+	for _, result := range r.Results {
+		record := make([]string, 0, len(header))
+		for _, pattern := range r.Patterns {
+			record = append(record, result.Coordinates[pattern])
+		}
+		record = append(record, result.JobID, result.State.String(),
+			strconv.Itoa(result.ExitStatus), result.Output)
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("failed writing CSV record: %w", err)
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
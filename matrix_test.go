@@ -0,0 +1,147 @@
+package wfl_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strconv"
+	"strings"
+
+	"github.com/dgruber/drmaa2interface"
+	"github.com/dgruber/wfl"
+	"github.com/dgruber/wfl/pkg/context/fake"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Matrix", func() {
+
+	var (
+		sm   *fake.SessionManager
+		flow *wfl.Workflow
+	)
+
+	BeforeEach(func() {
+		sm = fake.NewSessionManager()
+		flow = wfl.NewWorkflow(fake.NewFakeContextByCfg(fake.Config{SessionManager: sm}))
+		Ω(flow.HasError()).Should(BeFalse())
+	})
+
+	jt := drmaa2interface.JobTemplate{
+		RemoteCommand: "train.sh",
+		Args:          []string{"--lr={{lr}}", "--bs={{bs}}", "--epochs={{epochs}}"},
+	}
+	lr := wfl.Replacement{
+		Fields:       []wfl.JobTemplateField{wfl.Args},
+		Pattern:      "{{lr}}",
+		Replacements: []string{"0.1", "0.01"},
+	}
+	bs := wfl.Replacement{
+		Fields:       []wfl.JobTemplateField{wfl.Args},
+		Pattern:      "{{bs}}",
+		Replacements: []string{"32", "64", "128"},
+	}
+	epochs := wfl.Replacement{
+		Fields:       []wfl.JobTemplateField{wfl.Args},
+		Pattern:      "{{epochs}}",
+		Replacements: []string{"10", "20"},
+	}
+
+	It("should submit all combinations of three replacements", func() {
+		job := flow.RunMatrixNT(jt, lr, bs, epochs).Synchronize()
+		Ω(job.LastError()).Should(BeNil())
+		Ω(job.ListAll()).Should(HaveLen(12))
+		Ω(sm.Submitted()[11].Args).Should(Equal([]string{"--lr=0.01", "--bs=128", "--epochs=20"}))
+	})
+
+	It("should submit a sample of the combinations", func() {
+		job := flow.NewJob().RunMatrixOptsT(jt, wfl.MatrixOptions{
+			Sampling: wfl.LatinHypercubeSampling,
+			Samples:  2,
+			Seed:     1,
+			Exclude: func(values map[string]string) bool {
+				return values["{{bs}}"] == "128"
+			},
+		}, lr, bs, epochs).Synchronize()
+		Ω(job.LastError()).Should(BeNil())
+		Ω(len(sm.Submitted())).Should(BeNumerically("<=", 2))
+		for _, submitted := range sm.Submitted() {
+			Ω(submitted.Args).ShouldNot(ContainElement("--bs=128"))
+		}
+	})
+
+	It("should fail on zipped replacements with a different length", func() {
+		bsZipped, epochsZipped := bs, epochs
+		bsZipped.Group, epochsZipped.Group = "schedule", "schedule"
+		job := flow.RunMatrixNT(jt, lr, bsZipped, epochsZipped)
+		Ω(job.LastError()).ShouldNot(BeNil())
+		Ω(sm.Submitted()).Should(BeEmpty())
+	})
+
+	Context("Results", func() {
+
+		BeforeEach(func() {
+			// the accuracy is highest for lr=0.01 and bs=64
+			sm.On(func(jt drmaa2interface.JobTemplate) bool {
+				return strings.Join(jt.Args, " ") == "--lr=0.01 --bs=64 --epochs={{epochs}}"
+			}, fake.Outcome{Output: "0.93\n"})
+			sm.On(func(jt drmaa2interface.JobTemplate) bool {
+				return len(jt.Args) > 1 && jt.Args[1] == "--bs=128"
+			}, fake.Outcome{ExitStatus: 1, Output: "out of memory"})
+			sm.WithDefaultOutcome(fake.Outcome{Output: "0.81\n"})
+		})
+
+		It("should return the results indexed by the replacement values", func() {
+			job := flow.RunMatrixT(jt, lr, bs)
+			results := job.MatrixResults()
+			Ω(results.Patterns).Should(Equal([]string{"{{bs}}", "{{lr}}"}))
+			Ω(results.Results).Should(HaveLen(6))
+
+			result, found := results.Lookup(map[string]string{"{{lr}}": "0.1", "{{bs}}": "128"})
+			Ω(found).Should(BeTrue())
+			Ω(result.State).Should(Equal(drmaa2interface.Failed))
+			Ω(result.ExitStatus).Should(Equal(1))
+			Ω(result.Output).Should(Equal("out of memory"))
+
+			best, found := results.Best(func(r wfl.MatrixResult) (float64, bool) {
+				accuracy, err := strconv.ParseFloat(r.Output, 64)
+				return accuracy, err == nil && r.State == drmaa2interface.Done
+			})
+			Ω(found).Should(BeTrue())
+			Ω(best.Coordinates).Should(Equal(map[string]string{"{{lr}}": "0.01", "{{bs}}": "64"}))
+			Ω(best.Output).Should(Equal("0.93"))
+
+			// the coordinates are part of the task results
+			Ω(job.Results()[0].Coordinates).Should(Equal(map[string]string{
+				"{{lr}}": "0.1", "{{bs}}": "32"}))
+		})
+
+		It("should only return the last run of retried tasks", func() {
+			job := flow.RunMatrixT(jt, lr, bs)
+			failedID := job.JobID()
+			job.Retry(1)
+			Ω(job.JobID()).ShouldNot(Equal(failedID))
+
+			results := job.MatrixResults()
+			Ω(results.Results).Should(HaveLen(6))
+			result, found := results.Lookup(map[string]string{"{{lr}}": "0.01", "{{bs}}": "128"})
+			Ω(found).Should(BeTrue())
+			Ω(result.JobID).Should(Equal(job.JobID()))
+		})
+
+		It("should export the results as CSV", func() {
+			job := flow.NewJob().Run("prepare").RunMatrixNT(jt, lr, epochs)
+			var buf bytes.Buffer
+			Ω(job.MatrixResults().WriteCSV(&buf)).Should(BeNil())
+			records, err := csv.NewReader(&buf).ReadAll()
+			Ω(err).Should(BeNil())
+			Ω(records).Should(HaveLen(5))
+			Ω(records[0]).Should(Equal([]string{"{{epochs}}", "{{lr}}", "job_id",
+				"state", "exit_status", "output"}))
+			Ω(records[1][0:2]).Should(Equal([]string{"10", "0.1"}))
+			Ω(records[1][3:]).Should(Equal([]string{"Done", "0", "0.81"}))
+		})
+
+	})
+
+})
@@ -0,0 +1,344 @@
+package wfl
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultMetricsBuckets are the upper bounds in seconds of the histogram
+// buckets for the queue and run durations of tasks.
+var DefaultMetricsBuckets = []float64{1, 5, 15, 30, 60, 300, 900, 1800, 3600, 7200, 21600, 86400}
+
+// Metrics collects counters and histograms of the tasks of one or more
+// workflows (see Workflow.WithMetrics()). The metrics are labeled with
+// the backend and the tag of the job. They are available with Tasks()
+// and in the Prometheus text format with WriteTo(). Metrics implements
+// http.Handler for exposing them to Prometheus.
+//
+// Example:
+//
+//	metrics := wfl.NewMetrics()
+//	http.Handle("/metrics", metrics)
+//	go http.ListenAndServe(":9090", nil)
+//
+//	flow := wfl.NewWorkflow(ctx).WithMetrics(metrics)
+type Metrics struct {
+	mu      sync.Mutex
+	buckets []float64
+	tasks   map[metricLabels]*TaskMetrics
+	// running contains the time when the running state of a
+	// task was detected by job ID
+	running map[string]time.Time
+	// submitted contains the submission time by job ID
+	submitted map[string]time.Time
+}
+
+type metricLabels struct {
+	backend string
+	tag     string
+}
+
+// TaskMetrics are the metrics of the tasks with the same backend and tag.
+type TaskMetrics struct {
+	Backend string
+	Tag     string
+	// Submitted counts all submitted tasks including resubmissions.
+	Submitted        uint64
+	Succeeded        uint64
+	Failed           uint64
+	Retries          uint64
+	SubmissionErrors uint64
+	// QueueDuration is the time from the submission until the task
+	// started in seconds.
+	QueueDuration Histogram
+	// RunDuration is the run time of finished tasks in seconds.
+	RunDuration Histogram
+}
+
+// Histogram counts observations in buckets.
+type Histogram struct {
+	// Buckets are the upper bounds of the buckets.
+	Buckets []float64
+	// Counts are the cumulative counts of the buckets, i.e. the
+	// number of observations less or equal the upper bound.
+	Counts []uint64
+	Count  uint64
+	Sum    float64
+}
+
+func newHistogram(buckets []float64) Histogram {
+	return Histogram{
+		Buckets: buckets,
+		Counts:  make([]uint64, len(buckets)),
+	}
+}
+
+func (h *Histogram) observe(value float64) {
+	for i, bound := range h.Buckets {
+		if value <= bound {
+			h.Counts[i]++
+		}
+	}
+	h.Count++
+	h.Sum += value
+}
+
+func (h Histogram) copy() Histogram {
+	h.Counts = append([]uint64(nil), h.Counts...)
+	return h
+}
+
+// NewMetrics creates a metrics collector. The buckets are the upper bounds
+// in seconds of the histograms of the queue and run durations; without
+// buckets DefaultMetricsBuckets are used.
+func NewMetrics(buckets ...float64) *Metrics {
+	if len(buckets) == 0 {
+		buckets = DefaultMetricsBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	return &Metrics{
+		buckets:   buckets,
+		tasks:     make(map[metricLabels]*TaskMetrics),
+		running:   make(map[string]time.Time),
+		submitted: make(map[string]time.Time),
+	}
+}
+
+// WithMetrics lets the metrics collector count the tasks of the workflow.
+// A collector can be shared between workflows. The state transitions of
+// the tasks are detected by the event poller of the workflow (see
+// WithEventPollInterval()).
+func (w *Workflow) WithMetrics(m *Metrics) *Workflow {
+	if m == nil || w.ctx == nil {
+		return w
+	}
+	backend := w.ctx.SMType.String()
+	w.metricsMutex.Lock()
+	defer w.metricsMutex.Unlock()
+	if w.metrics != nil {
+		w.events.unsubscribe(w.metricsSubscription)
+	}
+	w.metrics = m
+	w.metricsSubscription = w.events.subscribe(func(e Event) {
+		m.handle(backend, e)
+	})
+	return w
+}
+
+// metricsCollector returns the metrics collector of the workflow.
+func (w *Workflow) metricsCollector() *Metrics {
+	w.metricsMutex.Lock()
+	defer w.metricsMutex.Unlock()
+	return w.metrics
+}
+
+// task returns the metrics for the labels. It must be called with the
+// lock held.
+func (m *Metrics) task(backend, tag string) *TaskMetrics {
+	labels := metricLabels{backend: backend, tag: tag}
+	t, exists := m.tasks[labels]
+	if !exists {
+		t = &TaskMetrics{
+			Backend:       backend,
+			Tag:           tag,
+			QueueDuration: newHistogram(m.buckets),
+			RunDuration:   newHistogram(m.buckets),
+		}
+		m.tasks[labels] = t
+	}
+	return t
+}
+
+// submissionError counts a failed submission.
+func (m *Metrics) submissionError(backend, tag string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.task(backend, tag).SubmissionErrors++
+}
+
+func (m *Metrics) handle(backend string, e Event) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t := m.task(backend, e.Tag)
+	switch e.Type {
+	case EventRetried:
+		t.Retries++
+		fallthrough
+	case EventSubmitted:
+		t.Submitted++
+		m.submitted[e.JobID] = e.Time
+	case EventRunning:
+		if _, exists := m.running[e.JobID]; !exists {
+			m.running[e.JobID] = e.Time
+		}
+	case EventDone, EventFailed:
+		if e.Type == EventDone {
+			t.Succeeded++
+		} else {
+			t.Failed++
+		}
+		queue, run := m.durations(e)
+		if queue >= 0 {
+			t.QueueDuration.observe(queue.Seconds())
+		}
+		if run >= 0 {
+			t.RunDuration.observe(run.Seconds())
+		}
+		delete(m.running, e.JobID)
+		delete(m.submitted, e.JobID)
+	case EventReaped:
+		delete(m.running, e.JobID)
+		delete(m.submitted, e.JobID)
+	}
+}
+
+// durations returns the queue and run duration of a finished task; -1
+// if a duration is unknown. The times of the JobInfo are preferred over
+// the times when the state transitions were detected.
+func (m *Metrics) durations(e Event) (queue, run time.Duration) {
+	queue, run = -1, -1
+	if ji := e.JobInfo; ji != nil && !ji.DispatchTime.IsZero() &&
+		!ji.FinishTime.IsZero() && !ji.FinishTime.Before(ji.DispatchTime) {
+		if !ji.SubmissionTime.IsZero() && !ji.DispatchTime.Before(ji.SubmissionTime) {
+			queue = ji.DispatchTime.Sub(ji.SubmissionTime)
+		}
+		return queue, ji.FinishTime.Sub(ji.DispatchTime)
+	}
+	submitted, wasSubmitted := m.submitted[e.JobID]
+	running, started := m.running[e.JobID]
+	if started {
+		if wasSubmitted {
+			queue = running.Sub(submitted)
+		}
+		run = e.Time.Sub(running)
+	}
+	return queue, run
+}
+
+// Tasks returns a copy of the task metrics sorted by backend and tag.
+func (m *Metrics) Tasks() []TaskMetrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tasks := make([]TaskMetrics, 0, len(m.tasks))
+	for _, t := range m.tasks {
+		c := *t
+		c.QueueDuration = t.QueueDuration.copy()
+		c.RunDuration = t.RunDuration.copy()
+		tasks = append(tasks, c)
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		if tasks[i].Backend != tasks[j].Backend {
+			return tasks[i].Backend < tasks[j].Backend
+		}
+		return tasks[i].Tag < tasks[j].Tag
+	})
+	return tasks
+}
+
+// WriteTo writes the metrics in the Prometheus text format.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	tasks := m.Tasks()
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+
+	counters := []struct {
+		name  string
+		help  string
+		value func(t TaskMetrics) uint64
+	}{
+		{"wfl_tasks_submitted_total", "Number of submitted tasks including resubmissions.",
+			func(t TaskMetrics) uint64 { return t.Submitted }},
+		{"wfl_tasks_succeeded_total", "Number of tasks which finished successfully.",
+			func(t TaskMetrics) uint64 { return t.Succeeded }},
+		{"wfl_tasks_failed_total", "Number of tasks which failed.",
+			func(t TaskMetrics) uint64 { return t.Failed }},
+		{"wfl_task_retries_total", "Number of resubmissions of failed tasks.",
+			func(t TaskMetrics) uint64 { return t.Retries }},
+		{"wfl_task_submission_errors_total", "Number of failed task submissions.",
+			func(t TaskMetrics) uint64 { return t.SubmissionErrors }},
+	}
+	for _, c := range counters {
+		fmt.Fprintf(cw, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name)
+		for _, t := range tasks {
+			fmt.Fprintf(cw, "%s{%s} %d\n", c.name, t.labels(), c.value(t))
+		}
+	}
+
+	histograms := []struct {
+		name  string
+		help  string
+		value func(t TaskMetrics) Histogram
+	}{
+		{"wfl_task_queue_duration_seconds", "Time from the submission until a task started.",
+			func(t TaskMetrics) Histogram { return t.QueueDuration }},
+		{"wfl_task_run_duration_seconds", "Run time of finished tasks.",
+			func(t TaskMetrics) Histogram { return t.RunDuration }},
+	}
+	for _, h := range histograms {
+		fmt.Fprintf(cw, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name)
+		for _, t := range tasks {
+			histogram := h.value(t)
+			labels := t.labels()
+			for i, bound := range histogram.Buckets {
+				fmt.Fprintf(cw, "%s_bucket{%s,le=\"%s\"} %d\n", h.name, labels,
+					formatFloat(bound), histogram.Counts[i])
+			}
+			fmt.Fprintf(cw, "%s_bucket{%s,le=\"+Inf\"} %d\n", h.name, labels, histogram.Count)
+			fmt.Fprintf(cw, "%s_sum{%s} %s\n", h.name, labels, formatFloat(histogram.Sum))
+			fmt.Fprintf(cw, "%s_count{%s} %d\n", h.name, labels, histogram.Count)
+		}
+	}
+	if cw.err != nil {
+		return cw.n, cw.err
+	}
+	return cw.n, cw.w.Flush()
+}
+
+// ServeHTTP writes the metrics in the Prometheus text format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WriteTo(w)
+}
+
+func (t TaskMetrics) labels() string {
+	return fmt.Sprintf("backend=\"%s\",tag=\"%s\"",
+		escapeLabelValue(t.Backend), escapeLabelValue(t.Tag))
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func escapeLabelValue(value string) string {
+	return labelValueEscaper.Replace(value)
+}
+
+func formatFloat(f float64) string {
+	if math.IsInf(f, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// countingWriter counts the written bytes and keeps the first error.
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	if cw.err != nil {
+		return 0, cw.err
+	}
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	cw.err = err
+	return n, err
+}
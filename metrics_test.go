@@ -0,0 +1,112 @@
+package wfl_test
+
+import (
+	"bytes"
+	"errors"
+	"net/http/httptest"
+	"time"
+
+	"github.com/dgruber/wfl"
+	"github.com/dgruber/wfl/pkg/context/fake"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Metrics", func() {
+
+	var (
+		sm      *fake.SessionManager
+		flow    *wfl.Workflow
+		metrics *wfl.Metrics
+	)
+
+	taskMetrics := func(tag string) func() wfl.TaskMetrics {
+		return func() wfl.TaskMetrics {
+			for _, t := range metrics.Tasks() {
+				if t.Tag == tag {
+					return t
+				}
+			}
+			return wfl.TaskMetrics{}
+		}
+	}
+
+	BeforeEach(func() {
+		sm = fake.NewSessionManager().
+			OnCommand("train", fake.Outcome{Duration: 2 * time.Minute}).
+			OnCommand("fail", fake.Outcome{ExitStatus: 1}).
+			OnCommand("broken", fake.Outcome{SubmitError: errors.New("broken")})
+		metrics = wfl.NewMetrics(60, 300)
+		flow = wfl.NewWorkflow(fake.NewFakeContextByCfg(fake.Config{SessionManager: sm})).
+			WithEventPollInterval(time.Millisecond).
+			WithMetrics(metrics)
+	})
+
+	It("should count the tasks per backend and tag", func() {
+		flow.NewJob().TagWith("training").Run("train").Run("train").Wait()
+		flow.NewJob().TagWith("flaky").Run("fail").Retry(2)
+		flow.NewJob().TagWith("training").Run("broken")
+
+		Eventually(func() uint64 { return taskMetrics("training")().Succeeded }).
+			Should(BeEquivalentTo(2))
+		Eventually(func() uint64 { return taskMetrics("flaky")().Failed }).
+			Should(BeEquivalentTo(3))
+
+		training := taskMetrics("training")()
+		Ω(training.Backend).Should(Equal("fake"))
+		Ω(training.Submitted).Should(BeEquivalentTo(2))
+		Ω(training.SubmissionErrors).Should(BeEquivalentTo(1))
+		Ω(training.Failed).Should(BeEquivalentTo(0))
+		Ω(training.RunDuration.Count).Should(BeEquivalentTo(2))
+		Ω(training.RunDuration.Sum).Should(BeNumerically("==", 240))
+		Ω(training.RunDuration.Counts).Should(Equal([]uint64{0, 2}))
+		Ω(training.QueueDuration.Counts).Should(Equal([]uint64{2, 2}))
+
+		flaky := taskMetrics("flaky")()
+		Ω(flaky.Submitted).Should(BeEquivalentTo(3))
+		Ω(flaky.Retries).Should(BeEquivalentTo(2))
+		Ω(flaky.Succeeded).Should(BeEquivalentTo(0))
+	})
+
+	It("should expose the metrics in the Prometheus text format", func() {
+		flow.NewJob().TagWith(`say "hi"`).Run("train").Wait()
+		Eventually(func() uint64 { return taskMetrics(`say "hi"`)().Succeeded }).
+			Should(BeEquivalentTo(1))
+
+		var buf bytes.Buffer
+		n, err := metrics.WriteTo(&buf)
+		Ω(err).Should(BeNil())
+		Ω(n).Should(BeEquivalentTo(buf.Len()))
+		text := buf.String()
+		labels := `backend="fake",tag="say \"hi\""`
+		Ω(text).Should(ContainSubstring("# TYPE wfl_tasks_submitted_total counter\n"))
+		Ω(text).Should(ContainSubstring("wfl_tasks_submitted_total{" + labels + "} 1\n"))
+		Ω(text).Should(ContainSubstring("wfl_tasks_succeeded_total{" + labels + "} 1\n"))
+		Ω(text).Should(ContainSubstring("wfl_task_submission_errors_total{" + labels + "} 0\n"))
+		Ω(text).Should(ContainSubstring("# TYPE wfl_task_run_duration_seconds histogram\n"))
+		Ω(text).Should(ContainSubstring("wfl_task_run_duration_seconds_bucket{" + labels + `,le="60"} 0` + "\n"))
+		Ω(text).Should(ContainSubstring("wfl_task_run_duration_seconds_bucket{" + labels + `,le="300"} 1` + "\n"))
+		Ω(text).Should(ContainSubstring("wfl_task_run_duration_seconds_bucket{" + labels + `,le="+Inf"} 1` + "\n"))
+		Ω(text).Should(ContainSubstring("wfl_task_run_duration_seconds_sum{" + labels + "} 120\n"))
+		Ω(text).Should(ContainSubstring("wfl_task_run_duration_seconds_count{" + labels + "} 1\n"))
+
+		recorder := httptest.NewRecorder()
+		metrics.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+		Ω(recorder.Code).Should(Equal(200))
+		Ω(recorder.Header().Get("Content-Type")).Should(HavePrefix("text/plain; version=0.0.4"))
+		Ω(recorder.Body.String()).Should(Equal(text))
+	})
+
+	It("should share a collector between workflows", func() {
+		other := wfl.NewWorkflow(fake.NewFakeContextByCfg(fake.Config{SessionManager: sm})).
+			WithEventPollInterval(time.Millisecond).
+			WithMetrics(metrics)
+		flow.Run("fail").Wait()
+		other.Run("fail").Wait()
+		Eventually(func() uint64 { return taskMetrics("")().Failed }).
+			Should(BeEquivalentTo(2))
+		Ω(metrics.Tasks()).Should(HaveLen(1))
+	})
+
+})
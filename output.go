@@ -1,21 +1,59 @@
 package wfl
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/dgruber/drmaa2interface"
 )
 
+// ErrOutputNotSupported is returned by the output methods of a job
+// when the backend of the workflow has no way to provide the output
+// of a job.
+var ErrOutputNotSupported = errors.New("output not supported for this backend")
+
+// SupportsOutput returns true if the output of jobs can be retrieved
+// with Output() and OutputsForJobIDs() for the backend of the context.
+// Backends which write the output into files require that the
+// OutputPath of the JobTemplate is set to a file which is accessible
+// from the workflow application.
+func (c *Context) SupportsOutput() bool {
+	if c == nil {
+		return false
+	}
+	switch c.SMType {
+	case DefaultSessionManager,
+		DockerSessionManager,
+		KubernetesSessionManager,
+		SingularitySessionManager,
+		PodmanSessionManager,
+		SlurmSessionManager,
+		LibDRMAASessionManager,
+		RemoteSessionManager,
+		FakeSessionManager:
+		return true
+	}
+	return false
+}
+
 func getJobOutputKubernetes(job drmaa2interface.Job) (string, error) {
+	return getJobInfoExtension(job, "output")
+}
+
+// getJobInfoExtension returns the output stored by the backend
+// under the given key in the ExtensionList of the JobInfo.
+func getJobInfoExtension(job drmaa2interface.Job, key string) (string, error) {
 	ji, err := job.GetJobInfo()
 	if err != nil {
 		return "", fmt.Errorf("failed getting job info: %s", err)
 	}
 	if ji.ExtensionList != nil {
-		if output, ok := ji.ExtensionList["output"]; ok {
+		if output, ok := ji.ExtensionList[key]; ok {
 			if len(output) > 0 && output[len(output)-1] == '\n' {
 				output = output[:len(output)-1]
 			}
@@ -25,23 +63,68 @@ func getJobOutputKubernetes(job drmaa2interface.Job) (string, error) {
 			return output, nil
 		}
 	}
-	return "", errors.New("no output in jobinfo")
+	return "", fmt.Errorf("no %s in jobinfo", key)
 }
 
-func getJobOutputOS(job drmaa2interface.Job) (string, error) {
+// getJobOutputFromOutputPath reads the output from the file the
+// backend wrote the output of the job to. This works for all backends
+// which respect the OutputPath of the JobTemplate.
+func getJobOutputFromOutputPath(job drmaa2interface.Job) (string, error) {
 	template, err := job.GetJobTemplate()
 	if err != nil {
 		return "", fmt.Errorf("failed getting job template: %s", err)
 	}
+	if template.OutputPath == "" {
+		return "", errors.New("OutputPath of job template is not set")
+	}
 	return getOutputFromPath(template.OutputPath)
 }
 
-func getJobOutputDocker(job drmaa2interface.Job) (string, error) {
+// getJobOutputSlurm reads the output from the OutputPath or, as the
+// Slurm cli backend does not forward the OutputPath to sbatch, from
+// the default Slurm output file (see slurmOutputFile()).
+func getJobOutputSlurm(job drmaa2interface.Job) (string, error) {
 	template, err := job.GetJobTemplate()
 	if err != nil {
 		return "", fmt.Errorf("failed getting job template: %s", err)
 	}
-	return getOutputFromPath(template.OutputPath)
+	if isPathLocalFile(template.OutputPath) {
+		return getFileContent(template.OutputPath)
+	}
+	return getOutputFromPath(slurmOutputFile(job.GetID()))
+}
+
+// slurmOutputFile returns the path of the file Slurm writes the output
+// of a job to when sbatch gets no --output: slurm-<jobid>.out or, for
+// array tasks, slurm-<arrayjobid>_<taskid>.out. As the Slurm cli backend
+// does not pass --chdir either, the file is in the directory sbatch was
+// called from, which is the working directory of this process.
+func slurmOutputFile(jobID string) string {
+	// array tasks are <arrayjobid>.<taskid> in DRMAA2
+	if arrayJobID, taskID, isTask := strings.Cut(jobID, "."); isTask {
+		jobID = arrayJobID + "_" + taskID
+	}
+	path, err := filepath.Abs(fmt.Sprintf("slurm-%s.out", jobID))
+	if err != nil {
+		return fmt.Sprintf("slurm-%s.out", jobID)
+	}
+	return path
+}
+
+// getJobOutputRemote returns the output provided by the remote server
+// in the "output" JobInfo extension. Otherwise the output is read from
+// the OutputPath which needs to be on a shared filesystem.
+func getJobOutputRemote(job drmaa2interface.Job) (string, error) {
+	output, errExtension := getJobOutputKubernetes(job)
+	if errExtension == nil {
+		return output, nil
+	}
+	output, err := getJobOutputFromOutputPath(job)
+	if err != nil {
+		return "", fmt.Errorf("remote server provides no output (%s) and %s",
+			errExtension, err)
+	}
+	return output, nil
 }
 
 func getOutputFromPath(outputPath string) (string, error) {
@@ -87,14 +170,14 @@ func isPathLocalFile(path string) bool {
 	return false
 }
 
-func getJobOutpuForJob(wflType SessionManagerType, job drmaa2interface.Job) (string, error) {
+func getJobOutpuForJob(ctx context.Context, wflType SessionManagerType, job drmaa2interface.Job) (string, error) {
 
 	state := job.GetState()
 	if state == drmaa2interface.Undetermined {
 		return "", errors.New("job state is undetermined")
 	}
 
-	err := job.WaitTerminated(drmaa2interface.InfiniteTime)
+	err := waitTerminated(ctx, job, drmaa2interface.InfiniteTime)
 	if err != nil {
 		return "", fmt.Errorf("failed waiting for job termination: %s", err)
 	}
@@ -113,7 +196,7 @@ func getJobOutpuForJob(wflType SessionManagerType, job drmaa2interface.Job) (str
 		}
 	case DockerSessionManager:
 		{
-			output, err := getJobOutputDocker(job)
+			output, err := getJobOutputFromOutputPath(job)
 			if err != nil {
 				return "", fmt.Errorf("failed getting job info for docker job %s: %s",
 					job.GetID(), err)
@@ -122,14 +205,137 @@ func getJobOutpuForJob(wflType SessionManagerType, job drmaa2interface.Job) (str
 		}
 	case DefaultSessionManager:
 		{
-			output, err := getJobOutputOS(job)
+			output, err := getJobOutputFromOutputPath(job)
 			if err != nil {
 				return "", fmt.Errorf("failed getting job info for OS job %s: %s",
 					job.GetID(), err)
 			}
 			return output, nil
 		}
+	case SingularitySessionManager, PodmanSessionManager, LibDRMAASessionManager:
+		{
+			output, err := getJobOutputFromOutputPath(job)
+			if err != nil {
+				return "", fmt.Errorf("failed getting output for job %s: %s",
+					job.GetID(), err)
+			}
+			return output, nil
+		}
+	case SlurmSessionManager:
+		{
+			output, err := getJobOutputSlurm(job)
+			if err != nil {
+				return "", fmt.Errorf("failed getting output for slurm job %s: %s",
+					job.GetID(), err)
+			}
+			return output, nil
+		}
+	case RemoteSessionManager:
+		{
+			output, err := getJobOutputRemote(job)
+			if err != nil {
+				return "", fmt.Errorf("failed getting output for remote job %s: %s",
+					job.GetID(), err)
+			}
+			return output, nil
+		}
+	case FakeSessionManager:
+		{
+			output, err := getJobInfoExtension(job, "output")
+			if err != nil {
+				return "", fmt.Errorf("failed getting output for fake job %s: %s",
+					job.GetID(), err)
+			}
+			return output, nil
+		}
+	}
+
+	return "", ErrOutputNotSupported
+}
+
+// getJobErrorFromErrorPath reads the error output from the file the
+// backend wrote the stderr of the job to.
+func getJobErrorFromErrorPath(job drmaa2interface.Job) (string, error) {
+	template, err := job.GetJobTemplate()
+	if err != nil {
+		return "", fmt.Errorf("failed getting job template: %s", err)
+	}
+	if template.ErrorPath == "" {
+		return "", errors.New("ErrorPath of job template is not set")
+	}
+	return getOutputFromPath(template.ErrorPath)
+}
+
+// getJobErrorSlurm reads the error output from the ErrorPath or from
+// the default Slurm output file which contains stdout and stderr.
+func getJobErrorSlurm(job drmaa2interface.Job) (string, error) {
+	template, err := job.GetJobTemplate()
+	if err != nil {
+		return "", fmt.Errorf("failed getting job template: %s", err)
+	}
+	if isPathLocalFile(template.ErrorPath) {
+		return getFileContent(template.ErrorPath)
+	}
+	return getOutputFromPath(slurmOutputFile(job.GetID()))
+}
+
+func getJobErrorForJob(ctx context.Context, wflType SessionManagerType, job drmaa2interface.Job) (string, error) {
+
+	state := job.GetState()
+	if state == drmaa2interface.Undetermined {
+		return "", errors.New("job state is undetermined")
+	}
+
+	err := waitTerminated(ctx, job, drmaa2interface.InfiniteTime)
+	if err != nil {
+		return "", fmt.Errorf("failed waiting for job termination: %s", err)
+	}
+
+	switch wflType {
+
+	case KubernetesSessionManager:
+		{
+			// the pod log contains stdout and stderr
+			output, err := getJobOutputKubernetes(job)
+			if err != nil {
+				return "", fmt.Errorf("failed getting job info for k8s job %s: %s",
+					job.GetID(), err)
+			}
+			return output, nil
+		}
+	case DefaultSessionManager,
+		DockerSessionManager,
+		SingularitySessionManager,
+		PodmanSessionManager,
+		LibDRMAASessionManager,
+		RemoteSessionManager:
+		{
+			output, err := getJobErrorFromErrorPath(job)
+			if err != nil {
+				return "", fmt.Errorf("failed getting error output for job %s: %s",
+					job.GetID(), err)
+			}
+			return output, nil
+		}
+	case SlurmSessionManager:
+		{
+			output, err := getJobErrorSlurm(job)
+			if err != nil {
+				return "", fmt.Errorf("failed getting error output for slurm job %s: %s",
+					job.GetID(), err)
+			}
+			return output, nil
+		}
+	case FakeSessionManager:
+		{
+			output, err := getJobInfoExtension(job, "error")
+			if err != nil {
+				return "", fmt.Errorf("failed getting error output for fake job %s: %s",
+					job.GetID(), err)
+			}
+			return output, nil
+		}
 	}
 
-	return "", errors.New("unsupported workflow type")
+	return "", ErrOutputNotSupported
 }
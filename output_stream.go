@@ -0,0 +1,215 @@
+package wfl
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/dgruber/drmaa2interface"
+)
+
+// OutputStreamer returns a reader which follows the output of the given
+// job while it is running. The reader returns io.EOF after the job is
+// finished and all output was read.
+type OutputStreamer func(ctx context.Context, job drmaa2interface.Job) (io.ReadCloser, error)
+
+// outputPollInterval defines how often a followed output file
+// is checked for new content.
+const outputPollInterval = 100 * time.Millisecond
+
+// maxOutputLineLength is the maximum length of a line passed to
+// an OnOutputLine() function.
+const maxOutputLineLength = 1024 * 1024
+
+// OutputStream returns a reader which follows the output of the last task
+// while it is running. Unlike Output() it does not wait until the task is
+// finished and does not load the complete output into memory. The reader
+// returns io.EOF when the task is finished and all output was read. It
+// must be closed by the caller.
+//
+// For the OS process, Singularity, Podman, libdrmaa, and Slurm backends the
+// file specified in JobTemplate.OutputPath is followed. Docker and Kubernetes
+// follow the container logs. In case of an error nil is returned and the
+// error is available with LastError().
+//
+// Example:
+//
+//	stream := flow.RunT(jt).OutputStream()
+//	defer stream.Close()
+//	io.Copy(os.Stdout, stream)
+func (j *Job) OutputStream() io.ReadCloser {
+	j.infof(j.ctx, "OutputStream()")
+	task := j.lastJob()
+	if task == nil || task.job == nil {
+		j.errorf(j.ctx, "OutputStream(): no task found")
+		j.lastError = errors.New("no task found")
+		return nil
+	}
+	stream, err := j.openOutputStream(task.job)
+	if err != nil {
+		j.errorf(j.ctx, "OutputStream(): %s", err)
+		j.lastError = err
+		return nil
+	}
+	return stream
+}
+
+// OnOutputLine calls the given function for each line of the output of
+// the last task while the task is running. It does not block. Wait()
+// and all other methods waiting for the task return after the function
+// was called for the last line.
+//
+// Example:
+//
+//	flow.Run("train.sh").OnOutputLine(func(line string) {
+//		fmt.Println("training:", line)
+//	}).Wait()
+func (j *Job) OnOutputLine(f func(line string)) *Job {
+	j.infof(j.ctx, "OnOutputLine()")
+	stream := j.OutputStream()
+	if stream == nil {
+		return j
+	}
+	task := j.lastJob()
+	done := make(chan struct{})
+	task.outputDone = append(task.outputDone, done)
+	go func() {
+		defer close(done)
+		defer stream.Close()
+		scanner := bufio.NewScanner(stream)
+		scanner.Buffer(make([]byte, 64*1024), maxOutputLineLength)
+		for scanner.Scan() {
+			f(scanner.Text())
+		}
+		if err := scanner.Err(); err != nil && !errors.Is(err, context.Canceled) {
+			j.warningf(j.ctx, "OnOutputLine(): reading output failed: %v", err)
+		}
+	}()
+	return j
+}
+
+func (j *Job) openOutputStream(job drmaa2interface.Job) (io.ReadCloser, error) {
+	if j.wfl == nil || j.wfl.ctx == nil {
+		return nil, errors.New("no context available")
+	}
+	if j.wfl.ctx.OutputStreamer != nil {
+		return j.wfl.ctx.OutputStreamer(j.Context(), job)
+	}
+	switch j.wfl.ctx.SMType {
+	case DefaultSessionManager,
+		DockerSessionManager,
+		SingularitySessionManager,
+		PodmanSessionManager,
+		LibDRMAASessionManager,
+		SlurmSessionManager:
+		template, err := job.GetJobTemplate()
+		if err != nil {
+			return nil, fmt.Errorf("failed getting job template: %s", err)
+		}
+		if template.OutputPath == "" || !isFollowablePath(template.OutputPath) {
+			return nil, fmt.Errorf("OutputPath %q of job template is not a file",
+				template.OutputPath)
+		}
+		return newFileFollower(j.Context(), job, template.OutputPath), nil
+	}
+	return nil, ErrOutputNotSupported
+}
+
+func isFollowablePath(path string) bool {
+	return path != "/dev/null" && path != "/dev/stdout" && path != "/dev/stderr"
+}
+
+// waitForOutput waits until all OnOutputLine() functions
+// of the task processed the output.
+func waitForOutput(ctx context.Context, t *task) {
+	for _, done := range t.outputDone {
+		if ctx == nil {
+			<-done
+			continue
+		}
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// fileFollower reads a file which is written by a running job
+// like "tail -f" until the job is finished.
+type fileFollower struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	job    drmaa2interface.Job
+	path   string
+
+	mu   sync.Mutex
+	file *os.File
+	// finished is set when the job was found in an end
+	// state; the next EOF ends the stream
+	finished bool
+}
+
+func newFileFollower(ctx context.Context, job drmaa2interface.Job, path string) *fileFollower {
+	ctx, cancel := context.WithCancel(ctx)
+	return &fileFollower{ctx: ctx, cancel: cancel, job: job, path: path}
+}
+
+func (f *fileFollower) Read(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for {
+		if err := f.ctx.Err(); err != nil {
+			return 0, err
+		}
+		if f.file == nil {
+			file, err := os.Open(f.path)
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return 0, err
+			}
+			if err == nil {
+				f.file = file
+			}
+		}
+		if f.file != nil {
+			n, err := f.file.Read(p)
+			if n > 0 {
+				return n, nil
+			}
+			if err != nil && err != io.EOF {
+				return 0, err
+			}
+		}
+		if f.finished {
+			return 0, io.EOF
+		}
+		// read once more after the job finished as it
+		// might have written output since the last read
+		f.finished = isTerminated(f.job.GetState())
+		if f.finished {
+			continue
+		}
+		select {
+		case <-time.After(outputPollInterval):
+		case <-f.ctx.Done():
+		}
+	}
+}
+
+// Close stops following the file. A blocked Read() returns.
+func (f *fileFollower) Close() error {
+	f.cancel()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.file != nil {
+		err := f.file.Close()
+		f.file = nil
+		return err
+	}
+	return nil
+}
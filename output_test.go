@@ -0,0 +1,233 @@
+package wfl_test
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dgruber/drmaa2interface"
+	"github.com/dgruber/wfl"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Output", func() {
+
+	// the process backend is used for all backends which
+	// read the output from files
+	workflowAs := func(smType wfl.SessionManagerType) *wfl.Workflow {
+		ctx := wfl.NewProcessContext()
+		ctx.SMType = smType
+		flow := wfl.NewWorkflow(ctx)
+		Ω(flow.HasError()).Should(BeFalse())
+		return flow
+	}
+
+	It("should report the output capability of the backends", func() {
+		for _, smType := range []wfl.SessionManagerType{
+			wfl.DefaultSessionManager, wfl.DockerSessionManager,
+			wfl.KubernetesSessionManager, wfl.SingularitySessionManager,
+			wfl.PodmanSessionManager, wfl.SlurmSessionManager,
+			wfl.LibDRMAASessionManager, wfl.RemoteSessionManager,
+		} {
+			Ω((&wfl.Context{SMType: smType}).SupportsOutput()).Should(BeTrue())
+		}
+		for _, smType := range []wfl.SessionManagerType{
+			wfl.CloudFoundrySessionManager, wfl.GoogleBatchSessionManager,
+			wfl.MPIOperatorSessionManager, wfl.ExternalSessionManager,
+		} {
+			Ω((&wfl.Context{SMType: smType}).SupportsOutput()).Should(BeFalse())
+		}
+	})
+
+	It("should read the output from the OutputPath", func() {
+		for _, smType := range []wfl.SessionManagerType{
+			wfl.SingularitySessionManager, wfl.PodmanSessionManager,
+			wfl.LibDRMAASessionManager, wfl.RemoteSessionManager,
+		} {
+			job := workflowAs(smType).RunT(drmaa2interface.JobTemplate{
+				RemoteCommand: "echo",
+				Args:          []string{"hello"},
+				OutputPath:    wfl.RandomFileNameInTempDir(),
+			})
+			Ω(job.Output()).Should(Equal("hello"))
+			Ω(job.LastError()).Should(BeNil())
+		}
+	})
+
+	It("should fail with a clear error when the OutputPath is not set", func() {
+		job := workflowAs(wfl.SingularitySessionManager).Run("echo", "hello")
+		Ω(job.Output()).Should(BeEmpty())
+		Ω(job.LastError()).ShouldNot(BeNil())
+		Ω(job.LastError().Error()).Should(ContainSubstring("OutputPath"))
+	})
+
+	Context("when reading the default Slurm output file", func() {
+
+		var tmpDir, wd string
+
+		// sbatch writes the file into the directory it was called from
+		BeforeEach(func() {
+			var err error
+			wd, err = os.Getwd()
+			Ω(err).Should(BeNil())
+			tmpDir, err = os.MkdirTemp("", "wflslurm")
+			Ω(err).Should(BeNil())
+			Ω(os.Chdir(tmpDir)).Should(BeNil())
+		})
+
+		AfterEach(func() {
+			Ω(os.Chdir(wd)).Should(BeNil())
+			os.RemoveAll(tmpDir)
+		})
+
+		It("should read it from the submit directory", func() {
+			job := workflowAs(wfl.SlurmSessionManager).RunT(drmaa2interface.JobTemplate{
+				RemoteCommand:    "sleep",
+				Args:             []string{"0"},
+				WorkingDirectory: os.TempDir(),
+			})
+			Ω(os.WriteFile(filepath.Join(tmpDir,
+				fmt.Sprintf("slurm-%s.out", job.JobID())), []byte("slurm\n"), 0600)).Should(BeNil())
+			Ω(job.Output()).Should(Equal("slurm"))
+			Ω(job.OutputError()).Should(Equal("slurm"))
+		})
+
+		It("should read the files of array tasks", func() {
+			job := workflowAs(wfl.SlurmSessionManager).RunArrayJob(1, 2, 1, 2, "sleep", "0")
+			Ω(job.LastError()).Should(BeNil())
+			jobs := job.ListAll()
+			Ω(jobs).Should(HaveLen(2))
+			for _, task := range jobs {
+				arrayJobID, taskID, isTask := strings.Cut(task.GetID(), ".")
+				Ω(isTask).Should(BeTrue())
+				Ω(os.WriteFile(filepath.Join(tmpDir,
+					fmt.Sprintf("slurm-%s_%s.out", arrayJobID, taskID)),
+					[]byte("task "+taskID+"\n"), 0600)).Should(BeNil())
+			}
+			outputs := job.Outputs()
+			Ω(outputs).Should(HaveLen(2))
+			for _, output := range outputs {
+				Ω(output.StdoutError).Should(BeNil())
+				Ω(output.Stdout).Should(HavePrefix("task "))
+			}
+			Ω(outputs[0].Stdout).ShouldNot(Equal(outputs[1].Stdout))
+		})
+
+	})
+
+	It("should return stdout and stderr separately", func() {
+		jt := drmaa2interface.JobTemplate{
+			RemoteCommand: "/bin/bash",
+			Args:          []string{"-c", "echo out; echo err >&2"},
+			OutputPath:    wfl.RandomFileNameInTempDir(),
+			ErrorPath:     wfl.RandomFileNameInTempDir(),
+		}
+		job := workflowAs(wfl.DefaultSessionManager).RunT(jt)
+		Ω(job.Output()).Should(Equal("out"))
+		Ω(job.OutputError()).Should(Equal("err"))
+		Ω(job.LastError()).Should(BeNil())
+
+		jt.OutputPath = wfl.RandomFileNameInTempDir()
+		jt.ErrorPath = wfl.RandomFileNameInTempDir()
+		jt.Args = []string{"-c", "echo out2; echo err2 >&2"}
+		outputs := job.RunT(jt).Outputs()
+		Ω(outputs).Should(HaveLen(2))
+		Ω(outputs[0].JobID).ShouldNot(Equal(outputs[1].JobID))
+		Ω(outputs[0].Stdout).Should(Equal("out"))
+		Ω(outputs[0].Stderr).Should(Equal("err"))
+		Ω(outputs[1].Stdout).Should(Equal("out2"))
+		Ω(outputs[1].Stderr).Should(Equal("err2"))
+		Ω(outputs[1].StdoutError).Should(BeNil())
+		Ω(outputs[1].StderrError).Should(BeNil())
+	})
+
+	It("should fail with a clear error when the ErrorPath is not set", func() {
+		job := workflowAs(wfl.DefaultSessionManager).RunT(drmaa2interface.JobTemplate{
+			RemoteCommand: "echo",
+			Args:          []string{"hello"},
+			OutputPath:    wfl.RandomFileNameInTempDir(),
+		})
+		Ω(job.OutputError()).Should(BeEmpty())
+		Ω(job.LastError().Error()).Should(ContainSubstring("ErrorPath"))
+		outputs := job.Outputs()
+		Ω(outputs).Should(HaveLen(1))
+		Ω(outputs[0].Stdout).Should(Equal("hello"))
+		Ω(outputs[0].StderrError).ShouldNot(BeNil())
+	})
+
+	Context("Streaming", func() {
+
+		It("should stream the output while the task is running", func() {
+			job := workflowAs(wfl.DefaultSessionManager).RunT(drmaa2interface.JobTemplate{
+				RemoteCommand: "/bin/bash",
+				Args:          []string{"-c", "echo first; sleep 2; echo second"},
+				OutputPath:    wfl.RandomFileNameInTempDir(),
+			})
+			stream := job.OutputStream()
+			Ω(job.LastError()).Should(BeNil())
+			Ω(stream).ShouldNot(BeNil())
+			defer stream.Close()
+
+			reader := bufio.NewReader(stream)
+			line, err := reader.ReadString('\n')
+			Ω(err).Should(BeNil())
+			Ω(line).Should(Equal("first\n"))
+			Ω(job.State()).ShouldNot(Equal(drmaa2interface.Done))
+
+			rest, err := io.ReadAll(reader)
+			Ω(err).Should(BeNil())
+			Ω(string(rest)).Should(Equal("second\n"))
+			Ω(job.State()).Should(Equal(drmaa2interface.Done))
+		})
+
+		It("should call a function for each output line", func() {
+			var lines []string
+			job := workflowAs(wfl.DefaultSessionManager).RunT(drmaa2interface.JobTemplate{
+				RemoteCommand: "/bin/bash",
+				Args:          []string{"-c", "for i in 1 2 3; do echo line $i; sleep 0.1; done"},
+				OutputPath:    wfl.RandomFileNameInTempDir(),
+			}).OnOutputLine(func(line string) {
+				lines = append(lines, line)
+			}).Wait()
+			Ω(job.LastError()).Should(BeNil())
+			Ω(lines).Should(Equal([]string{"line 1", "line 2", "line 3"}))
+		})
+
+		It("should stop streaming when the context is cancelled", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			job := workflowAs(wfl.DefaultSessionManager).NewJob().WithContext(ctx).
+				RunT(drmaa2interface.JobTemplate{
+					RemoteCommand: "sleep",
+					Args:          []string{"10"},
+					OutputPath:    wfl.RandomFileNameInTempDir(),
+				})
+			stream := job.OutputStream()
+			Ω(stream).ShouldNot(BeNil())
+			cancel()
+			_, err := io.ReadAll(stream)
+			Ω(err).Should(Equal(context.Canceled))
+			job.Kill()
+		})
+
+		It("should fail when the OutputPath is not set", func() {
+			job := workflowAs(wfl.DefaultSessionManager).Run("echo", "hello")
+			Ω(job.OutputStream()).Should(BeNil())
+			Ω(job.LastError()).ShouldNot(BeNil())
+		})
+
+	})
+
+	It("should return ErrOutputNotSupported for other backends", func() {
+		job := workflowAs(wfl.GoogleBatchSessionManager).Run("echo", "hello")
+		Ω(job.Output()).Should(BeEmpty())
+		Ω(job.LastError()).Should(Equal(wfl.ErrOutputNotSupported))
+		Ω(job.OutputsForJobIDs(nil)).Should(BeNil())
+	})
+
+})
@@ -0,0 +1,241 @@
+package wfl
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+
+	"github.com/dgruber/drmaa2interface"
+	"github.com/mitchellh/copystructure"
+)
+
+// pipeScript runs the command of a pipe stage with its stdin and stdout
+// redirected to the FIFOs given in the environment. The FIFOs are opened
+// by the stage itself so that the reader gets EOF when the writer exits.
+const pipeScript = `exec "$@"`
+
+const (
+	pipeInEnv  = "WFL_PIPE_IN"
+	pipeOutEnv = "WFL_PIPE_OUT"
+)
+
+// pipeContainerInput is the path inside the container where the output
+// of the previous stage is staged in for the Docker backend.
+const pipeContainerInput = "/tmp/wfl-pipe-input"
+
+// Pipe works like the shell pipe stage1 | stage2 | ...: the output of
+// each stage is the input of the next stage. All stages are tasks of
+// the job; Output(), ExitStatus(), and Success() refer to the last
+// stage and PipeStatus() returns the exit status of each stage. When a
+// stage fails the stages which are still running are terminated, hence
+// AnyFailed() returns true like for "set -o pipefail".
+//
+// For the process backend the stages run at the same time and are
+// connected by named pipes (FIFOs). The InputPath of the first and the
+// OutputPath of the last stage are respected. For the Singularity,
+// libdrmaa, and fake backends the stages run one after the other: the
+// output of a stage is written to a temporary file (OutputPath) which is
+// the InputPath of the next stage. In that case Pipe() blocks until the
+// pipe is finished. The Docker backend ignores the InputPath, hence the
+// file is staged in (StageInFiles) and the command is executed with
+// /bin/sh with its stdin redirected; the image needs a shell. Other
+// backends are not supported.
+//
+// Example:
+//
+//	job := flow.Pipe(
+//		drmaa2interface.JobTemplate{RemoteCommand: "cat", Args: []string{"/etc/services"}},
+//		drmaa2interface.JobTemplate{RemoteCommand: "sort"},
+//		drmaa2interface.JobTemplate{RemoteCommand: "uniq", OutputPath: "/tmp/out"},
+//	)
+//	fmt.Println(job.PipeStatus())
+func (j *Job) Pipe(stages ...drmaa2interface.JobTemplate) *Job {
+	j.begin(j.ctx, fmt.Sprintf("Pipe(%d stages)", len(stages)))
+	j.pipe = nil
+	if err := j.checkCtx(); err != nil {
+		j.lastError = err
+		return j
+	}
+	if len(stages) == 0 {
+		j.lastError = errors.New("no stages given")
+		return j
+	}
+	if !pipeSupported(j.wfl.ctx.SMType) {
+		j.lastError = fmt.Errorf("Pipe() is not supported by the %s backend",
+			j.wfl.ctx.SMType)
+		return j
+	}
+	dir, err := os.MkdirTemp("", "wfl-pipe")
+	if err != nil {
+		j.lastError = fmt.Errorf("failed creating pipe directory: %w", err)
+		return j
+	}
+	if j.wfl.ctx.SMType == DefaultSessionManager {
+		return j.pipeProcesses(dir, stages)
+	}
+	defer os.RemoveAll(dir)
+	return j.pipeFiles(dir, stages)
+}
+
+// PipeStatus waits until all stages of the last Pipe() are finished and
+// returns their exit status in order (like PIPESTATUS of bash). Stages
+// which were not submitted have the exit status -1.
+func (j *Job) PipeStatus() []int {
+	j.begin(j.ctx, "PipeStatus()")
+	status := make([]int, len(j.pipe))
+	for i, t := range j.pipe {
+		status[i] = -1
+		if t == nil || t.job == nil {
+			continue
+		}
+		if err := wait(j.ctx, t, drmaa2interface.InfiniteTime); err != nil {
+			continue
+		}
+		if t.waitForEndStateCollectedJobInfo && t.jobinfoError == nil {
+			status[i] = t.jobinfo.ExitStatus
+		}
+	}
+	return status
+}
+
+// pipeProcesses connects the stages with FIFOs and submits them.
+func (j *Job) pipeProcesses(dir string, stages []drmaa2interface.JobTemplate) *Job {
+	fifos := make([]string, len(stages)-1)
+	for i := range fifos {
+		fifos[i] = filepath.Join(dir, fmt.Sprintf("stage%d", i))
+		if err := syscall.Mkfifo(fifos[i], 0600); err != nil {
+			os.RemoveAll(dir)
+			j.lastError = fmt.Errorf("failed creating pipe: %w", err)
+			return j
+		}
+	}
+	j.pipe = make([]*task, 0, len(stages))
+	for i, stage := range stages {
+		jt := pipeStage(stage)
+		script := pipeScript
+		if i > 0 {
+			jt.InputPath = ""
+			jt.JobEnvironment[pipeInEnv] = fifos[i-1]
+			script += ` <"$` + pipeInEnv + `"`
+		}
+		if i < len(stages)-1 {
+			jt.OutputPath = ""
+			jt.JobEnvironment[pipeOutEnv] = fifos[i]
+			script += ` >"$` + pipeOutEnv + `"`
+		}
+		jt.Args = append([]string{"-c", script, "wfl-pipe", jt.RemoteCommand}, jt.Args...)
+		jt.RemoteCommand = "/bin/sh"
+		j.runT(jt, nil)
+		if err := j.lastError; err != nil {
+			j.errorf(j.ctx, "Pipe(): submitting stage %d failed: %v", i, err)
+			// the other stages would block while opening the pipe
+			for _, t := range j.pipe {
+				t.job.Terminate()
+			}
+			j.pipe = append(j.pipe, make([]*task, len(stages)-i)...)
+			os.RemoveAll(dir)
+			return j
+		}
+		j.pipe = append(j.pipe, j.lastJob())
+	}
+	go j.watchPipe(dir, j.pipe)
+	return j
+}
+
+// watchPipe terminates all stages when a stage failed and removes
+// the FIFOs when the pipe is finished.
+func (j *Job) watchPipe(dir string, stages []*task) {
+	defer os.RemoveAll(dir)
+	var once sync.Once
+	var wg sync.WaitGroup
+	for _, stage := range stages {
+		wg.Add(1)
+		go func(failed drmaa2interface.Job) {
+			defer wg.Done()
+			if waitTerminated(j.ctx, failed, drmaa2interface.InfiniteTime) != nil ||
+				failed.GetState() != drmaa2interface.Failed {
+				return
+			}
+			once.Do(func() {
+				j.warningf(j.ctx, "Pipe(): stage %s failed: terminating the other stages",
+					failed.GetID())
+				for _, s := range stages {
+					if !isTerminated(s.job.GetState()) {
+						s.job.Terminate()
+					}
+				}
+			})
+		}(stage.job)
+	}
+	wg.Wait()
+}
+
+// pipeFiles runs the stages one after the other connected by files.
+func (j *Job) pipeFiles(dir string, stages []drmaa2interface.JobTemplate) *Job {
+	j.pipe = make([]*task, 0, len(stages))
+	for i, stage := range stages {
+		jt := pipeStage(stage)
+		if i > 0 {
+			pipeFileInput(j.wfl.ctx.SMType, &jt, filepath.Join(dir, fmt.Sprintf("stage%d", i-1)))
+		}
+		if i < len(stages)-1 {
+			jt.OutputPath = filepath.Join(dir, fmt.Sprintf("stage%d", i))
+		}
+		j.runT(jt, nil)
+		if err := j.lastError; err != nil {
+			j.errorf(j.ctx, "Pipe(): submitting stage %d failed: %v", i, err)
+			j.pipe = append(j.pipe, make([]*task, len(stages)-i)...)
+			return j
+		}
+		j.pipe = append(j.pipe, j.lastJob())
+		if j.Wait(); j.State() != drmaa2interface.Done {
+			j.warningf(j.ctx, "Pipe(): stage %d failed: skipping the remaining stages", i)
+			j.pipe = append(j.pipe, make([]*task, len(stages)-i-1)...)
+			return j
+		}
+	}
+	return j
+}
+
+// pipeSupported returns true if the backend writes the output of a
+// stage to its OutputPath and reads its input from a local file.
+func pipeSupported(smType SessionManagerType) bool {
+	switch smType {
+	case DefaultSessionManager, DockerSessionManager, SingularitySessionManager,
+		LibDRMAASessionManager, FakeSessionManager:
+		return true
+	}
+	return false
+}
+
+// pipeFileInput sets the file with the output of the previous stage as
+// input of the stage.
+func pipeFileInput(smType SessionManagerType, jt *drmaa2interface.JobTemplate, input string) {
+	if smType != DockerSessionManager {
+		jt.InputPath = input
+		return
+	}
+	jt.InputPath = ""
+	if jt.StageInFiles == nil {
+		jt.StageInFiles = make(map[string]string, 1)
+	}
+	jt.StageInFiles[input] = pipeContainerInput
+	jt.Args = append([]string{"-c", `exec "$@" <` + pipeContainerInput,
+		"wfl-pipe", jt.RemoteCommand}, jt.Args...)
+	jt.RemoteCommand = "/bin/sh"
+}
+
+// pipeStage returns a copy of the job template of a stage.
+func pipeStage(stage drmaa2interface.JobTemplate) drmaa2interface.JobTemplate {
+	jt := stage
+	if jtCopy, err := copystructure.Copy(stage); err == nil {
+		jt = jtCopy.(drmaa2interface.JobTemplate)
+	}
+	if jt.JobEnvironment == nil {
+		jt.JobEnvironment = make(map[string]string, 1)
+	}
+	return jt
+}
@@ -0,0 +1,62 @@
+package wfl
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dgruber/drmaa2interface"
+	g "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = g.Describe("Pipe with files", func() {
+
+	var tmpDir string
+
+	g.BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "wflpipefiles")
+		Ω(err).Should(BeNil())
+	})
+
+	g.AfterEach(func() {
+		os.RemoveAll(tmpDir)
+	})
+
+	g.It("should pass the output of a stage as input file to the next", func() {
+		out := filepath.Join(tmpDir, "out")
+		job := NewWorkflow(NewProcessContext()).NewJob().pipeFiles(tmpDir,
+			[]drmaa2interface.JobTemplate{
+				{RemoteCommand: "printf", Args: []string{"c\\nb\\na\\nb\\n"}},
+				{RemoteCommand: "sort"},
+				{RemoteCommand: "uniq", OutputPath: out},
+			})
+		Ω(job.LastError()).Should(BeNil())
+		Ω(job.PipeStatus()).Should(Equal([]int{0, 0, 0}))
+		content, err := os.ReadFile(out)
+		Ω(err).Should(BeNil())
+		Ω(string(content)).Should(Equal("a\nb\nc\n"))
+	})
+
+	g.It("should stage in the input file and redirect stdin for Docker", func() {
+		input := filepath.Join(tmpDir, "stage0")
+		Ω(os.WriteFile(input, []byte("b\na\n"), 0600)).Should(Succeed())
+		jt := drmaa2interface.JobTemplate{RemoteCommand: "sort", Args: []string{"-r"}}
+		pipeFileInput(DockerSessionManager, &jt, input)
+		Ω(jt.InputPath).Should(BeEmpty())
+		Ω(jt.StageInFiles).Should(Equal(map[string]string{input: pipeContainerInput}))
+		Ω(jt.RemoteCommand).Should(Equal("/bin/sh"))
+
+		// run the command of the container with the file at its host path
+		jt.Args[1] = strings.Replace(jt.Args[1], pipeContainerInput, input, 1)
+		jt.StageInFiles = nil
+		jt.OutputPath = filepath.Join(tmpDir, "out")
+		job := NewWorkflow(NewProcessContext()).RunT(jt).Wait()
+		Ω(job.Success()).Should(BeTrue())
+		content, err := os.ReadFile(jt.OutputPath)
+		Ω(err).Should(BeNil())
+		Ω(string(content)).Should(Equal("b\na\n"))
+	})
+
+})
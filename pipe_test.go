@@ -0,0 +1,109 @@
+package wfl_test
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/dgruber/drmaa2interface"
+	"github.com/dgruber/wfl"
+	"github.com/dgruber/wfl/pkg/context/fake"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Pipe", func() {
+
+	var (
+		flow   *wfl.Workflow
+		tmpDir string
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "wflpipe")
+		Ω(err).Should(BeNil())
+		flow = wfl.NewWorkflow(wfl.NewProcessContext())
+		Ω(flow.HasError()).Should(BeFalse())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(tmpDir)
+	})
+
+	It("should connect the output of a process with the input of the next", func() {
+		out := filepath.Join(tmpDir, "out")
+		job := flow.Pipe(
+			drmaa2interface.JobTemplate{
+				RemoteCommand: "printf",
+				Args:          []string{"c\\nb\\na\\nb\\n"},
+			},
+			drmaa2interface.JobTemplate{RemoteCommand: "sort"},
+			drmaa2interface.JobTemplate{
+				RemoteCommand: "uniq",
+				OutputPath:    out,
+			},
+		)
+		Ω(job.LastError()).Should(BeNil())
+		Ω(job.PipeStatus()).Should(Equal([]int{0, 0, 0}))
+		Ω(job.AnyFailed()).Should(BeFalse())
+
+		content, err := os.ReadFile(out)
+		Ω(err).Should(BeNil())
+		Ω(string(content)).Should(Equal("a\nb\nc\n"))
+	})
+
+	It("should terminate the other stages when a stage fails", func() {
+		job := flow.Pipe(
+			drmaa2interface.JobTemplate{RemoteCommand: "sleep", Args: []string{"60"}},
+			drmaa2interface.JobTemplate{
+				RemoteCommand: "/bin/sh",
+				Args:          []string{"-c", "exit 3"},
+			},
+		)
+		Ω(job.LastError()).Should(BeNil())
+		status := job.PipeStatus()
+		Ω(status).Should(HaveLen(2))
+		Ω(status[1]).Should(Equal(3))
+		Ω(job.AnyFailed()).Should(BeTrue())
+	})
+
+	It("should connect the stages with files for other backends", func() {
+		sm := fake.NewSessionManager().
+			OnCommand("fail", fake.Outcome{ExitStatus: 1})
+		flow := wfl.NewWorkflow(fake.NewFakeContextByCfg(fake.Config{SessionManager: sm}))
+
+		job := flow.Pipe(
+			drmaa2interface.JobTemplate{RemoteCommand: "cat", InputPath: "/in"},
+			drmaa2interface.JobTemplate{RemoteCommand: "sort", OutputPath: "/out"},
+		)
+		Ω(job.LastError()).Should(BeNil())
+		Ω(job.PipeStatus()).Should(Equal([]int{0, 0}))
+		submitted := sm.Submitted()
+		Ω(submitted[0].InputPath).Should(Equal("/in"))
+		Ω(submitted[0].OutputPath).ShouldNot(BeEmpty())
+		Ω(submitted[1].InputPath).Should(Equal(submitted[0].OutputPath))
+		Ω(submitted[1].OutputPath).Should(Equal("/out"))
+
+		job = flow.Pipe(
+			drmaa2interface.JobTemplate{RemoteCommand: "fail"},
+			drmaa2interface.JobTemplate{RemoteCommand: "sort"},
+		)
+		Ω(job.PipeStatus()).Should(Equal([]int{1, -1}))
+		Ω(job.AnyFailed()).Should(BeTrue())
+		Ω(sm.Submitted()).Should(HaveLen(3))
+	})
+
+	It("should fail for backends which cannot read the input from a file", func() {
+		sm := fake.NewSessionManager()
+		ctx := fake.NewFakeContextByCfg(fake.Config{SessionManager: sm})
+		ctx.SMType = wfl.KubernetesSessionManager
+		job := wfl.NewWorkflow(ctx).Pipe(
+			drmaa2interface.JobTemplate{RemoteCommand: "cat"},
+			drmaa2interface.JobTemplate{RemoteCommand: "sort"},
+		)
+		Ω(job.LastError()).Should(MatchError(ContainSubstring("not supported")))
+		Ω(sm.Submitted()).Should(BeEmpty())
+	})
+
+})
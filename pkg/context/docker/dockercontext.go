@@ -38,5 +38,6 @@ func NewDockerContextByCfg(cfg Config) *wfl.Context {
 		DefaultDockerImage: cfg.DefaultDockerImage,
 		CtxCreationErr:     err,
 		DefaultTemplate:    cfg.DefaultTemplate,
+		OutputStreamer:     followContainerOutput,
 	}
 }
@@ -0,0 +1,51 @@
+package docker
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/dgruber/drmaa2interface"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// followContainerOutput returns the stdout of the container of the
+// given job by following the container logs.
+func followContainerOutput(ctx context.Context, job drmaa2interface.Job) (io.ReadCloser, error) {
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		return nil, fmt.Errorf("failed creating docker client: %w", err)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	logs, err := cli.ContainerLogs(ctx, job.GetID(), container.LogsOptions{
+		ShowStdout: true,
+		Follow:     true,
+	})
+	if err != nil {
+		cancel()
+		cli.Close()
+		return nil, fmt.Errorf("failed following logs of container %s: %w",
+			job.GetID(), err)
+	}
+	// the log stream multiplexes stdout and stderr
+	reader, writer := io.Pipe()
+	go func() {
+		_, err := stdcopy.StdCopy(writer, io.Discard, logs)
+		logs.Close()
+		cli.Close()
+		writer.CloseWithError(err)
+	}()
+	return &containerOutput{PipeReader: reader, cancel: cancel}, nil
+}
+
+type containerOutput struct {
+	*io.PipeReader
+	cancel context.CancelFunc
+}
+
+func (c *containerOutput) Close() error {
+	c.cancel()
+	return c.PipeReader.Close()
+}
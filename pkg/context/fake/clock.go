@@ -0,0 +1,61 @@
+package fake
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is the virtual clock which drives the state of the fake jobs.
+// A job finishes when the clock reaches its submission time plus the
+// Duration of its Outcome.
+type Clock struct {
+	mu  sync.Mutex
+	now time.Time
+	// changed is closed and replaced each time the clock moves
+	changed chan struct{}
+}
+
+// NewClock creates a virtual clock starting at the given time.
+func NewClock(start time.Time) *Clock {
+	return &Clock{now: start, changed: make(chan struct{})}
+}
+
+// Now returns the current virtual time.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by the given duration. Waiting
+// jobs are woken up.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.move(c.now.Add(d))
+}
+
+// advanceTo moves the clock forward to the given time. The clock
+// never goes backwards.
+func (c *Clock) advanceTo(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.move(t)
+}
+
+func (c *Clock) move(t time.Time) {
+	if !t.After(c.now) {
+		return
+	}
+	c.now = t
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// wait returns a channel which is closed the next time
+// the clock moves.
+func (c *Clock) wait() <-chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.changed
+}
@@ -0,0 +1,88 @@
+// Package fake provides a wfl context which simulates jobs in memory.
+// It allows to test workflows deterministically and without delay:
+//
+//	sm := fake.NewSessionManager().
+//		OnCommand("train.sh", fake.Outcome{ExitStatus: 1}, fake.Outcome{Output: "ok"})
+//	flow := wfl.NewWorkflow(fake.NewFakeContextByCfg(fake.Config{SessionManager: sm}))
+//	job := flow.Run("train.sh").RetryAnyFailed(1)
+//	// job.Output() == "ok"
+package fake
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/dgruber/drmaa2interface"
+	"github.com/dgruber/wfl"
+)
+
+// Config determines the session manager and the default job template
+// of the fake context.
+type Config struct {
+	// SessionManager with the rules for the job outcomes. A new
+	// session manager is created if not set.
+	SessionManager  *SessionManager
+	DefaultTemplate drmaa2interface.JobTemplate
+}
+
+// NewFakeContext creates a new Context with an in-memory session manager
+// in which all jobs succeed immediately. The session manager can be
+// accessed with ctx.SM.(*fake.SessionManager) for adding rules.
+func NewFakeContext() *wfl.Context {
+	return NewFakeContextByCfg(Config{})
+}
+
+// NewFakeContextByCfg creates a new Context based on the given Config.
+func NewFakeContextByCfg(cfg Config) *wfl.Context {
+	if cfg.SessionManager == nil {
+		cfg.SessionManager = NewSessionManager()
+	}
+	return &wfl.Context{
+		SM:              cfg.SessionManager,
+		SMType:          wfl.FakeSessionManager,
+		DefaultTemplate: cfg.DefaultTemplate,
+		OutputStreamer:  streamOutput,
+	}
+}
+
+// streamOutput returns the output of the job after it is finished.
+func streamOutput(ctx context.Context, job drmaa2interface.Job) (io.ReadCloser, error) {
+	fakeJob, ok := job.(*Job)
+	if !ok {
+		return nil, fmt.Errorf("job %s is not a fake job", job.GetID())
+	}
+	return &outputReader{ctx: ctx, job: fakeJob}, nil
+}
+
+// outputReader waits for the end of the job before the
+// output can be read.
+type outputReader struct {
+	ctx    context.Context
+	job    *Job
+	output *strings.Reader
+}
+
+func (r *outputReader) Read(p []byte) (int, error) {
+	if r.output == nil {
+		done := make(chan error, 1)
+		go func() {
+			done <- r.job.WaitTerminated(drmaa2interface.InfiniteTime)
+		}()
+		select {
+		case err := <-done:
+			if err != nil {
+				return 0, err
+			}
+		case <-r.ctx.Done():
+			return 0, r.ctx.Err()
+		}
+		r.output = strings.NewReader(r.job.outcome.Output)
+	}
+	return r.output.Read(p)
+}
+
+func (r *outputReader) Close() error {
+	return nil
+}
@@ -0,0 +1,209 @@
+package fake_test
+
+import (
+	"errors"
+	"time"
+
+	"github.com/dgruber/drmaa2interface"
+	"github.com/dgruber/wfl"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "github.com/dgruber/wfl/pkg/context/fake"
+)
+
+var _ = Describe("Fake", func() {
+
+	var (
+		sm   *SessionManager
+		flow *wfl.Workflow
+	)
+
+	BeforeEach(func() {
+		sm = NewSessionManager()
+		flow = wfl.NewWorkflow(NewFakeContextByCfg(Config{SessionManager: sm}))
+		Ω(flow.HasError()).Should(BeFalse())
+	})
+
+	backendJob := func(id string) drmaa2interface.Job {
+		js, err := sm.OpenJobSession("wfl")
+		Ω(err).Should(BeNil())
+		filter := drmaa2interface.CreateJobInfo()
+		filter.ID = id
+		jobs, err := js.GetJobs(filter)
+		Ω(err).Should(BeNil())
+		Ω(jobs).Should(HaveLen(1))
+		return jobs[0]
+	}
+
+	It("should create a context with a new session manager", func() {
+		ctx := NewFakeContext()
+		Ω(ctx.CtxCreationErr).Should(BeNil())
+		Ω(ctx.SMType).Should(Equal(wfl.FakeSessionManager))
+		Ω(ctx.SM).Should(BeAssignableToTypeOf(&SessionManager{}))
+		Ω(ctx.SupportsOutput()).Should(BeTrue())
+	})
+
+	It("should run a chain of jobs with the scripted outcomes", func() {
+		sm.OnCommand("prepare", Outcome{Output: "prepared\n", Duration: time.Minute}).
+			OnCommand("train", Outcome{ExitStatus: 3, ErrorOutput: "diverged"})
+
+		job := flow.Run("prepare")
+		Ω(job.Output()).Should(Equal("prepared"))
+		Ω(job.State()).Should(Equal(drmaa2interface.Done))
+
+		job.ThenRun("train")
+		Ω(job.State()).Should(Equal(drmaa2interface.Failed))
+		Ω(job.ExitStatus()).Should(Equal(3))
+		Ω(job.OutputError()).Should(Equal("diverged"))
+
+		// unmatched commands succeed
+		Ω(job.ThenRun("echo").Success()).Should(BeTrue())
+	})
+
+	It("should use a virtual clock for the job timings", func() {
+		start := sm.Clock().Now()
+		sm.OnCommand("sleep", Outcome{Duration: time.Hour})
+
+		begin := time.Now()
+		job := flow.Run("sleep").Wait()
+		Ω(time.Since(begin)).Should(BeNumerically("<", time.Minute))
+		Ω(job.State()).Should(Equal(drmaa2interface.Done))
+		Ω(sm.Clock().Now().Sub(start)).Should(Equal(time.Hour))
+
+		ji := job.JobInfo()
+		Ω(ji.SubmissionTime).Should(Equal(start))
+		Ω(ji.FinishTime).Should(Equal(start.Add(time.Hour)))
+		Ω(ji.WallclockTime).Should(Equal(time.Hour))
+	})
+
+	It("should return a timeout error when the job does not finish in time", func() {
+		sm.OnCommand("sleep", Outcome{Duration: time.Hour})
+		job := flow.Run("sleep")
+		err := backendJob(job.JobID()).WaitTerminated(time.Minute)
+		Ω(err).ShouldNot(BeNil())
+		Ω(err.(drmaa2interface.Error).ID).Should(Equal(drmaa2interface.Timeout))
+		Ω(job.State()).Should(Equal(drmaa2interface.Running))
+		Ω(backendJob(job.JobID()).WaitTerminated(time.Hour)).Should(BeNil())
+		Ω(job.State()).Should(Equal(drmaa2interface.Done))
+	})
+
+	It("should use the clock set with WithClock", func() {
+		start := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+		clock := NewClock(start)
+		done := make(chan *Clock)
+		go func() {
+			done <- sm.Clock()
+		}()
+		sm.WithClock(clock).OnCommand("sleep", Outcome{Duration: time.Hour})
+		Ω(<-done).ShouldNot(BeNil())
+		Ω(sm.Clock()).Should(BeIdenticalTo(clock))
+
+		job := flow.Run("sleep").Wait()
+		Ω(job.State()).Should(Equal(drmaa2interface.Done))
+		Ω(job.JobInfo().SubmissionTime).Should(Equal(start))
+		Ω(clock.Now()).Should(Equal(start.Add(time.Hour)))
+	})
+
+	It("should only advance a manual clock when requested", func() {
+		sm.WithManualClock().OnCommand("sleep", Outcome{Duration: time.Hour})
+		job := flow.Run("sleep")
+		Ω(backendJob(job.JobID()).WaitTerminated(10 * time.Millisecond)).ShouldNot(BeNil())
+		Ω(job.State()).Should(Equal(drmaa2interface.Running))
+
+		sm.Clock().Advance(30 * time.Minute)
+		Ω(job.State()).Should(Equal(drmaa2interface.Running))
+
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			sm.Clock().Advance(30 * time.Minute)
+		}()
+		Ω(job.Wait().State()).Should(Equal(drmaa2interface.Done))
+	})
+
+	It("should retry failed jobs with the next outcome", func() {
+		sm.OnCommand("flaky",
+			Outcome{ExitStatus: 1},
+			Outcome{ExitStatus: 1},
+			Outcome{Output: "ok"})
+		job := flow.Run("flaky").RetryAnyFailed(2)
+		Ω(job.Success()).Should(BeTrue())
+		Ω(job.Output()).Should(Equal("ok"))
+		Ω(sm.Submitted()).Should(HaveLen(3))
+	})
+
+	It("should fail the submission", func() {
+		sm.OnCommand("broken", Outcome{SubmitError: errors.New("quota exceeded")})
+		job := flow.Run("broken")
+		Ω(job.LastError()).ShouldNot(BeNil())
+		Ω(job.LastError().Error()).Should(ContainSubstring("quota exceeded"))
+	})
+
+	It("should run job arrays with outcomes per task", func() {
+		sm.On(func(jt drmaa2interface.JobTemplate) bool {
+			return jt.JobEnvironment["TASK_ID"] == "2"
+		}, Outcome{ExitStatus: 1}, Outcome{})
+
+		job := flow.RunArrayJob(1, 3, 1, 3, "process")
+		Ω(job.LastError()).Should(BeNil())
+		job.Synchronize()
+		failed := job.ListAllFailed()
+		Ω(failed).Should(HaveLen(1))
+		Ω(failed[0].GetID()).Should(HaveSuffix(".2"))
+
+		job.RetryAnyFailed(1)
+		Ω(job.ListAllFailed()).Should(BeEmpty())
+		Ω(job.ListAll()).Should(HaveLen(3))
+	})
+
+	It("should submit all combinations of a matrix", func() {
+		job := flow.RunMatrixT(drmaa2interface.JobTemplate{
+			RemoteCommand: "{{cmd}}",
+			Args:          []string{"{{arg}}"},
+		}, wfl.Replacement{
+			Fields:       []wfl.JobTemplateField{wfl.RemoteCommand},
+			Pattern:      "{{cmd}}",
+			Replacements: []string{"a", "b"},
+		}, wfl.Replacement{
+			Fields:       []wfl.JobTemplateField{wfl.Args},
+			Pattern:      "{{arg}}",
+			Replacements: []string{"1", "2"},
+		}).Synchronize()
+		Ω(job.LastError()).Should(BeNil())
+
+		commands := []string{}
+		for _, jt := range sm.Submitted() {
+			commands = append(commands, jt.RemoteCommand+jt.Args[0])
+		}
+		Ω(commands).Should(ConsistOf("a1", "a2", "b1", "b2"))
+	})
+
+	It("should terminate running jobs", func() {
+		sm.OnCommand("sleep", Outcome{Duration: time.Hour})
+		job := flow.Run("sleep").Kill()
+		Ω(job.State()).Should(Equal(drmaa2interface.Failed))
+		Ω(job.JobInfo().ExitStatus).Should(Equal(143))
+	})
+
+	It("should suspend and resume jobs", func() {
+		sm.WithManualClock().OnCommand("sleep", Outcome{Duration: time.Hour})
+		job := flow.Run("sleep")
+		Ω(job.Suspend().State()).Should(Equal(drmaa2interface.Suspended))
+		sm.Clock().Advance(2 * time.Hour)
+		Ω(job.State()).Should(Equal(drmaa2interface.Suspended))
+		Ω(job.Resume().State()).Should(Equal(drmaa2interface.Running))
+		sm.Clock().Advance(time.Hour)
+		Ω(job.State()).Should(Equal(drmaa2interface.Done))
+	})
+
+	It("should stream the output of a job", func() {
+		sm.OnCommand("print", Outcome{Output: "one\ntwo\n", Duration: time.Second})
+		lines := []string{}
+		flow.Run("print").OnOutputLine(func(line string) {
+			lines = append(lines, line)
+		}).Wait()
+		Ω(lines).Should(Equal([]string{"one", "two"}))
+	})
+
+})
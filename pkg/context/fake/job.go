@@ -0,0 +1,268 @@
+package fake
+
+import (
+	"time"
+
+	"github.com/dgruber/drmaa2interface"
+)
+
+// terminatedExitStatus is the exit status of a job which was
+// terminated (like a process killed by SIGTERM).
+const terminatedExitStatus = 143
+
+// Job is a fake job implementing the drmaa2interface.Job. Its state
+// is derived from the virtual clock and its Outcome.
+type Job struct {
+	id       string
+	session  string
+	template drmaa2interface.JobTemplate
+	outcome  Outcome
+	sm       *SessionManager
+
+	// all fields below are protected by the lock
+	// of the session manager
+	submitted time.Time
+	finish    time.Time
+	// remaining run time while the job is suspended
+	remaining  time.Duration
+	suspended  bool
+	terminated bool
+	reaped     bool
+}
+
+// GetID returns the job ID.
+func (j *Job) GetID() string {
+	return j.id
+}
+
+// GetSessionName returns the name of the job session of the job.
+func (j *Job) GetSessionName() string {
+	return j.session
+}
+
+// GetJobTemplate returns the template the job was submitted with.
+func (j *Job) GetJobTemplate() (drmaa2interface.JobTemplate, error) {
+	return j.template, nil
+}
+
+// GetState returns the state of the job at the current virtual time.
+func (j *Job) GetState() drmaa2interface.JobState {
+	j.sm.mu.Lock()
+	defer j.sm.mu.Unlock()
+	return j.state(j.sm.clock.Now())
+}
+
+// state must be called with the lock held.
+func (j *Job) state(now time.Time) drmaa2interface.JobState {
+	switch {
+	case j.reaped:
+		return drmaa2interface.Undetermined
+	case j.terminated:
+		return drmaa2interface.Failed
+	case j.suspended:
+		return drmaa2interface.Suspended
+	case now.Before(j.finish):
+		return drmaa2interface.Running
+	case j.outcome.ExitStatus != 0:
+		return drmaa2interface.Failed
+	}
+	return drmaa2interface.Done
+}
+
+func (j *Job) finished(now time.Time) bool {
+	state := j.state(now)
+	return state == drmaa2interface.Done || state == drmaa2interface.Failed
+}
+
+// GetJobInfo returns the job info at the current virtual time. The
+// output of a finished job is available in the "output" and "error"
+// extensions.
+func (j *Job) GetJobInfo() (drmaa2interface.JobInfo, error) {
+	j.sm.mu.Lock()
+	defer j.sm.mu.Unlock()
+	now := j.sm.clock.Now()
+	ji := drmaa2interface.CreateJobInfo()
+	ji.ID = j.id
+	ji.State = j.state(now)
+	ji.Slots = 1
+	ji.AllocatedMachines = []string{"localhost"}
+	ji.SubmissionMachine = "localhost"
+	ji.SubmissionTime = j.submitted
+	ji.DispatchTime = j.submitted
+	if j.finished(now) {
+		ji.FinishTime = j.finish
+		ji.WallclockTime = j.finish.Sub(j.submitted)
+		ji.ExitStatus = j.outcome.ExitStatus
+		if j.terminated {
+			ji.ExitStatus = terminatedExitStatus
+		}
+		ji.ExtensionList = map[string]string{
+			"output": j.outcome.Output,
+			"error":  j.outcome.ErrorOutput,
+		}
+	} else {
+		ji.WallclockTime = now.Sub(j.submitted)
+	}
+	return ji, nil
+}
+
+// Suspend stops the progress of the job until it is resumed.
+func (j *Job) Suspend() error {
+	j.sm.mu.Lock()
+	defer j.sm.mu.Unlock()
+	now := j.sm.clock.Now()
+	if j.state(now) != drmaa2interface.Running {
+		return invalidState("suspend", j.id)
+	}
+	j.suspended = true
+	j.remaining = j.finish.Sub(now)
+	j.sm.signal()
+	return nil
+}
+
+// Resume continues a suspended job.
+func (j *Job) Resume() error {
+	j.sm.mu.Lock()
+	defer j.sm.mu.Unlock()
+	if j.state(j.sm.clock.Now()) != drmaa2interface.Suspended {
+		return invalidState("resume", j.id)
+	}
+	j.suspended = false
+	j.finish = j.sm.clock.Now().Add(j.remaining)
+	j.sm.signal()
+	return nil
+}
+
+// Hold is not supported as fake jobs start immediately.
+func (j *Job) Hold() error {
+	return unsupported("Hold")
+}
+
+// Release is not supported as fake jobs start immediately.
+func (j *Job) Release() error {
+	return unsupported("Release")
+}
+
+// Terminate ends an unfinished job in Failed state.
+func (j *Job) Terminate() error {
+	j.sm.mu.Lock()
+	defer j.sm.mu.Unlock()
+	now := j.sm.clock.Now()
+	if j.finished(now) || j.reaped {
+		return nil
+	}
+	j.terminated = true
+	j.suspended = false
+	j.finish = now
+	j.sm.signal()
+	return nil
+}
+
+// WaitStarted returns immediately as fake jobs are running
+// after submission.
+func (j *Job) WaitStarted(timeout time.Duration) error {
+	return nil
+}
+
+// WaitTerminated waits until the job is finished. Unless the clock of
+// the session manager is manual the clock is advanced to the end of
+// the job or by the timeout, whichever comes first.
+func (j *Job) WaitTerminated(timeout time.Duration) error {
+	return j.sm.waitUntil(timeout,
+		func() bool {
+			j.sm.mu.Lock()
+			defer j.sm.mu.Unlock()
+			return j.finished(j.sm.clock.Now()) || j.reaped
+		},
+		func() (time.Time, bool) {
+			j.sm.mu.Lock()
+			defer j.sm.mu.Unlock()
+			return j.finish, !j.suspended
+		})
+}
+
+// Reap removes the job from the job session.
+func (j *Job) Reap() error {
+	j.sm.mu.Lock()
+	defer j.sm.mu.Unlock()
+	j.reaped = true
+	return nil
+}
+
+func invalidState(operation, id string) error {
+	return drmaa2interface.Error{
+		Message: "cannot " + operation + " job " + id + " in its current state",
+		ID:      drmaa2interface.InvalidState,
+	}
+}
+
+// ArrayJob is a fake job array implementing the
+// drmaa2interface.ArrayJob.
+type ArrayJob struct {
+	id       string
+	session  string
+	template drmaa2interface.JobTemplate
+	jobs     []drmaa2interface.Job
+}
+
+// GetID returns the ID of the job array.
+func (a *ArrayJob) GetID() string {
+	return a.id
+}
+
+// GetJobs returns the tasks of the job array.
+func (a *ArrayJob) GetJobs() []drmaa2interface.Job {
+	return a.jobs
+}
+
+// GetSessionName returns the name of the job session.
+func (a *ArrayJob) GetSessionName() string {
+	return a.session
+}
+
+// GetJobTemplate returns the template the job array was
+// submitted with.
+func (a *ArrayJob) GetJobTemplate() drmaa2interface.JobTemplate {
+	return a.template
+}
+
+// Suspend suspends all running tasks.
+func (a *ArrayJob) Suspend() error {
+	return a.forAll(drmaa2interface.Job.Suspend)
+}
+
+// Resume resumes all suspended tasks.
+func (a *ArrayJob) Resume() error {
+	return a.forAll(drmaa2interface.Job.Resume)
+}
+
+// Hold is not supported.
+func (a *ArrayJob) Hold() error {
+	return unsupported("Hold")
+}
+
+// Release is not supported.
+func (a *ArrayJob) Release() error {
+	return unsupported("Release")
+}
+
+// Terminate terminates all unfinished tasks.
+func (a *ArrayJob) Terminate() error {
+	return a.forAll(drmaa2interface.Job.Terminate)
+}
+
+// forAll applies the operation to all tasks in the expected state
+// and returns the first error.
+func (a *ArrayJob) forAll(operation func(drmaa2interface.Job) error) error {
+	var firstErr error
+	for _, job := range a.jobs {
+		if err := operation(job); err != nil && firstErr == nil {
+			if derr, ok := err.(drmaa2interface.Error); ok &&
+				derr.ID == drmaa2interface.InvalidState {
+				continue
+			}
+			firstErr = err
+		}
+	}
+	return firstErr
+}
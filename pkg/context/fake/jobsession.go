@@ -0,0 +1,205 @@
+package fake
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dgruber/drmaa2interface"
+)
+
+// arrayTaskIDEnv is the environment variable which is set in the
+// job template of each job array task (like in the process backend).
+const arrayTaskIDEnv = "TASK_ID"
+
+// JobSession implements the drmaa2interface.JobSession in memory.
+type JobSession struct {
+	name    string
+	contact string
+	sm      *SessionManager
+}
+
+// Close does nothing as the job session is in memory.
+func (js *JobSession) Close() error {
+	return nil
+}
+
+// GetContact returns the contact string of the job session.
+func (js *JobSession) GetContact() (string, error) {
+	return js.contact, nil
+}
+
+// GetSessionName returns the name of the job session.
+func (js *JobSession) GetSessionName() (string, error) {
+	return js.name, nil
+}
+
+// GetJobCategories returns no job categories.
+func (js *JobSession) GetJobCategories() ([]string, error) {
+	return []string{}, nil
+}
+
+// GetJobs returns the jobs of the session which are not reaped. The
+// ID and the State of the filter are evaluated when they are set.
+func (js *JobSession) GetJobs(filter drmaa2interface.JobInfo) ([]drmaa2interface.Job, error) {
+	js.sm.mu.Lock()
+	defer js.sm.mu.Unlock()
+	now := js.sm.clock.Now()
+	jobs := []drmaa2interface.Job{}
+	for _, job := range js.sm.jobs {
+		if job.session != js.name || job.reaped {
+			continue
+		}
+		if filter.ID != "" && filter.ID != job.id {
+			continue
+		}
+		if filter.State != drmaa2interface.Unset && filter.State != job.state(now) {
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// GetJobArray returns the job array with the given ID.
+func (js *JobSession) GetJobArray(id string) (drmaa2interface.ArrayJob, error) {
+	js.sm.mu.Lock()
+	defer js.sm.mu.Unlock()
+	array, exists := js.sm.arrays[id]
+	if !exists || array.session != js.name {
+		return nil, drmaa2interface.Error{
+			Message: fmt.Sprintf("job array %s does not exist", id),
+			ID:      drmaa2interface.InvalidArgument,
+		}
+	}
+	return array, nil
+}
+
+// RunJob submits a fake job. The outcome of the job is determined by
+// the rules of the session manager.
+func (js *JobSession) RunJob(jt drmaa2interface.JobTemplate) (drmaa2interface.Job, error) {
+	js.sm.mu.Lock()
+	defer js.sm.mu.Unlock()
+	outcome := js.sm.outcome(jt)
+	if outcome.SubmitError != nil {
+		return nil, outcome.SubmitError
+	}
+	return js.newJob(js.sm.nextID(), jt, outcome), nil
+}
+
+// RunBulkJobs submits a job array. Each task gets its own outcome
+// based on its template, which contains the task ID in the TASK_ID
+// environment variable. The task IDs are "<arrayid>.<taskid>". The
+// submission fails if the outcome of any task has a SubmitError.
+// maxParallel is ignored.
+func (js *JobSession) RunBulkJobs(jt drmaa2interface.JobTemplate, begin, end, step, maxParallel int) (drmaa2interface.ArrayJob, error) {
+	if step <= 0 || begin > end {
+		return nil, drmaa2interface.Error{
+			Message: fmt.Sprintf("invalid job array range %d-%d:%d", begin, end, step),
+			ID:      drmaa2interface.InvalidArgument,
+		}
+	}
+	js.sm.mu.Lock()
+	defer js.sm.mu.Unlock()
+	templates := []drmaa2interface.JobTemplate{}
+	outcomes := []Outcome{}
+	for i := begin; i <= end; i += step {
+		taskTemplate := jt
+		taskTemplate.JobEnvironment = make(map[string]string, len(jt.JobEnvironment)+1)
+		for k, v := range jt.JobEnvironment {
+			taskTemplate.JobEnvironment[k] = v
+		}
+		taskTemplate.JobEnvironment[arrayTaskIDEnv] = fmt.Sprintf("%d", i)
+		outcome := js.sm.outcome(taskTemplate)
+		if outcome.SubmitError != nil {
+			return nil, outcome.SubmitError
+		}
+		templates = append(templates, taskTemplate)
+		outcomes = append(outcomes, outcome)
+	}
+	array := &ArrayJob{
+		id:       js.sm.nextID(),
+		session:  js.name,
+		template: jt,
+	}
+	for i, taskTemplate := range templates {
+		id := fmt.Sprintf("%s.%s", array.id, taskTemplate.JobEnvironment[arrayTaskIDEnv])
+		array.jobs = append(array.jobs, js.newJob(id, taskTemplate, outcomes[i]))
+	}
+	js.sm.arrays[array.id] = array
+	return array, nil
+}
+
+// newJob must be called with the lock held.
+func (js *JobSession) newJob(id string, jt drmaa2interface.JobTemplate, outcome Outcome) *Job {
+	now := js.sm.clock.Now()
+	job := &Job{
+		id:        id,
+		session:   js.name,
+		template:  jt,
+		outcome:   outcome,
+		sm:        js.sm,
+		submitted: now,
+		finish:    now.Add(outcome.Duration),
+	}
+	js.sm.jobs = append(js.sm.jobs, job)
+	return job
+}
+
+// WaitAnyStarted returns the first job as fake jobs are running
+// after submission.
+func (js *JobSession) WaitAnyStarted(jobs []drmaa2interface.Job, timeout time.Duration) (drmaa2interface.Job, error) {
+	if len(jobs) == 0 {
+		return nil, drmaa2interface.Error{
+			Message: "no jobs given",
+			ID:      drmaa2interface.InvalidArgument,
+		}
+	}
+	return jobs[0], nil
+}
+
+// WaitAnyTerminated waits until one of the given jobs is finished.
+// Unless the clock is manual it is advanced to the end of the job
+// which finishes first.
+func (js *JobSession) WaitAnyTerminated(jobs []drmaa2interface.Job, timeout time.Duration) (drmaa2interface.Job, error) {
+	if len(jobs) == 0 {
+		return nil, drmaa2interface.Error{
+			Message: "no jobs given",
+			ID:      drmaa2interface.InvalidArgument,
+		}
+	}
+	var terminated drmaa2interface.Job
+	err := js.sm.waitUntil(timeout,
+		func() bool {
+			for _, job := range jobs {
+				if isFinished(job.GetState()) {
+					terminated = job
+					return true
+				}
+			}
+			return false
+		},
+		func() (time.Time, bool) {
+			js.sm.mu.Lock()
+			defer js.sm.mu.Unlock()
+			var next time.Time
+			found := false
+			for _, job := range jobs {
+				fakeJob, ok := job.(*Job)
+				if !ok || fakeJob.suspended {
+					continue
+				}
+				if !found || fakeJob.finish.Before(next) {
+					next, found = fakeJob.finish, true
+				}
+			}
+			return next, found
+		})
+	if err != nil {
+		return nil, err
+	}
+	return terminated, nil
+}
+
+func isFinished(state drmaa2interface.JobState) bool {
+	return state == drmaa2interface.Done || state == drmaa2interface.Failed
+}
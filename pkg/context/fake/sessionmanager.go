@@ -0,0 +1,362 @@
+package fake
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/dgruber/drmaa2interface"
+)
+
+// Outcome defines how a fake job behaves.
+type Outcome struct {
+	// ExitStatus of the job. The job ends in Failed state
+	// when it is not 0.
+	ExitStatus int
+	// Output is returned as stdout of the job.
+	Output string
+	// ErrorOutput is returned as stderr of the job.
+	ErrorOutput string
+	// Duration is the virtual run time of the job.
+	Duration time.Duration
+	// SubmitError lets the submission of the job fail.
+	SubmitError error
+}
+
+// rule assigns outcomes to the job templates it matches. The n-th
+// submission matching the rule gets the n-th outcome, the last
+// outcome is repeated.
+type rule struct {
+	match    func(jt drmaa2interface.JobTemplate) bool
+	outcomes []Outcome
+	calls    int
+}
+
+func (r *rule) next() Outcome {
+	i := r.calls
+	if i >= len(r.outcomes) {
+		i = len(r.outcomes) - 1
+	}
+	r.calls++
+	return r.outcomes[i]
+}
+
+// defaultStart is the initial time of the clock of a new
+// session manager so that job timings are reproducible.
+var defaultStart = time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// SessionManager implements the drmaa2interface.SessionManager in
+// memory. Jobs are not executed; their outcome is defined by the rules
+// added with OnCommand() and On() and their state is derived from the
+// virtual clock.
+//
+// By default the clock advances automatically when a job is waited
+// for, so that workflows run without any delay. With WithManualClock()
+// the clock only moves when Clock().Advance() is called.
+type SessionManager struct {
+	mu             sync.Mutex
+	clock          *Clock
+	manualClock    bool
+	rules          []*rule
+	defaultOutcome Outcome
+	sessions       map[string]*JobSession
+	jobs           []*Job
+	arrays         map[string]*ArrayJob
+	submitted      []drmaa2interface.JobTemplate
+	lastID         int
+	// changed is closed and replaced when a job is terminated,
+	// suspended, or resumed
+	changed chan struct{}
+}
+
+// NewSessionManager creates an in-memory session manager. All jobs
+// succeed immediately without output until rules are added.
+func NewSessionManager() *SessionManager {
+	return &SessionManager{
+		clock:    NewClock(defaultStart),
+		sessions: make(map[string]*JobSession),
+		arrays:   make(map[string]*ArrayJob),
+		changed:  make(chan struct{}),
+	}
+}
+
+// Clock returns the virtual clock of the session manager.
+func (sm *SessionManager) Clock() *Clock {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	return sm.clock
+}
+
+// WithClock replaces the virtual clock. Calls which already wait for
+// jobs keep using the previous clock, hence it should be replaced
+// before jobs are submitted.
+func (sm *SessionManager) WithClock(clock *Clock) *SessionManager {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.clock = clock
+	return sm
+}
+
+// WithManualClock disables advancing the clock when waiting for jobs.
+// Waiting calls block (up to their timeout in real time) until the
+// clock is advanced by the test.
+func (sm *SessionManager) WithManualClock() *SessionManager {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.manualClock = true
+	return sm
+}
+
+// WithDefaultOutcome sets the outcome of jobs which are not
+// matched by any rule.
+func (sm *SessionManager) WithDefaultOutcome(outcome Outcome) *SessionManager {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.defaultOutcome = outcome
+	return sm
+}
+
+// OnCommand defines the outcomes of jobs with the given RemoteCommand.
+// The first submission gets the first outcome, the second submission
+// the second one, and so on. The last outcome is used for all further
+// submissions. This allows to test retries:
+//
+//	sm.OnCommand("flaky.sh", fake.Outcome{ExitStatus: 1}, fake.Outcome{})
+func (sm *SessionManager) OnCommand(command string, outcomes ...Outcome) *SessionManager {
+	return sm.On(func(jt drmaa2interface.JobTemplate) bool {
+		return jt.RemoteCommand == command
+	}, outcomes...)
+}
+
+// On defines the outcomes of jobs whose templates match. Rules are
+// evaluated in the order they were added; the first matching rule
+// determines the outcome.
+func (sm *SessionManager) On(match func(jt drmaa2interface.JobTemplate) bool, outcomes ...Outcome) *SessionManager {
+	if len(outcomes) == 0 {
+		outcomes = []Outcome{{}}
+	}
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.rules = append(sm.rules, &rule{match: match, outcomes: outcomes})
+	return sm
+}
+
+// Submitted returns the templates of all submission attempts in
+// submission order, including the ones which failed. For job arrays
+// there is one template for each array task.
+func (sm *SessionManager) Submitted() []drmaa2interface.JobTemplate {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	submitted := make([]drmaa2interface.JobTemplate, len(sm.submitted))
+	copy(submitted, sm.submitted)
+	return submitted
+}
+
+// outcome returns the outcome of the next submission of the
+// template. Must be called with the lock held.
+func (sm *SessionManager) outcome(jt drmaa2interface.JobTemplate) Outcome {
+	sm.submitted = append(sm.submitted, jt)
+	for _, r := range sm.rules {
+		if r.match(jt) {
+			return r.next()
+		}
+	}
+	return sm.defaultOutcome
+}
+
+// nextID must be called with the lock held.
+func (sm *SessionManager) nextID() string {
+	sm.lastID++
+	return strconv.Itoa(sm.lastID)
+}
+
+// signal wakes up all waiting calls. Must be called
+// with the lock held.
+func (sm *SessionManager) signal() {
+	close(sm.changed)
+	sm.changed = make(chan struct{})
+}
+
+// CreateJobSession creates a new job session.
+func (sm *SessionManager) CreateJobSession(name, contact string) (drmaa2interface.JobSession, error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if _, exists := sm.sessions[name]; exists {
+		return nil, drmaa2interface.Error{
+			Message: fmt.Sprintf("job session %s already exists", name),
+			ID:      drmaa2interface.InvalidArgument,
+		}
+	}
+	js := &JobSession{name: name, contact: contact, sm: sm}
+	sm.sessions[name] = js
+	return js, nil
+}
+
+// OpenJobSession opens an existing job session.
+func (sm *SessionManager) OpenJobSession(name string) (drmaa2interface.JobSession, error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	js, exists := sm.sessions[name]
+	if !exists {
+		return nil, drmaa2interface.Error{
+			Message: fmt.Sprintf("job session %s does not exist", name),
+			ID:      drmaa2interface.InvalidArgument,
+		}
+	}
+	return js, nil
+}
+
+// DestroyJobSession removes the job session and its jobs.
+func (sm *SessionManager) DestroyJobSession(name string) error {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if _, exists := sm.sessions[name]; !exists {
+		return drmaa2interface.Error{
+			Message: fmt.Sprintf("job session %s does not exist", name),
+			ID:      drmaa2interface.InvalidArgument,
+		}
+	}
+	delete(sm.sessions, name)
+	jobs := sm.jobs[:0]
+	for _, job := range sm.jobs {
+		if job.session != name {
+			jobs = append(jobs, job)
+		}
+	}
+	sm.jobs = jobs
+	for id, array := range sm.arrays {
+		if array.session == name {
+			delete(sm.arrays, id)
+		}
+	}
+	return nil
+}
+
+// GetJobSessionNames returns the names of all job sessions.
+func (sm *SessionManager) GetJobSessionNames() ([]string, error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	names := make([]string, 0, len(sm.sessions))
+	for name := range sm.sessions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// GetDrmsName returns "fake".
+func (sm *SessionManager) GetDrmsName() (string, error) {
+	return "fake", nil
+}
+
+// GetDrmsVersion returns the version of the fake backend.
+func (sm *SessionManager) GetDrmsVersion() (drmaa2interface.Version, error) {
+	return drmaa2interface.Version{Major: "1", Minor: "0"}, nil
+}
+
+// Supports returns false as no optional capability is implemented.
+func (sm *SessionManager) Supports(capability drmaa2interface.Capability) bool {
+	return false
+}
+
+// CreateReservationSession is not supported.
+func (sm *SessionManager) CreateReservationSession(name, contact string) (drmaa2interface.ReservationSession, error) {
+	return nil, unsupported("CreateReservationSession")
+}
+
+// OpenMonitoringSession is not supported.
+func (sm *SessionManager) OpenMonitoringSession(name string) (drmaa2interface.MonitoringSession, error) {
+	return nil, unsupported("OpenMonitoringSession")
+}
+
+// OpenReservationSession is not supported.
+func (sm *SessionManager) OpenReservationSession(name string) (drmaa2interface.ReservationSession, error) {
+	return nil, unsupported("OpenReservationSession")
+}
+
+// DestroyReservationSession is not supported.
+func (sm *SessionManager) DestroyReservationSession(name string) error {
+	return unsupported("DestroyReservationSession")
+}
+
+// GetReservationSessionNames is not supported.
+func (sm *SessionManager) GetReservationSessionNames() ([]string, error) {
+	return nil, unsupported("GetReservationSessionNames")
+}
+
+// RegisterEventNotification is not supported.
+func (sm *SessionManager) RegisterEventNotification() (drmaa2interface.EventChannel, error) {
+	return nil, unsupported("RegisterEventNotification")
+}
+
+func unsupported(method string) error {
+	return drmaa2interface.Error{
+		Message: method + "() is not supported by the fake backend",
+		ID:      drmaa2interface.UnsupportedOperation,
+	}
+}
+
+// waitUntil blocks until done() returns true. Unless the clock is
+// manual it is advanced to the time returned by next(), which is when
+// the next job finishes. Otherwise waitUntil blocks until the clock is
+// advanced or the state of a job is changed by someone else. The
+// timeout is virtual time when the clock advances automatically and
+// real time otherwise.
+func (sm *SessionManager) waitUntil(timeout time.Duration, done func() bool, next func() (time.Time, bool)) error {
+	sm.mu.Lock()
+	manual := sm.manualClock
+	clock := sm.clock
+	sm.mu.Unlock()
+
+	start := clock.Now()
+	var deadline <-chan time.Time
+	if manual && timeout != drmaa2interface.InfiniteTime {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+	for {
+		tick := clock.wait()
+		sm.mu.Lock()
+		changed := sm.changed
+		sm.mu.Unlock()
+		if done() {
+			return nil
+		}
+		if timeout == drmaa2interface.ZeroTime {
+			return timeoutError()
+		}
+		if !manual {
+			if t, ok := next(); ok {
+				if timeout != drmaa2interface.InfiniteTime && t.Sub(start) > timeout {
+					clock.advanceTo(start.Add(timeout))
+					return timeoutError()
+				}
+				clock.advanceTo(t)
+				continue
+			}
+			// nothing finishes by itself (suspended jobs) so
+			// wait for changes in real time
+			if deadline == nil && timeout != drmaa2interface.InfiniteTime {
+				timer := time.NewTimer(timeout)
+				defer timer.Stop()
+				deadline = timer.C
+			}
+		}
+		select {
+		case <-tick:
+		case <-changed:
+		case <-deadline:
+			return timeoutError()
+		}
+	}
+}
+
+func timeoutError() error {
+	return drmaa2interface.Error{
+		Message: "timeout while waiting for job",
+		ID:      drmaa2interface.Timeout,
+	}
+}
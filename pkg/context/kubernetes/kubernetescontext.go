@@ -39,6 +39,7 @@ func NewKubernetesContextByCfg(cfg Config) *wfl.Context {
 		DefaultDockerImage: cfg.DefaultImage,
 		CtxCreationErr:     err,
 		DefaultTemplate:    cfg.DefaultTemplate,
+		OutputStreamer:     newOutputFollower(cfg.Namespace),
 	}
 }
 
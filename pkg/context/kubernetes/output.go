@@ -0,0 +1,101 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/dgruber/drmaa2interface"
+	"github.com/dgruber/drmaa2os/pkg/jobtracker/kubernetestracker"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// podStartPollInterval defines how often it is checked if the
+// pod of a job is started so that its logs can be followed.
+const podStartPollInterval = time.Second
+
+// newOutputFollower returns an OutputStreamer which follows the
+// logs of the pod of a job in the given namespace. The returned
+// reader is available immediately; the pod is looked up in the
+// background until it is started or the job is finished.
+func newOutputFollower(namespace string) func(ctx context.Context, job drmaa2interface.Job) (io.ReadCloser, error) {
+	if namespace == "" {
+		namespace = "default"
+	}
+	return func(ctx context.Context, job drmaa2interface.Job) (io.ReadCloser, error) {
+		cs, err := kubernetestracker.NewClientSet()
+		if err != nil {
+			return nil, fmt.Errorf("failed creating kubernetes client: %w", err)
+		}
+		ctx, cancel := context.WithCancel(ctx)
+		reader, writer := io.Pipe()
+		go func() {
+			defer cancel()
+			stream, err := openPodLogs(ctx, cs, namespace, job)
+			if err != nil {
+				writer.CloseWithError(err)
+				return
+			}
+			defer stream.Close()
+			_, err = io.Copy(writer, stream)
+			writer.CloseWithError(err)
+		}()
+		return &followedOutput{PipeReader: reader, cancel: cancel}, nil
+	}
+}
+
+// openPodLogs waits until the pod of the job is started and returns
+// its log stream. It fails when the job is finished without a pod
+// whose logs can be read.
+func openPodLogs(ctx context.Context, cs *kubernetes.Clientset, namespace string, job drmaa2interface.Job) (io.ReadCloser, error) {
+	for {
+		pods, err := cs.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+			LabelSelector: "job-name=" + job.GetID(),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed listing pods of job %s: %w",
+				job.GetID(), err)
+		}
+		// the state is checked before the logs are requested so
+		// that the logs of a just finished pod are not missed
+		state := job.GetState()
+		finished := state == drmaa2interface.Done || state == drmaa2interface.Failed
+		if len(pods.Items) > 0 {
+			stream, err := cs.CoreV1().Pods(namespace).GetLogs(pods.Items[0].Name,
+				&corev1.PodLogOptions{
+					Container: job.GetID(),
+					Follow:    true,
+				}).Stream(ctx)
+			if err == nil {
+				return stream, nil
+			}
+			if finished {
+				return nil, fmt.Errorf("failed following logs of job %s: %w",
+					job.GetID(), err)
+			}
+		} else if finished {
+			return nil, fmt.Errorf("job %s finished in state %s without a pod",
+				job.GetID(), state)
+		}
+		// the pod is not created or the container not started yet
+		select {
+		case <-time.After(podStartPollInterval):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// followedOutput stops following the logs when it is closed.
+type followedOutput struct {
+	*io.PipeReader
+	cancel context.CancelFunc
+}
+
+func (f *followedOutput) Close() error {
+	f.cancel()
+	return f.PipeReader.Close()
+}
@@ -104,3 +104,37 @@ func (kl *Klog) Begin(ctx context.Context, f string) {
 	}
 	klog.InfoDepth(getLogDepth(ctx), fmt.Sprintf("Entry: %s", f))
 }
+
+// BeginWith writes a default log with fields at the beginning of a
+// function.
+func (kl *Klog) BeginWith(ctx context.Context, f string, fields ...Field) {
+	if kl.logLevelThreshold > 1 {
+		return
+	}
+	klog.InfoSDepth(getLogDepth(ctx), fmt.Sprintf("Entry: %s", f), keysAndValues(fields)...)
+}
+
+// Info is used for structured logging at info level.
+func (kl *Klog) Info(ctx context.Context, msg string, fields ...Field) {
+	if kl.logLevelThreshold > 1 {
+		return
+	}
+	klog.InfoSDepth(getLogDepth(ctx), msg, keysAndValues(fields)...)
+}
+
+// Warning is used for structured logging at warning level. klog has
+// no structured warnings, hence the fields are appended to the message.
+func (kl *Klog) Warning(ctx context.Context, msg string, fields ...Field) {
+	if kl.logLevelThreshold > 2 {
+		return
+	}
+	klog.WarningDepth(getLogDepth(ctx), appendFields(msg, fields))
+}
+
+// Error is used for structured logging at error level.
+func (kl *Klog) Error(ctx context.Context, msg string, fields ...Field) {
+	if kl.logLevelThreshold > 3 {
+		return
+	}
+	klog.ErrorSDepth(getLogDepth(ctx), nil, msg, keysAndValues(fields)...)
+}
@@ -14,21 +14,28 @@ type DefaultLogger struct {
 }
 
 func getDefaultLoggerLevel() logrus.Level {
-	switch strings.ToUpper(os.Getenv(logLevelEnv)) {
-	case string(DebugLevel):
-		return logrus.DebugLevel
-	case string(InfoLevel):
-		return logrus.InfoLevel
-	case string(WarningLevel):
-		return logrus.WarnLevel
-	case string(ErrorLevel):
-		return logrus.ErrorLevel
-	case string(NoneLevel):
-		return logrus.PanicLevel
+	if level, ok := getLogrusLevel(LogLevel(strings.ToUpper(os.Getenv(logLevelEnv)))); ok {
+		return level
 	}
 	return logrus.WarnLevel
 }
 
+func getLogrusLevel(level LogLevel) (logrus.Level, bool) {
+	switch level {
+	case DebugLevel:
+		return logrus.DebugLevel, true
+	case InfoLevel:
+		return logrus.InfoLevel, true
+	case WarningLevel:
+		return logrus.WarnLevel, true
+	case ErrorLevel:
+		return logrus.ErrorLevel, true
+	case NoneLevel:
+		return logrus.PanicLevel, true
+	}
+	return logrus.WarnLevel, false
+}
+
 // NewDefaultLogger creates the default logger with settings
 // found in the process environment.
 func NewDefaultLogger() *DefaultLogger {
@@ -44,17 +51,15 @@ func NewDefaultLogger() *DefaultLogger {
 }
 
 func SetLevel(level LogLevel) {
-	switch level {
-	case DebugLevel:
-		logrus.SetLevel(logrus.DebugLevel)
-	case InfoLevel:
-		logrus.SetLevel(logrus.InfoLevel)
-	case WarningLevel:
-		logrus.SetLevel(logrus.WarnLevel)
-	case ErrorLevel:
-		logrus.SetLevel(logrus.ErrorLevel)
-	case NoneLevel:
-		logrus.SetLevel(logrus.PanicLevel)
+	if l, ok := getLogrusLevel(level); ok {
+		logrus.SetLevel(l)
+	}
+}
+
+// SetLogLevel changes the log level of the logger.
+func (dl *DefaultLogger) SetLogLevel(level LogLevel) {
+	if l, ok := getLogrusLevel(level); ok {
+		dl.log.SetLevel(l)
 	}
 }
 
@@ -80,3 +85,32 @@ func (dl *DefaultLogger) Begin(ctx context.Context, f string) {
 	}
 	dl.Infof(ctx, "Entry: %s", f)
 }
+
+// BeginWith writes a default log with fields at the beginning of a
+// function.
+func (dl *DefaultLogger) BeginWith(ctx context.Context, f string, fields ...Field) {
+	dl.log.WithFields(logrusFields(fields)).Infof("Entry: %s", f)
+}
+
+// Info is used for structured logging at info level.
+func (dl *DefaultLogger) Info(ctx context.Context, msg string, fields ...Field) {
+	dl.log.WithFields(logrusFields(fields)).Info(msg)
+}
+
+// Warning is used for structured logging at warning level.
+func (dl *DefaultLogger) Warning(ctx context.Context, msg string, fields ...Field) {
+	dl.log.WithFields(logrusFields(fields)).Warn(msg)
+}
+
+// Error is used for structured logging at error level.
+func (dl *DefaultLogger) Error(ctx context.Context, msg string, fields ...Field) {
+	dl.log.WithFields(logrusFields(fields)).Error(msg)
+}
+
+func logrusFields(fields []Field) logrus.Fields {
+	lf := make(logrus.Fields, len(fields))
+	for _, f := range fields {
+		lf[f.Key] = f.Value
+	}
+	return lf
+}
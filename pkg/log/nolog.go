@@ -38,3 +38,24 @@ func (l *Nolog) Errorf(ctx context.Context, s string, args ...interface{}) {
 func (l *Nolog) Begin(ctx context.Context, f string) {
 	return
 }
+
+// BeginWith writes a default log with fields at the beginning of a
+// function.
+func (l *Nolog) BeginWith(ctx context.Context, f string, fields ...Field) {
+	return
+}
+
+// Info is used for structured logging at info level.
+func (l *Nolog) Info(ctx context.Context, msg string, fields ...Field) {
+	return
+}
+
+// Warning is used for structured logging at warning level.
+func (l *Nolog) Warning(ctx context.Context, msg string, fields ...Field) {
+	return
+}
+
+// Error is used for structured logging at error level.
+func (l *Nolog) Error(ctx context.Context, msg string, fields ...Field) {
+	return
+}
@@ -0,0 +1,119 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"time"
+)
+
+// Slog is a wrapper around a log/slog Logger.
+type Slog struct {
+	logger *slog.Logger
+	// level is the minimum level of the messages which are logged
+	level slog.Level
+}
+
+// levelNone is above all slog levels so that no message is logged.
+const levelNone = slog.LevelError + 1
+
+// NewSlogLogger returns a new instance of a wrapper around the given
+// slog logger; when it is nil slog.Default() is used. Like the other
+// loggers only warnings and errors are logged until the log level
+// is changed with SetLogLevel().
+//
+// Example:
+//
+//	logger, _ := log.NewSlogLogger(slog.New(slog.NewJSONHandler(os.Stderr, nil)))
+//	flow := wfl.NewWorkflow(ctx).SetLogger(logger).SetLogLevel(log.InfoLevel)
+func NewSlogLogger(l *slog.Logger) (Logger, error) {
+	if l == nil {
+		l = slog.Default()
+	}
+	return &Slog{
+		logger: l,
+		level:  slog.LevelWarn,
+	}, nil
+}
+
+func (l *Slog) SetLogLevel(ll LogLevel) {
+	l.level = getSlogLevel(ll)
+}
+
+func getSlogLevel(ll LogLevel) slog.Level {
+	switch ll {
+	case DebugLevel:
+		return slog.LevelDebug
+	case InfoLevel:
+		return slog.LevelInfo
+	case WarningLevel:
+		return slog.LevelWarn
+	case ErrorLevel:
+		return slog.LevelError
+	case NoneLevel:
+		return levelNone
+	}
+	return slog.LevelWarn
+}
+
+// Infof is used for logging at info level.
+func (l *Slog) Infof(ctx context.Context, s string, args ...interface{}) {
+	l.log(ctx, slog.LevelInfo, fmt.Sprintf(s, args...), nil)
+}
+
+// Warningf is used for logging at warning level.
+func (l *Slog) Warningf(ctx context.Context, s string, args ...interface{}) {
+	l.log(ctx, slog.LevelWarn, fmt.Sprintf(s, args...), nil)
+}
+
+// Errorf is used for logging at error level.
+func (l *Slog) Errorf(ctx context.Context, s string, args ...interface{}) {
+	l.log(ctx, slog.LevelError, fmt.Sprintf(s, args...), nil)
+}
+
+// Begin writes a default log at the beginning of a function.
+func (l *Slog) Begin(ctx context.Context, f string) {
+	l.log(ctx, slog.LevelInfo, "Entry: "+f, nil)
+}
+
+// BeginWith writes a default log with fields at the beginning of a
+// function.
+func (l *Slog) BeginWith(ctx context.Context, f string, fields ...Field) {
+	l.log(ctx, slog.LevelInfo, "Entry: "+f, fields)
+}
+
+// Info is used for structured logging at info level.
+func (l *Slog) Info(ctx context.Context, msg string, fields ...Field) {
+	l.log(ctx, slog.LevelInfo, msg, fields)
+}
+
+// Warning is used for structured logging at warning level.
+func (l *Slog) Warning(ctx context.Context, msg string, fields ...Field) {
+	l.log(ctx, slog.LevelWarn, msg, fields)
+}
+
+// Error is used for structured logging at error level.
+func (l *Slog) Error(ctx context.Context, msg string, fields ...Field) {
+	l.log(ctx, slog.LevelError, msg, fields)
+}
+
+// log passes the record to the handler of the slog logger. The source
+// of the record is the user function which called wfl, like for klog
+// (see getLogDepth()).
+func (l *Slog) log(ctx context.Context, level slog.Level, msg string, fields []Field) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if level < l.level || !l.logger.Enabled(ctx, level) {
+		return
+	}
+	var pcs [1]uintptr
+	// skip runtime.Callers, log, and the exported method of Slog
+	runtime.Callers(getLogDepth(ctx)+2, pcs[:])
+	r := slog.NewRecord(time.Now(), level, msg, pcs[0])
+	for _, f := range fields {
+		r.AddAttrs(slog.Any(f.Key, f.Value))
+	}
+	_ = l.logger.Handler().Handle(ctx, r)
+}
@@ -0,0 +1,94 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Keys of the fields which are added by wfl to its log messages.
+const (
+	JobIDKey   = "job_id"
+	TagKey     = "tag"
+	SessionKey = "session"
+	CommandKey = "command"
+	// AttemptKey is the attempt of the task, starting with 1 and
+	// increased with each resubmission.
+	AttemptKey = "attempt"
+)
+
+// Field is a key/value pair which is attached to a log message.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// F creates a field.
+func F(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
+}
+
+// StructuredLogger is a Logger which keeps the fields of log messages
+// separate from the message so that they can be indexed by the logging
+// backend. wfl uses these methods when the logger implements them.
+type StructuredLogger interface {
+	Logger
+	// BeginWith is Begin with fields.
+	BeginWith(ctx context.Context, f string, fields ...Field)
+	Info(ctx context.Context, msg string, fields ...Field)
+	Warning(ctx context.Context, msg string, fields ...Field)
+	Error(ctx context.Context, msg string, fields ...Field)
+}
+
+// Structured returns the logger as StructuredLogger. Loggers which only
+// implement Logger get the fields appended as key=value to the message.
+func Structured(l Logger) StructuredLogger {
+	if sl, ok := l.(StructuredLogger); ok {
+		return sl
+	}
+	return &printfLogger{Logger: l}
+}
+
+// printfLogger adds the structured methods to a Logger.
+type printfLogger struct {
+	Logger
+}
+
+// BeginWith calls Begin of the logger; the fields are left out.
+func (l *printfLogger) BeginWith(ctx context.Context, f string, fields ...Field) {
+	l.Begin(ctx, f)
+}
+
+func (l *printfLogger) Info(ctx context.Context, msg string, fields ...Field) {
+	l.Infof(ctx, "%s", appendFields(msg, fields))
+}
+
+func (l *printfLogger) Warning(ctx context.Context, msg string, fields ...Field) {
+	l.Warningf(ctx, "%s", appendFields(msg, fields))
+}
+
+func (l *printfLogger) Error(ctx context.Context, msg string, fields ...Field) {
+	l.Errorf(ctx, "%s", appendFields(msg, fields))
+}
+
+// appendFields appends the fields as key=value to the message.
+func appendFields(msg string, fields []Field) string {
+	if len(fields) == 0 {
+		return msg
+	}
+	var sb strings.Builder
+	sb.WriteString(msg)
+	for _, f := range fields {
+		fmt.Fprintf(&sb, " %s=%v", f.Key, f.Value)
+	}
+	return sb.String()
+}
+
+// keysAndValues returns the fields as alternating keys and values.
+func keysAndValues(fields []Field) []interface{} {
+	kv := make([]interface{}, 0, 2*len(fields))
+	for _, f := range fields {
+		kv = append(kv, f.Key, f.Value)
+	}
+	return kv
+}
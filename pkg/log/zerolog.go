@@ -59,3 +59,31 @@ func (l *Zerolog) Errorf(ctx context.Context, s string, args ...interface{}) {
 func (l *Zerolog) Begin(ctx context.Context, f string) {
 	l.logger.Info().Msg(f)
 }
+
+// BeginWith writes a default log with fields at the begining of a
+// function.
+func (l *Zerolog) BeginWith(ctx context.Context, f string, fields ...Field) {
+	withFields(l.logger.Info(), fields).Msg(f)
+}
+
+// Info is used for structured logging at info level.
+func (l *Zerolog) Info(ctx context.Context, msg string, fields ...Field) {
+	withFields(l.logger.Info(), fields).Msg(msg)
+}
+
+// Warning is used for structured logging at warning level.
+func (l *Zerolog) Warning(ctx context.Context, msg string, fields ...Field) {
+	withFields(l.logger.Warn(), fields).Msg(msg)
+}
+
+// Error is used for structured logging at error level.
+func (l *Zerolog) Error(ctx context.Context, msg string, fields ...Field) {
+	withFields(l.logger.Error(), fields).Msg(msg)
+}
+
+func withFields(e *zerolog.Event, fields []Field) *zerolog.Event {
+	for _, f := range fields {
+		e = e.Interface(f.Key, f.Value)
+	}
+	return e
+}
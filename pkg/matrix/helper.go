@@ -5,6 +5,7 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/dgruber/drmaa2interface"
 	"github.com/mitchellh/copystructure"
@@ -43,6 +44,22 @@ func CopyJobTemplate(jt drmaa2interface.JobTemplate) (drmaa2interface.JobTemplat
 	return out, err
 }
 
+// AllStrings is a special field name for ReplaceInField() which
+// replaces the pattern in all string, string slice, and string map
+// fields of the job template (including the extension list).
+const AllStrings = "allStrings"
+
+// ReplaceInField returns a copy of the job template where the pattern in
+// the given field is replaced. How the replacement is applied depends on
+// the type of the field:
+//
+//   - string, []string, map[string]string: each occurrence of the pattern
+//     is replaced (in map keys and values)
+//   - bool: the field is set to the parsed replacement ("true", "false")
+//   - int64: the field is set to the parsed replacement
+//   - time.Time: the field is set to the parsed replacement (see ParseTime())
+//
+// The field "ExtensionList" addresses the extension list of the template.
 func ReplaceInField(jt drmaa2interface.JobTemplate, fieldName, pattern, replacement string) (drmaa2interface.JobTemplate, error) {
 
 	copyJT, err := CopyJobTemplate(jt)
@@ -50,12 +67,65 @@ func ReplaceInField(jt drmaa2interface.JobTemplate, fieldName, pattern, replacem
 		return jt, fmt.Errorf("error copying job template: %s", err)
 	}
 
-	jtValue := reflect.ValueOf(&copyJT)
+	jtValue := reflect.Indirect(reflect.ValueOf(&copyJT))
+
+	if fieldName == AllStrings {
+		for _, name := range stringFieldNames(jtValue.Type()) {
+			err := replaceInValue(jtValue.FieldByName(name), name, pattern, replacement)
+			if err != nil {
+				return jt, err
+			}
+		}
+		return copyJT, nil
+	}
 
-	jtField := reflect.Indirect(jtValue).FieldByName(fieldName)
+	jtField := jtValue.FieldByName(fieldName)
 	if !jtField.IsValid() {
 		return jt, fmt.Errorf("unknown JobTemplate field name %s", fieldName)
 	}
+	if err := replaceInValue(jtField, fieldName, pattern, replacement); err != nil {
+		return jt, err
+	}
+	return copyJT, nil
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	stringMapType = reflect.TypeOf(map[string]string{})
+	stringsType   = reflect.TypeOf([]string{})
+)
+
+// stringFieldNames returns the names of all string, string slice, and
+// string map fields including the ones of embedded structs.
+func stringFieldNames(t reflect.Type) []string {
+	names := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			names = append(names, stringFieldNames(field.Type)...)
+			continue
+		}
+		if field.Type.Kind() == reflect.String || field.Type == stringsType ||
+			field.Type == stringMapType {
+			names = append(names, field.Name)
+		}
+	}
+	return names
+}
+
+func replaceInValue(jtField reflect.Value, fieldName, pattern, replacement string) error {
+	if jtField.Type() == timeType {
+		t, err := ParseTime(replacement, time.Now())
+		if err != nil {
+			return fmt.Errorf("replacement %s for field %s is not a time: %v",
+				replacement, fieldName, err)
+		}
+		jtField.Set(reflect.ValueOf(t))
+		return nil
+	}
 
 	switch jtField.Kind() {
 	case reflect.String:
@@ -66,23 +136,24 @@ func ReplaceInField(jt drmaa2interface.JobTemplate, fieldName, pattern, replacem
 		// for bool fields set to true or false deping if replacements is a bool value
 		bV, err := strconv.ParseBool(replacement)
 		if err != nil {
-			return jt, fmt.Errorf("replacement %s for field %s is not a bool value: %v",
+			return fmt.Errorf("replacement %s for field %s is not a bool value: %v",
 				replacement, fieldName, err)
 		}
 		jtField.SetBool(bV)
-	case reflect.Int32, reflect.Int64:
+	case reflect.Int, reflect.Int32, reflect.Int64:
 		// for int fields set to the int value of the replacement
-		i, err := strconv.Atoi(replacement)
+		i, err := strconv.ParseInt(strings.TrimSpace(replacement), 10, 64)
 		if err != nil {
-			return jt, fmt.Errorf("failed to convert %s to int: %s", jtField.String(), err)
+			return fmt.Errorf("replacement %s for field %s is not an int value: %v",
+				replacement, fieldName, err)
 		}
-		jtField.SetInt(int64(i))
+		jtField.SetInt(i)
 	case reflect.Slice:
 		// for slice fields replace each occurrence of the pattern
+		if jtField.Type() != stringsType {
+			return fmt.Errorf("field %s is not a string slice", fieldName)
+		}
 		if jtField.Len() > 0 {
-			if jtField.Index(0).Kind() != reflect.String {
-				return jt, fmt.Errorf("field %s is not a string slice", fieldName)
-			}
 			v := make([]string, 0)
 			for _, s := range jtField.Interface().([]string) {
 				v = append(v, strings.Replace(s, pattern, replacement, -1))
@@ -92,21 +163,44 @@ func ReplaceInField(jt drmaa2interface.JobTemplate, fieldName, pattern, replacem
 	case reflect.Map:
 		// for map fields replace each occurrence of the pattern found in any key
 		// or value. Expects a map of strings.
-		iter := jtField.MapRange()
+		if jtField.Type() != stringMapType {
+			return fmt.Errorf("unsupported map type %s for field %s",
+				jtField.Type(), fieldName)
+		}
+		if jtField.Len() == 0 {
+			return nil
+		}
 		m := map[string]string{}
-		for iter.Next() {
-			if iter.Key().Kind() != reflect.String || iter.Value().Kind() != reflect.String {
-				return jt, fmt.Errorf("unsupported map type %s or value %s for field %s",
-					iter.Key().Type(), iter.Value().Type(), fieldName)
-			}
+		for k, v := range jtField.Interface().(map[string]string) {
 			// key and value are strings - try replacing pattern in key and value
-			k := strings.Replace(iter.Key().String(), pattern, replacement, -1)
-			v := strings.Replace(iter.Value().String(), pattern, replacement, -1)
-			m[k] = v
+			m[strings.Replace(k, pattern, replacement, -1)] =
+				strings.Replace(v, pattern, replacement, -1)
 		}
 		jtField.Set(reflect.ValueOf(m))
 	default:
-		return jt, fmt.Errorf("unsupported field type %s", jtField.Kind())
+		return fmt.Errorf("unsupported field type %s", jtField.Kind())
 	}
-	return copyJT, nil
+	return nil
+}
+
+// ParseTime parses the value of a time field. Supported are RFC 3339
+// timestamps ("2024-01-02T15:04:05Z"), Unix timestamps in seconds
+// ("1704207845"), "now", and durations relative to now ("+2h", "-30m",
+// or "90s").
+func ParseTime(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "now" {
+		return now, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+	d, err := time.ParseDuration(strings.TrimPrefix(value, "+"))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is neither RFC 3339, a Unix timestamp, nor a duration", value)
+	}
+	return now.Add(d), nil
 }
@@ -1,6 +1,8 @@
 package matrix_test
 
 import (
+	"time"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
@@ -148,6 +150,86 @@ var _ = Describe("Helper", func() {
 			Expect(jt.JobEnvironment["new2"]).To(Equal("new"))
 		})
 
+		It("should set time fields", func() {
+			now := time.Now()
+			jt, err := matrix.ReplaceInField(drmaa2interface.JobTemplate{},
+				"StartTime", "", "2030-01-02T03:04:05Z")
+			Expect(err).To(BeNil())
+			Expect(jt.StartTime.UTC()).To(Equal(time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)))
+
+			jt, err = matrix.ReplaceInField(jt, "DeadlineTime", "", "+2h")
+			Expect(err).To(BeNil())
+			Expect(jt.DeadlineTime).To(BeTemporally("~", now.Add(2*time.Hour), time.Minute))
+
+			_, err = matrix.ReplaceInField(jt, "DeadlineTime", "", "tomorrow")
+			Expect(err).NotTo(BeNil())
+		})
+
+		It("should replace in nested and nil fields", func() {
+			t := drmaa2interface.JobTemplate{
+				RemoteCommand:  "{{x}}.sh",
+				ResourceLimits: map[string]string{"h_rt": "{{x}}"},
+			}
+			t.ExtensionList = map[string]string{"image": "img:{{x}}"}
+
+			jt, err := matrix.ReplaceInField(t, "ExtensionList", "{{x}}", "v1")
+			Expect(err).To(BeNil())
+			Expect(jt.ExtensionList["image"]).To(Equal("img:v1"))
+			Expect(t.ExtensionList["image"]).To(Equal("img:{{x}}"))
+
+			jt, err = matrix.ReplaceInField(t, "StageInFiles", "{{x}}", "v1")
+			Expect(err).To(BeNil())
+			Expect(jt.StageInFiles).To(BeNil())
+
+			jt, err = matrix.ReplaceInField(t, "MinPhysMemory", "", "1024")
+			Expect(err).To(BeNil())
+			Expect(jt.MinPhysMemory).To(BeNumerically("==", 1024))
+
+			_, err = matrix.ReplaceInField(t, "MinPhysMemory", "", "1G")
+			Expect(err).NotTo(BeNil())
+
+			_, err = matrix.ReplaceInField(t, "NoSuchField", "", "1")
+			Expect(err).NotTo(BeNil())
+		})
+
+		It("should replace in all string fields", func() {
+			t := drmaa2interface.JobTemplate{
+				RemoteCommand:  "{{x}}.sh",
+				Args:           []string{"--x={{x}}"},
+				ResourceLimits: map[string]string{"h_rt": "{{x}}"},
+			}
+			t.ExtensionList = map[string]string{"image": "img:{{x}}"}
+			jt, err := matrix.ReplaceInField(t, matrix.AllStrings, "{{x}}", "60")
+			Expect(err).To(BeNil())
+			Expect(jt.RemoteCommand).To(Equal("60.sh"))
+			Expect(jt.Args).To(Equal([]string{"--x=60"}))
+			Expect(jt.ResourceLimits["h_rt"]).To(Equal("60"))
+			Expect(jt.ExtensionList["image"]).To(Equal("img:60"))
+		})
+
+	})
+
+	Context("ExecuteTemplate", func() {
+
+		It("should compute values from the coordinate", func() {
+			values := map[string]string{"{{epochs}}": "3", "{{lr}}": "0.5"}
+			v, err := matrix.ExecuteTemplate("{{mul .epochs 100}}", values)
+			Expect(err).To(BeNil())
+			Expect(v).To(Equal("300"))
+			v, err = matrix.ExecuteTemplate("{{div .lr 2}}-{{add .epochs 1}}-{{sub .lr 1}}", values)
+			Expect(err).To(BeNil())
+			Expect(v).To(Equal("0.25-4--0.5"))
+		})
+
+		It("should fail for unknown names and invalid numbers", func() {
+			_, err := matrix.ExecuteTemplate("{{.unknown}}", map[string]string{})
+			Expect(err).NotTo(BeNil())
+			_, err = matrix.ExecuteTemplate(`{{mul .x 2}}`, map[string]string{"x": "a"})
+			Expect(err).NotTo(BeNil())
+			_, err = matrix.ExecuteTemplate(`{{div .x 0}}`, map[string]string{"x": "1"})
+			Expect(err).NotTo(BeNil())
+		})
+
 	})
 
 })
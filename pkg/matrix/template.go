@@ -0,0 +1,120 @@
+package matrix
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// PatternName returns the name of a replacement pattern under which its
+// value is available in templates executed by ExecuteTemplate(). It is
+// the pattern without surrounding braces and spaces, i.e. "{{lr}}"
+// becomes "lr".
+func PatternName(pattern string) string {
+	return strings.TrimSpace(strings.Trim(strings.TrimSpace(pattern), "{}"))
+}
+
+// ExecuteTemplate computes a replacement value by executing the given
+// Go text/template. The values map the patterns to their values; in the
+// template each value is accessed by the name of its pattern (see
+// PatternName()).
+//
+// Besides the text/template builtins the functions add, sub, mul, and
+// div are available. They accept numbers as well as strings containing
+// numbers. If all arguments are integers the result is an integer.
+//
+// Example: "{{mul .epochs 100}}" with values {"{{epochs}}": "3"} is "300".
+func ExecuteTemplate(text string, values map[string]string) (string, error) {
+	t, err := template.New("replacement").Funcs(templateFuncs).
+		Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("failed parsing template %q: %w", text, err)
+	}
+	data := make(map[string]string, len(values))
+	for pattern, value := range values {
+		data[PatternName(pattern)] = value
+	}
+	var out bytes.Buffer
+	if err := t.Execute(&out, data); err != nil {
+		return "", fmt.Errorf("failed executing template %q: %w", text, err)
+	}
+	return out.String(), nil
+}
+
+var templateFuncs = template.FuncMap{
+	"add": func(a, b interface{}) (string, error) {
+		return arithmetic(a, b, func(x, y int64) int64 { return x + y },
+			func(x, y float64) float64 { return x + y })
+	},
+	"sub": func(a, b interface{}) (string, error) {
+		return arithmetic(a, b, func(x, y int64) int64 { return x - y },
+			func(x, y float64) float64 { return x - y })
+	},
+	"mul": func(a, b interface{}) (string, error) {
+		return arithmetic(a, b, func(x, y int64) int64 { return x * y },
+			func(x, y float64) float64 { return x * y })
+	},
+	"div": func(a, b interface{}) (string, error) {
+		y, err := toFloat(b)
+		if err != nil {
+			return "", err
+		}
+		if y == 0 {
+			return "", fmt.Errorf("division by zero")
+		}
+		return arithmetic(a, b, nil, func(x, y float64) float64 { return x / y })
+	},
+}
+
+// arithmetic applies the integer operation when both arguments are
+// integers and the operation is set, otherwise the float operation.
+func arithmetic(a, b interface{}, intOp func(x, y int64) int64, floatOp func(x, y float64) float64) (string, error) {
+	if intOp != nil {
+		x, errX := toInt(a)
+		y, errY := toInt(b)
+		if errX == nil && errY == nil {
+			return strconv.FormatInt(intOp(x, y), 10), nil
+		}
+	}
+	x, err := toFloat(a)
+	if err != nil {
+		return "", err
+	}
+	y, err := toFloat(b)
+	if err != nil {
+		return "", err
+	}
+	return strconv.FormatFloat(floatOp(x, y), 'g', -1, 64), nil
+}
+
+func toInt(v interface{}) (int64, error) {
+	switch n := v.(type) {
+	case int:
+		return int64(n), nil
+	case int64:
+		return n, nil
+	case string:
+		return strconv.ParseInt(strings.TrimSpace(n), 10, 64)
+	}
+	return 0, fmt.Errorf("%v is not an integer", v)
+}
+
+func toFloat(v interface{}) (float64, error) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), nil
+	case int64:
+		return float64(n), nil
+	case float64:
+		return n, nil
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(n), 64)
+		if err != nil {
+			return 0, fmt.Errorf("%q is not a number", n)
+		}
+		return f, nil
+	}
+	return 0, fmt.Errorf("%v is not a number", v)
+}
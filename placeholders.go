@@ -0,0 +1,155 @@
+package wfl
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/dgruber/drmaa2interface"
+	"github.com/mitchellh/copystructure"
+)
+
+// Placeholders are the values which can be used in the string fields
+// (including string slices and string maps but not the ExtensionList)
+// of a job template as Go text/template placeholders. They are replaced
+// before each submission of a task. Fields which cannot be expanded are
+// submitted as they are and a warning is logged.
+//
+// Text which must not be expanded, like the format of "docker ps", is
+// escaped with a template string: {{"{{.ID}}"}} becomes {{.ID}}.
+//
+// Example:
+//
+//	drmaa2interface.JobTemplate{
+//		RemoteCommand: "train.sh",
+//		Args:          []string{"--run={{.Session}}-{{.ID}}", "--seed={{.Retry}}"},
+//		OutputPath:    "/tmp/{{.Tag}}-{{.ID}}.out",
+//		JobName:       "{{.Vars.project}}-{{.Timestamp}}",
+//	}
+type Placeholders struct {
+	// ID is the task ID of the context which is incremented for
+	// each submitted task (see Context.ContextTaskID).
+	ID int64
+	// ArrayIndex is the index of a job array task. When the job array
+	// is submitted as a whole the index is not known yet; then it is
+	// the reference "${TASK_ID}" to the environment variable containing
+	// the index, which is expanded by the shell of the task. For tasks
+	// which are not part of a job array it is empty.
+	ArrayIndex string
+	// Tag of the job (see TagWith()).
+	Tag string
+	// Session is the name of the job session of the workflow.
+	Session string
+	// Retry is 0 for the first submission of a task and increased
+	// for each retry or resubmission.
+	Retry int
+	// Time is the submission time of the task.
+	Time time.Time
+	// Timestamp is the submission time as Unix timestamp in seconds.
+	Timestamp int64
+	// Vars are the custom variables of the context (see
+	// Context.WithVariable()).
+	Vars map[string]string
+}
+
+// expandPlaceholders replaces the placeholders in all string fields of
+// the job template. Fields which are not a valid template or which use
+// unknown placeholders are kept as they are; their errors are returned.
+func expandPlaceholders(jt drmaa2interface.JobTemplate, p Placeholders) (drmaa2interface.JobTemplate, error) {
+	if jtCopy, err := copystructure.Copy(jt); err == nil {
+		jt = jtCopy.(drmaa2interface.JobTemplate)
+	}
+	var errs []error
+	expand := func(input string) string {
+		output, err := expandPlaceholder(input, p)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		return output
+	}
+	v := reflect.ValueOf(&jt).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		if v.Type().Field(i).Anonymous {
+			// skip the extension list
+			continue
+		}
+		switch value := field.Interface().(type) {
+		case string:
+			field.SetString(expand(value))
+		case []string:
+			for i := range value {
+				value[i] = expand(value[i])
+			}
+		case map[string]string:
+			if value == nil {
+				continue
+			}
+			expanded := make(map[string]string, len(value))
+			for k, v := range value {
+				expanded[expand(k)] = expand(v)
+			}
+			field.Set(reflect.ValueOf(expanded))
+		}
+	}
+	return jt, errors.Join(errs...)
+}
+
+func expandPlaceholder(input string, p Placeholders) (string, error) {
+	if !strings.Contains(input, "{{") {
+		return input, nil
+	}
+	tmpl, err := template.New("placeholder").Option("missingkey=error").Parse(input)
+	if err != nil {
+		return input, fmt.Errorf("%q: %w", input, err)
+	}
+	out := bytes.Buffer{}
+	if err := tmpl.Execute(&out, p); err != nil {
+		return input, fmt.Errorf("%q: %w", input, err)
+	}
+	return out.String(), nil
+}
+
+// placeholders returns the values of the placeholders for the next
+// submission of a task of the job. It increments the task ID of the
+// context.
+func (j *Job) placeholders(retry int, arrayIndex string) Placeholders {
+	now := time.Now()
+	p := Placeholders{
+		ID:         j.wfl.ctx.GetNextContextTaskID(),
+		ArrayIndex: arrayIndex,
+		Tag:        j.tag,
+		Retry:      retry,
+		Time:       now,
+		Timestamp:  now.Unix(),
+		Vars:       j.wfl.ctx.variables(),
+	}
+	if j.wfl.js != nil {
+		p.Session, _ = j.wfl.js.GetSessionName()
+	}
+	if p.Session == "" {
+		p.Session = j.wfl.ctx.JobSessionName
+	}
+	return p
+}
+
+// expand replaces the placeholders of the job template for the next
+// submission. The index of job array tasks which are resubmitted
+// individually is taken from their job environment.
+func (j *Job) expand(jt drmaa2interface.JobTemplate, retry int) drmaa2interface.JobTemplate {
+	return j.expandWith(jt, j.placeholders(retry, jt.JobEnvironment[arrayTaskIDEnv]))
+}
+
+// expandWith replaces the placeholders of the job template with the
+// given values. Fields which cannot be expanded are logged.
+func (j *Job) expandWith(jt drmaa2interface.JobTemplate, p Placeholders) drmaa2interface.JobTemplate {
+	expanded, err := expandPlaceholders(jt, p)
+	if err != nil {
+		j.warningf(j.ctx, "placeholders of job template not expanded: %v", err)
+	}
+	return expanded
+}
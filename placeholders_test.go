@@ -0,0 +1,112 @@
+package wfl_test
+
+import (
+	"strconv"
+
+	"github.com/dgruber/drmaa2interface"
+	"github.com/dgruber/wfl"
+	"github.com/dgruber/wfl/pkg/context/fake"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Placeholders", func() {
+
+	var (
+		sm   *fake.SessionManager
+		ctx  *wfl.Context
+		flow *wfl.Workflow
+	)
+
+	BeforeEach(func() {
+		sm = fake.NewSessionManager()
+		ctx = fake.NewFakeContextByCfg(fake.Config{SessionManager: sm}).
+			WithSessionName("session").
+			WithVariable("project", "mnist")
+		flow = wfl.NewWorkflow(ctx)
+		Ω(flow.HasError()).Should(BeFalse())
+	})
+
+	It("should replace the placeholders in all string fields", func() {
+		jt := drmaa2interface.JobTemplate{
+			RemoteCommand:    "{{.Vars.project}}.sh",
+			Args:             []string{"--id={{.ID}}", "{{.Session}}"},
+			JobEnvironment:   map[string]string{"TAG_{{.ID}}": "{{.Tag}}"},
+			WorkingDirectory: "/work/{{.Vars.project}}",
+			JobName:          "{{.Tag}}-{{.Retry}}",
+			OutputPath:       "/tmp/{{.ID}}.out",
+			ErrorPath:        "/tmp/{{.ID}}.err",
+			StageInFiles:     map[string]string{"/data/{{.Vars.project}}": "/data"},
+		}
+		job := flow.NewJob().TagWith("train").RunT(jt)
+		Ω(job.LastError()).Should(BeNil())
+
+		submitted := sm.Submitted()[0]
+		id := strconv.FormatInt(ctx.ContextTaskID, 10)
+		Ω(submitted.RemoteCommand).Should(Equal("mnist.sh"))
+		Ω(submitted.Args).Should(Equal([]string{"--id=" + id, "session"}))
+		Ω(submitted.JobEnvironment).Should(Equal(map[string]string{"TAG_" + id: "train"}))
+		Ω(submitted.WorkingDirectory).Should(Equal("/work/mnist"))
+		Ω(submitted.JobName).Should(Equal("train-0"))
+		Ω(submitted.OutputPath).Should(Equal("/tmp/" + id + ".out"))
+		Ω(submitted.ErrorPath).Should(Equal("/tmp/" + id + ".err"))
+		Ω(submitted.StageInFiles).Should(HaveKey("/data/mnist"))
+		// the job template of the task is the submitted one
+		Ω(job.Template().JobName).Should(Equal("train-0"))
+	})
+
+	It("should keep unknown placeholders", func() {
+		jt := drmaa2interface.JobTemplate{
+			RemoteCommand: "docker",
+			Args:          []string{"inspect", "--format", "{{.State.Status}}", "{{lr}}"},
+		}
+		logger := &printfLogger{}
+		flow.SetLogger(logger).RunT(jt)
+		Ω(sm.Submitted()[0].Args).Should(Equal(jt.Args))
+		Ω(logger.warnings).Should(HaveLen(1))
+		Ω(logger.warnings[0]).Should(ContainSubstring("{{.State.Status}}"))
+		Ω(logger.warnings[0]).Should(ContainSubstring("{{lr}}"))
+	})
+
+	It("should not expand escaped placeholders", func() {
+		jt := drmaa2interface.JobTemplate{
+			RemoteCommand: "docker",
+			Args:          []string{"ps", "--format", `{{"{{.ID}}"}}`, `{{"{{"}}.}}`},
+		}
+		flow.RunT(jt)
+		Ω(sm.Submitted()[0].Args).Should(Equal([]string{"ps", "--format", "{{.ID}}", "{{.}}"}))
+	})
+
+	It("should replace the placeholders again when a task is retried", func() {
+		sm.OnCommand("train.sh", fake.Outcome{ExitStatus: 1}, fake.Outcome{})
+		job := flow.RunT(drmaa2interface.JobTemplate{
+			RemoteCommand: "train.sh",
+			Args:          []string{"--attempt={{.Retry}}"},
+		}).RetryAnyFailed(1)
+		Ω(job.Success()).Should(BeTrue())
+
+		submitted := sm.Submitted()
+		Ω(submitted).Should(HaveLen(2))
+		Ω(submitted[0].Args).Should(Equal([]string{"--attempt=0"}))
+		Ω(submitted[1].Args).Should(Equal([]string{"--attempt=1"}))
+	})
+
+	It("should replace the array index of job array tasks", func() {
+		sm.On(func(jt drmaa2interface.JobTemplate) bool {
+			return jt.JobEnvironment["TASK_ID"] == "2"
+		}, fake.Outcome{ExitStatus: 1}, fake.Outcome{})
+		job := flow.NewJob().RunArrayT(1, 2, 1, 2, drmaa2interface.JobTemplate{
+			RemoteCommand: "task.sh",
+			Args:          []string{"--index={{.ArrayIndex}}"},
+		}).RetryAnyFailed(1)
+		Ω(job.Success()).Should(BeTrue())
+
+		submitted := sm.Submitted()
+		Ω(submitted).Should(HaveLen(3))
+		Ω(submitted[0].Args).Should(Equal([]string{"--index=${TASK_ID}"}))
+		// the retried array task knows its index
+		Ω(submitted[2].Args).Should(Equal([]string{"--index=2"}))
+	})
+
+})
@@ -0,0 +1,279 @@
+package wfl
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dgruber/drmaa2interface"
+)
+
+// TaskResult is the record of one task of a job. For job arrays
+// there is one record for each array task.
+type TaskResult struct {
+	// Tag of the job the task belongs to.
+	Tag string
+	// JobID is the ID of the task in the backend. Empty if the
+	// task could not be submitted.
+	JobID string
+	// ArrayJobID is set when the task is part of a job array.
+	ArrayJobID string
+	// Template is the job template the task was submitted with.
+	Template drmaa2interface.JobTemplate
+	// State of the task at the time the result was created.
+	State drmaa2interface.JobState
+	// ExitStatus of the task when it is finished.
+	ExitStatus int
+	// SubmitError is set if the task could not be submitted.
+	SubmitError error
+	// TerminationError is set if waiting for the end of the task failed.
+	TerminationError error
+	// Retry is the amount of resubmissions of the task.
+	Retry int
+	// Coordinates contains the value of each replacement pattern
+	// when the task was submitted by a matrix run.
+	Coordinates map[string]string
+	// Timings from the JobInfo of the task when available.
+	SubmissionTime time.Time
+	DispatchTime   time.Time
+	FinishTime     time.Time
+	WallclockTime  time.Duration
+}
+
+// Failed returns true if the task could not be submitted or ended
+// in failed state.
+func (r TaskResult) Failed() bool {
+	return r.SubmitError != nil || r.State == drmaa2interface.Failed
+}
+
+type taskResultJSON struct {
+	Tag              string                      `json:"tag,omitempty"`
+	JobID            string                      `json:"jobID,omitempty"`
+	ArrayJobID       string                      `json:"arrayJobID,omitempty"`
+	Template         drmaa2interface.JobTemplate `json:"template"`
+	State            string                      `json:"state"`
+	ExitStatus       int                         `json:"exitStatus"`
+	SubmitError      string                      `json:"submitError,omitempty"`
+	TerminationError string                      `json:"terminationError,omitempty"`
+	Retry            int                         `json:"retry"`
+	Coordinates      map[string]string           `json:"coordinates,omitempty"`
+	SubmissionTime   *time.Time                  `json:"submissionTime,omitempty"`
+	DispatchTime     *time.Time                  `json:"dispatchTime,omitempty"`
+	FinishTime       *time.Time                  `json:"finishTime,omitempty"`
+	WallclockTime    string                      `json:"wallclockTime,omitempty"`
+}
+
+// MarshalJSON encodes the result with the state and errors as strings.
+func (r TaskResult) MarshalJSON() ([]byte, error) {
+	out := taskResultJSON{
+		Tag:            r.Tag,
+		JobID:          r.JobID,
+		ArrayJobID:     r.ArrayJobID,
+		Template:       r.Template,
+		State:          r.State.String(),
+		ExitStatus:     r.ExitStatus,
+		Retry:          r.Retry,
+		Coordinates:    r.Coordinates,
+		SubmissionTime: timeOrNil(r.SubmissionTime),
+		DispatchTime:   timeOrNil(r.DispatchTime),
+		FinishTime:     timeOrNil(r.FinishTime),
+	}
+	if r.SubmitError != nil {
+		out.SubmitError = r.SubmitError.Error()
+	}
+	if r.TerminationError != nil {
+		out.TerminationError = r.TerminationError.Error()
+	}
+	if r.WallclockTime > 0 {
+		out.WallclockTime = r.WallclockTime.String()
+	}
+	return json.Marshal(out)
+}
+
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// Results returns a record for each task of the job in submission
+// order. Results does not wait for the tasks. Call Synchronize()
+// before for getting the final state of all tasks.
+func (j *Job) Results() []TaskResult {
+	j.begin(j.ctx, "Results()")
+	results := make([]TaskResult, 0, len(j.tasklist))
+	for _, t := range j.tasklist {
+		if t.jobArray == nil {
+			results = append(results, j.taskResult(t, ""))
+			continue
+		}
+		if t.members == nil {
+			for _, job := range t.jobArray.GetJobs() {
+				member := &task{job: job, template: t.template}
+				results = append(results, j.taskResult(member, t.jobArray.GetID()))
+			}
+			continue
+		}
+		for _, member := range t.members {
+			results = append(results, j.taskResult(member, t.jobArray.GetID()))
+		}
+	}
+	return results
+}
+
+func (j *Job) taskResult(t *task, arrayJobID string) TaskResult {
+	result := TaskResult{
+		Tag:              j.tag,
+		ArrayJobID:       arrayJobID,
+		Template:         t.template,
+		State:            drmaa2interface.Undetermined,
+		SubmitError:      t.submitError,
+		TerminationError: t.terminationError,
+		Retry:            t.retry,
+		Coordinates:      t.coordinates,
+	}
+	if t.job == nil {
+		return result
+	}
+	result.JobID = t.job.GetID()
+	result.State = t.job.GetState()
+
+	jobInfo := t.jobinfo
+	if !t.waitForEndStateCollectedJobInfo || t.jobinfoError != nil {
+		ji, err := t.job.GetJobInfo()
+		if err != nil {
+			j.warningf(j.ctx, "Results(): GetJobInfo() of job %s failed: %v",
+				result.JobID, err)
+			return result
+		}
+		jobInfo = ji
+	}
+	if isTerminated(result.State) {
+		result.ExitStatus = jobInfo.ExitStatus
+	}
+	result.SubmissionTime = jobInfo.SubmissionTime
+	result.DispatchTime = jobInfo.DispatchTime
+	result.FinishTime = jobInfo.FinishTime
+	result.WallclockTime = jobInfo.WallclockTime
+	return result
+}
+
+// WorkflowReport contains the results of all tasks submitted
+// through the jobs of a workflow.
+type WorkflowReport struct {
+	SessionName string       `json:"sessionName"`
+	Created     time.Time    `json:"created"`
+	Tasks       int          `json:"tasks"`
+	Failed      int          `json:"failed"`
+	Results     []TaskResult `json:"results"`
+}
+
+// WithReport lets the workflow keep all jobs which are created after the
+// call so that Report() can include them. The jobs are kept for the
+// lifetime of the workflow, hence for long running workflows which
+// create many jobs (like with RunEveryT()) the jobs should rather be
+// passed to Report().
+func (w *Workflow) WithReport() *Workflow {
+	w.jobsMutex.Lock()
+	defer w.jobsMutex.Unlock()
+	w.reportJobs = true
+	return w
+}
+
+// Report creates a report of all tasks of the given jobs. Without jobs
+// the report contains the jobs created in the workflow after WithReport()
+// was called. Like Results() it does not wait for the tasks.
+//
+// Example:
+//
+//	flow := wfl.NewWorkflow(ctx).WithReport()
+//	flow.Run("sleep", "1").Synchronize()
+//	flow.Report().WriteTable(os.Stdout)
+func (w *Workflow) Report(jobs ...*Job) *WorkflowReport {
+	report := &WorkflowReport{
+		Created: time.Now(),
+		Results: []TaskResult{},
+	}
+	if w.ctx != nil {
+		report.SessionName = w.ctx.JobSessionName
+	}
+	if len(jobs) == 0 {
+		jobs = w.registeredJobs()
+	}
+	for _, job := range jobs {
+		for _, result := range job.Results() {
+			report.Tasks++
+			if result.Failed() {
+				report.Failed++
+			}
+			report.Results = append(report.Results, result)
+		}
+	}
+	return report
+}
+
+// WriteJSON writes the report as indented JSON.
+func (r *WorkflowReport) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// WriteTable writes the report as human readable table.
+func (r *WorkflowReport) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "TAG\tJOB ID\tSTATE\tEXIT\tRETRY\tWALLCLOCK\tCOMMAND\tERROR")
+	for _, result := range r.Results {
+		exit, wallclock := "-", "-"
+		if isTerminated(result.State) {
+			exit = fmt.Sprintf("%d", result.ExitStatus)
+		}
+		if result.WallclockTime > 0 {
+			wallclock = result.WallclockTime.Round(time.Millisecond).String()
+		}
+		jobID := result.JobID
+		if jobID == "" {
+			jobID = "-"
+		}
+		errMsg := "-"
+		if result.SubmitError != nil {
+			errMsg = result.SubmitError.Error()
+		} else if result.TerminationError != nil {
+			errMsg = result.TerminationError.Error()
+		}
+		command := strings.TrimSpace(result.Template.RemoteCommand + " " +
+			strings.Join(result.Template.Args, " "))
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%s\t%s\t%s\n",
+			valueOrDash(result.Tag), jobID, result.State, exit, result.Retry,
+			wallclock, valueOrDash(command), errMsg)
+	}
+	fmt.Fprintf(tw, "\n%d tasks, %d failed\n", r.Tasks, r.Failed)
+	return tw.Flush()
+}
+
+func valueOrDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func (w *Workflow) registerJob(job *Job) {
+	w.jobsMutex.Lock()
+	defer w.jobsMutex.Unlock()
+	if w.reportJobs {
+		w.jobs = append(w.jobs, job)
+	}
+}
+
+func (w *Workflow) registeredJobs() []*Job {
+	w.jobsMutex.Lock()
+	defer w.jobsMutex.Unlock()
+	jobs := make([]*Job, len(w.jobs))
+	copy(jobs, w.jobs)
+	return jobs
+}
@@ -0,0 +1,91 @@
+package wfl_test
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/dgruber/drmaa2interface"
+	"github.com/dgruber/wfl"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Report", func() {
+
+	var flow *wfl.Workflow
+
+	BeforeEach(func() {
+		flow = wfl.NewWorkflow(wfl.NewProcessContext())
+		Ω(flow.HasError()).Should(BeFalse())
+	})
+
+	It("should return a result for each task of a job", func() {
+		job := flow.NewJob().TagWith("results").
+			Run("sleep", "0").
+			Run("./test_scripts/exit.sh", "3").
+			RunArray(1, 2, 1, 2, "sleep", "0").
+			Synchronize()
+
+		results := job.Results()
+		Ω(results).Should(HaveLen(4))
+		for _, r := range results {
+			Ω(r.Tag).Should(Equal("results"))
+			Ω(r.JobID).ShouldNot(BeEmpty())
+			Ω(r.SubmissionTime.IsZero()).Should(BeFalse())
+		}
+		Ω(results[0].State).Should(Equal(drmaa2interface.Done))
+		Ω(results[0].Template.RemoteCommand).Should(Equal("sleep"))
+		Ω(results[0].Failed()).Should(BeFalse())
+		Ω(results[1].State).Should(Equal(drmaa2interface.Failed))
+		Ω(results[1].ExitStatus).Should(Equal(3))
+		Ω(results[1].Failed()).Should(BeTrue())
+		Ω(results[2].ArrayJobID).ShouldNot(BeEmpty())
+		Ω(results[3].ArrayJobID).Should(Equal(results[2].ArrayJobID))
+	})
+
+	It("should count retries", func() {
+		job := flow.NewJob().Run("./test_scripts/exit.sh", "1").RetryAnyFailed(2)
+		results := job.Results()
+		Ω(results).Should(HaveLen(1))
+		Ω(results[0].Retry).Should(Equal(2))
+	})
+
+	It("should report all jobs of the workflow as JSON and table", func() {
+		flow.WithReport()
+		flow.Run("sleep", "0").Wait()
+		flow.NewJob().TagWith("failing").Run("./test_scripts/exit.sh", "1").Wait()
+
+		report := flow.Report()
+		Ω(report.Tasks).Should(Equal(2))
+		Ω(report.Failed).Should(Equal(1))
+
+		var buf bytes.Buffer
+		Ω(report.WriteJSON(&buf)).Should(BeNil())
+		var decoded map[string]interface{}
+		Ω(json.Unmarshal(buf.Bytes(), &decoded)).Should(BeNil())
+		Ω(decoded["failed"]).Should(BeNumerically("==", 1))
+		results := decoded["results"].([]interface{})
+		Ω(results).Should(HaveLen(2))
+		Ω(results[1].(map[string]interface{})["state"]).Should(Equal("Failed"))
+		Ω(results[1].(map[string]interface{})["tag"]).Should(Equal("failing"))
+
+		buf.Reset()
+		Ω(report.WriteTable(&buf)).Should(BeNil())
+		Ω(buf.String()).Should(ContainSubstring("TAG"))
+		Ω(buf.String()).Should(ContainSubstring("failing"))
+		Ω(buf.String()).Should(ContainSubstring("./test_scripts/exit.sh 1"))
+		Ω(buf.String()).Should(ContainSubstring("2 tasks, 1 failed"))
+	})
+
+	It("should only keep the jobs for the report with WithReport()", func() {
+		flow.Run("sleep", "0").Wait()
+		Ω(flow.Report().Tasks).Should(Equal(0))
+
+		job := flow.NewJob().Run("./test_scripts/exit.sh", "1").Wait()
+		report := flow.Report(job)
+		Ω(report.Tasks).Should(Equal(1))
+		Ω(report.Failed).Should(Equal(1))
+	})
+
+})
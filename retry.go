@@ -0,0 +1,275 @@
+package wfl
+
+import (
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/dgruber/drmaa2interface"
+	"github.com/mitchellh/copystructure"
+	"golang.org/x/exp/slices"
+)
+
+// RetryPolicy defines how Retry() and RetryAnyFailed() resubmit failed
+// tasks. The zero value resubmits each failed task immediately.
+//
+// Example which retries tasks killed by the OOM killer (exit code 137)
+// with increasing memory:
+//
+//	job := flow.NewJob().WithRetryPolicy(wfl.RetryPolicy{
+//		InitialBackoff:   time.Second,
+//		MaxBackoff:       time.Minute,
+//		Jitter:           0.2,
+//		MaxDuration:      time.Hour,
+//		RetryOnExitCodes: []int{137},
+//		Mutate: func(jt drmaa2interface.JobTemplate, failed wfl.RetryAttempt) drmaa2interface.JobTemplate {
+//			jt.MinPhysMemory *= 2
+//			return jt
+//		},
+//	}).RunT(jt).RetryAnyFailed(3)
+type RetryPolicy struct {
+	// InitialBackoff is the delay before the first resubmission.
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between two attempts. 0 means no cap.
+	MaxBackoff time.Duration
+	// Multiplier increases the delay with each attempt. Defaults to 2.
+	Multiplier float64
+	// Jitter randomizes the delay by the given fraction (0.0 - 1.0)
+	// so that failed tasks are not resubmitted all at the same time.
+	Jitter float64
+	// MaxDuration limits the total time spent on a task measured from
+	// its first submission. No resubmission happens after the duration
+	// is exceeded. 0 means no limit.
+	MaxDuration time.Duration
+	// RetryOnExitCodes restricts retries to tasks which failed with
+	// one of the given exit codes.
+	RetryOnExitCodes []int
+	// RetryOnSubmissionError retries tasks which could not be submitted.
+	// If neither RetryOnExitCodes nor RetryOnSubmissionError is set, all
+	// failed tasks are retried.
+	RetryOnSubmissionError bool
+	// Mutate is called before each resubmission and returns the job
+	// template used for the next attempt (like a template which requests
+	// more memory).
+	Mutate func(jt drmaa2interface.JobTemplate, failed RetryAttempt) drmaa2interface.JobTemplate
+}
+
+// RetryAttempt describes one submission of a task.
+type RetryAttempt struct {
+	// Attempt is 0 for the initial submission and counts the retries.
+	Attempt int
+	// JobID is the ID of the submitted job. Empty if the submission failed.
+	JobID string
+	// SubmitError is set when the job could not be submitted.
+	SubmitError error
+	// SubmissionTime is the time the attempt was submitted.
+	SubmissionTime time.Time
+	// Backoff is the delay waited before the attempt was submitted.
+	Backoff time.Duration
+	// State is the job state at the time the attempt was evaluated.
+	State drmaa2interface.JobState
+	// ExitStatus of the job if it is finished.
+	ExitStatus int
+	// JobInfo of the job if it is finished.
+	JobInfo drmaa2interface.JobInfo
+}
+
+// WithRetryPolicy sets the policy which is applied by Retry()
+// and RetryAnyFailed() when tasks of the job are resubmitted.
+func (j *Job) WithRetryPolicy(policy RetryPolicy) *Job {
+	j.retryPolicy = policy
+	return j
+}
+
+// RetryHistory returns all attempts of the task which was or is
+// executed as the job with the given ID. The ID can also be the ID
+// of a job array task which was resubmitted by RetryAnyFailed(). The
+// attempts are ordered starting with the initial submission. If no
+// task is found it returns nil.
+func (j *Job) RetryHistory(jobID string) []RetryAttempt {
+	j.begin(j.ctx, "RetryHistory()")
+	for i := len(j.tasklist) - 1; i >= 0; i-- {
+		if history := j.tasklist[i].retryHistory(jobID); history != nil {
+			return history
+		}
+		for _, member := range j.tasklist[i].members {
+			if history := member.retryHistory(jobID); history != nil {
+				return history
+			}
+		}
+	}
+	return nil
+}
+
+func (t *task) retryHistory(jobID string) []RetryAttempt {
+	for _, attempt := range t.history {
+		if attempt.JobID != jobID {
+			continue
+		}
+		if t.jobArray == nil {
+			t.evaluateAttempt()
+		}
+		history := make([]RetryAttempt, len(t.history))
+		copy(history, t.history)
+		return history
+	}
+	return nil
+}
+
+// backoff returns the delay before the given retry (starting with 1).
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	if p.InitialBackoff <= 0 || retry < 1 {
+		return 0
+	}
+	multiplier := p.Multiplier
+	if multiplier <= 0 {
+		multiplier = 2
+	}
+	delay := float64(p.InitialBackoff) * math.Pow(multiplier, float64(retry-1))
+	if p.MaxBackoff > 0 && delay > float64(p.MaxBackoff) {
+		delay = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		jitter := math.Min(p.Jitter, 1.0)
+		delay += delay * jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(delay)
+}
+
+// retryable returns true if the failed attempt matches the failure
+// classification of the policy.
+func (p RetryPolicy) retryable(a RetryAttempt) bool {
+	if a.SubmitError != nil {
+		return p.RetryOnSubmissionError || len(p.RetryOnExitCodes) == 0
+	}
+	if len(p.RetryOnExitCodes) > 0 {
+		return slices.Contains(p.RetryOnExitCodes, a.ExitStatus)
+	}
+	return !p.RetryOnSubmissionError
+}
+
+// recordAttempt adds a new submission to the history of the task.
+func (t *task) recordAttempt(backoff time.Duration) {
+	attempt := RetryAttempt{
+		Attempt:        t.retry,
+		SubmitError:    t.submitError,
+		SubmissionTime: time.Now(),
+		Backoff:        backoff,
+		State:          drmaa2interface.Undetermined,
+	}
+	switch {
+	case t.submitError != nil:
+		// a failed resubmission keeps the job of the previous attempt
+	case t.job != nil:
+		attempt.JobID = t.job.GetID()
+	case t.jobArray != nil:
+		attempt.JobID = t.jobArray.GetID()
+	}
+	t.history = append(t.history, attempt)
+}
+
+// evaluateAttempt updates the latest attempt with the current job
+// state and returns it.
+func (t *task) evaluateAttempt() RetryAttempt {
+	if len(t.history) == 0 {
+		t.recordAttempt(0)
+	}
+	attempt := &t.history[len(t.history)-1]
+	if t.job == nil || attempt.SubmitError != nil {
+		return *attempt
+	}
+	attempt.State = t.job.GetState()
+	if t.waitForEndStateCollectedJobInfo && t.jobinfoError == nil {
+		attempt.ExitStatus = t.jobinfo.ExitStatus
+		attempt.JobInfo = t.jobinfo
+		return *attempt
+	}
+	if isTerminated(attempt.State) {
+		if ji, err := t.job.GetJobInfo(); err == nil {
+			attempt.ExitStatus = ji.ExitStatus
+			attempt.JobInfo = ji
+		}
+	}
+	return *attempt
+}
+
+// failed returns true if the task could not be submitted or its
+// job is in failed state. A job array task failed when one of its
+// tasks failed.
+func (t *task) failed() bool {
+	if t.jobArray != nil {
+		if t.members != nil {
+			for _, member := range t.members {
+				if member.failed() {
+					return true
+				}
+			}
+			return false
+		}
+		for _, job := range t.arrayJobs() {
+			if job.GetState() == drmaa2interface.Failed {
+				return true
+			}
+		}
+		return false
+	}
+	if t.submitError != nil {
+		return true
+	}
+	if t.job == nil {
+		return false
+	}
+	return t.job.GetState() == drmaa2interface.Failed
+}
+
+// prepareRetry checks if the failed task should be resubmitted according
+// to the retry policy of the job. It waits for the backoff and returns
+// the job template for the next attempt.
+func (j *Job) prepareRetry(t *task) (drmaa2interface.JobTemplate, time.Duration, bool) {
+	policy := j.retryPolicy
+	failed := t.evaluateAttempt()
+	if !policy.retryable(failed) {
+		j.infof(j.ctx, "retry policy: task %s failed with exit status %d which is not retried",
+			failed.JobID, failed.ExitStatus)
+		return t.template, 0, false
+	}
+	delay := policy.backoff(t.retry + 1)
+	if policy.MaxDuration > 0 &&
+		time.Since(t.history[0].SubmissionTime)+delay > policy.MaxDuration {
+		j.warningf(j.ctx, "retry policy: max duration %s for task %s exceeded",
+			policy.MaxDuration, failed.JobID)
+		return t.template, 0, false
+	}
+	if delay > 0 {
+		j.infof(j.ctx, "retry policy: waiting %s before resubmitting task %s",
+			delay, failed.JobID)
+		select {
+		case <-time.After(delay):
+		case <-j.Context().Done():
+			j.cancelled()
+			return t.template, 0, false
+		}
+	}
+	jt := t.sourceTemplate()
+	if policy.Mutate != nil {
+		if jtCopy, err := copystructure.Copy(jt); err == nil {
+			jt = jtCopy.(drmaa2interface.JobTemplate)
+		}
+		jt = policy.Mutate(jt, failed)
+	}
+	return jt, delay, true
+}
+
+// anyTaskFailed waits for all tasks and returns true if one of them
+// failed or could not be submitted.
+func (j *Job) anyTaskFailed() bool {
+	for _, task := range j.tasklist {
+		if err := wait(j.ctx, task, drmaa2interface.InfiniteTime); err != nil && j.cancelled() {
+			return false
+		}
+		if task.failed() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package wfl
+
+import (
+	"errors"
+	"time"
+
+	g "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = g.Describe("RetryPolicy", func() {
+
+	g.It("should calculate the backoff", func() {
+		p := RetryPolicy{InitialBackoff: time.Second, MaxBackoff: 5 * time.Second}
+		Ω(p.backoff(0)).Should(Equal(time.Duration(0)))
+		Ω(p.backoff(1)).Should(Equal(time.Second))
+		Ω(p.backoff(2)).Should(Equal(2 * time.Second))
+		Ω(p.backoff(3)).Should(Equal(4 * time.Second))
+		Ω(p.backoff(4)).Should(Equal(5 * time.Second))
+		Ω(RetryPolicy{}.backoff(3)).Should(Equal(time.Duration(0)))
+
+		p = RetryPolicy{InitialBackoff: time.Second, Multiplier: 1, Jitter: 0.5}
+		for i := 0; i < 100; i++ {
+			Ω(p.backoff(1)).Should(BeNumerically(">=", 500*time.Millisecond))
+			Ω(p.backoff(1)).Should(BeNumerically("<=", 1500*time.Millisecond))
+		}
+	})
+
+	g.It("should classify failures", func() {
+		submitError := RetryAttempt{SubmitError: errors.New("submission failed")}
+		exit1 := RetryAttempt{ExitStatus: 1}
+		exit2 := RetryAttempt{ExitStatus: 2}
+
+		all := RetryPolicy{}
+		Ω(all.retryable(submitError)).Should(BeTrue())
+		Ω(all.retryable(exit1)).Should(BeTrue())
+
+		codes := RetryPolicy{RetryOnExitCodes: []int{2}}
+		Ω(codes.retryable(submitError)).Should(BeFalse())
+		Ω(codes.retryable(exit1)).Should(BeFalse())
+		Ω(codes.retryable(exit2)).Should(BeTrue())
+
+		submission := RetryPolicy{RetryOnSubmissionError: true}
+		Ω(submission.retryable(submitError)).Should(BeTrue())
+		Ω(submission.retryable(exit1)).Should(BeFalse())
+
+		both := RetryPolicy{RetryOnSubmissionError: true, RetryOnExitCodes: []int{1}}
+		Ω(both.retryable(submitError)).Should(BeTrue())
+		Ω(both.retryable(exit1)).Should(BeTrue())
+		Ω(both.retryable(exit2)).Should(BeFalse())
+	})
+
+})
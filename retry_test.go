@@ -0,0 +1,136 @@
+package wfl_test
+
+import (
+	"errors"
+	"time"
+
+	"github.com/dgruber/drmaa2interface"
+	"github.com/dgruber/wfl"
+	"github.com/dgruber/wfl/pkg/context/fake"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RetryPolicy", func() {
+
+	var flow *wfl.Workflow
+
+	BeforeEach(func() {
+		flow = wfl.NewWorkflow(wfl.NewProcessContext())
+		Ω(flow.HasError()).Should(BeFalse())
+	})
+
+	exitT := func(code string) drmaa2interface.JobTemplate {
+		return drmaa2interface.JobTemplate{
+			RemoteCommand: "./test_scripts/exit.sh",
+			Args:          []string{code},
+		}
+	}
+
+	It("should wait with exponential backoff between retries", func() {
+		start := time.Now()
+		job := flow.NewJob().WithRetryPolicy(wfl.RetryPolicy{
+			InitialBackoff: 100 * time.Millisecond,
+			Multiplier:     2,
+		}).RunT(exitT("1"))
+		firstID := job.JobID()
+		job.Retry(2).Wait()
+		Ω(job.Success()).Should(BeFalse())
+		Ω(time.Since(start)).Should(BeNumerically(">=", 300*time.Millisecond))
+
+		history := job.RetryHistory(firstID)
+		Ω(history).Should(HaveLen(3))
+		Ω(history[0].Attempt).Should(Equal(0))
+		Ω(history[0].Backoff).Should(Equal(time.Duration(0)))
+		Ω(history[1].Attempt).Should(Equal(1))
+		Ω(history[1].Backoff).Should(Equal(100 * time.Millisecond))
+		Ω(history[2].Backoff).Should(Equal(200 * time.Millisecond))
+		for _, attempt := range history {
+			Ω(attempt.State).Should(Equal(drmaa2interface.Failed))
+			Ω(attempt.ExitStatus).Should(Equal(1))
+		}
+		Ω(job.RetryHistory(job.JobID())).Should(Equal(history))
+	})
+
+	It("should only retry the configured exit codes", func() {
+		job := flow.NewJob().WithRetryPolicy(wfl.RetryPolicy{
+			RetryOnExitCodes: []int{2},
+		}).RunT(exitT("1")).RunT(exitT("2"))
+		firstID := job.ListAll()[0].GetID()
+		secondID := job.JobID()
+		job.RetryAnyFailed(2)
+		Ω(job.RetryHistory(firstID)).Should(HaveLen(1))
+		Ω(job.RetryHistory(secondID)).Should(HaveLen(3))
+	})
+
+	It("should mutate the job template between attempts", func() {
+		var failed []wfl.RetryAttempt
+		job := flow.NewJob().WithRetryPolicy(wfl.RetryPolicy{
+			Mutate: func(jt drmaa2interface.JobTemplate, attempt wfl.RetryAttempt) drmaa2interface.JobTemplate {
+				failed = append(failed, attempt)
+				jt.Args = []string{"0"}
+				return jt
+			},
+		}).RunT(exitT("1"))
+		firstID := job.JobID()
+		job.RetryAnyFailed(3)
+		Ω(job.HasAnyFailed()).Should(BeFalse())
+		Ω(failed).Should(HaveLen(1))
+		Ω(failed[0].JobID).Should(Equal(firstID))
+		Ω(failed[0].ExitStatus).Should(Equal(1))
+		Ω(job.Template().Args).Should(Equal([]string{"0"}))
+		Ω(job.RetryHistory(firstID)).Should(HaveLen(2))
+	})
+
+	It("should stop retrying when the max duration is exceeded", func() {
+		job := flow.NewJob().WithRetryPolicy(wfl.RetryPolicy{
+			InitialBackoff: time.Second,
+			MaxDuration:    500 * time.Millisecond,
+		}).RunT(exitT("1"))
+		start := time.Now()
+		job.RetryAnyFailed(-1)
+		Ω(time.Since(start)).Should(BeNumerically("<", time.Second))
+		Ω(job.RetryHistory(job.JobID())).Should(HaveLen(1))
+		Ω(job.HasAnyFailed()).Should(BeTrue())
+	})
+
+	Context("when a resubmission fails", func() {
+
+		var sm *fake.SessionManager
+
+		BeforeEach(func() {
+			sm = fake.NewSessionManager().OnCommand("flaky",
+				fake.Outcome{ExitStatus: 1},
+				fake.Outcome{SubmitError: errors.New("queue is full")})
+			flow = wfl.NewWorkflow(fake.NewFakeContextByCfg(fake.Config{SessionManager: sm}))
+		})
+
+		It("should keep reporting the failed task", func() {
+			job := flow.NewJob().Run("flaky")
+			failedID := job.JobID()
+			job.RetryAnyFailed(1)
+			Ω(job.HasAnyFailed()).Should(BeTrue())
+			Ω(job.AnyFailed()).Should(BeTrue())
+			Ω(job.State()).Should(Equal(drmaa2interface.Failed))
+			Ω(job.ListAll()).Should(HaveLen(1))
+			Ω(job.JobID()).Should(Equal(failedID))
+
+			history := job.RetryHistory(failedID)
+			Ω(history).Should(HaveLen(2))
+			Ω(history[1].SubmitError).Should(MatchError("queue is full"))
+			Ω(history[1].JobID).Should(BeEmpty())
+		})
+
+		It("should keep reporting the failed job array task", func() {
+			job := flow.NewJob().RunArray(1, 1, 1, 1, "flaky")
+			job.RetryAnyFailed(1)
+			Ω(job.HasAnyFailed()).Should(BeTrue())
+			Ω(job.AnyFailed()).Should(BeTrue())
+			Ω(job.State()).Should(Equal(drmaa2interface.Failed))
+			Ω(job.ListAll()).Should(HaveLen(1))
+		})
+
+	})
+
+})
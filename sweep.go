@@ -0,0 +1,288 @@
+package wfl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dgruber/drmaa2interface"
+)
+
+// sweepPollInterval defines how often a sweep checks the metrics
+// of its running trials.
+const sweepPollInterval = 100 * time.Millisecond
+
+// Sweep defines a parameter sweep which runs a task for each candidate
+// of a search space and evaluates a metric written by the tasks to
+// their output. Poor performing candidates can be stopped early by
+// successive halving.
+type Sweep struct {
+	// Template is the job template with the placeholders of the
+	// search space.
+	Template drmaa2interface.JobTemplate
+	// Space defines the search space. Like for RunMatrixNT() the
+	// replacements are combined unless they share a Group.
+	Space []Replacement
+	// Metric parses the metric from a line of the output of a task.
+	// Lines without metric return false. A task can report a metric
+	// multiple times (like after each epoch); the last one counts.
+	Metric func(line string) (float64, bool)
+	// Minimize selects the candidate with the lowest metric (like a
+	// loss) as best. By default the highest metric wins.
+	Minimize bool
+	// Budget is the amount of candidates which are drawn randomly from
+	// the search space. If 0 all combinations of the space are run.
+	Budget int
+	// Parallel is the amount of candidates running at the same time.
+	// Successive halving compares the candidates of such a batch. If 0
+	// all candidates run at the same time.
+	Parallel int
+	// Rungs enable successive halving. Each rung is an amount of reported
+	// metrics. When all running candidates of a batch reported that many
+	// metrics only the best 1/Eta of them continue; the others are killed.
+	Rungs []int
+	// Eta is the reduction factor of successive halving. Default is 2.
+	Eta int
+	// Seed for drawing the candidates. If 0 the current time is used.
+	Seed int64
+	// Context stops the sweep and terminates its tasks when cancelled.
+	Context context.Context
+}
+
+// Trial is the run of a candidate of a sweep.
+type Trial struct {
+	// Coordinates contains the value of each pattern of the space.
+	Coordinates map[string]string
+	JobID       string
+	State       drmaa2interface.JobState
+	// Metrics are all metrics reported by the task in order.
+	Metrics []float64
+	// Stopped is true when the task was killed by successive halving.
+	Stopped bool
+	// Error is set when the task could not be submitted.
+	Error error
+}
+
+// Metric returns the last metric the task reported.
+func (t Trial) Metric() (float64, bool) {
+	if len(t.Metrics) == 0 {
+		return 0, false
+	}
+	return t.Metrics[len(t.Metrics)-1], true
+}
+
+// SweepResult contains all trials of a sweep in submission order.
+type SweepResult struct {
+	Trials   []Trial
+	minimize bool
+}
+
+// Best returns the trial with the best metric. Trials which were
+// stopped early or did not finish successfully are ignored.
+func (r *SweepResult) Best() (Trial, bool) {
+	var best Trial
+	found := false
+	for _, trial := range r.Trials {
+		metric, ok := trial.Metric()
+		if !ok || trial.Stopped || trial.State != drmaa2interface.Done {
+			continue
+		}
+		bestMetric, _ := best.Metric()
+		if !found || (r.minimize && metric < bestMetric) ||
+			(!r.minimize && metric > bestMetric) {
+			best, found = trial, true
+		}
+	}
+	return best, found
+}
+
+// sweepTrial is a running trial.
+type sweepTrial struct {
+	mu    sync.Mutex
+	trial Trial
+	job   *Job
+}
+
+func (t *sweepTrial) metrics() []float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]float64(nil), t.trial.Metrics...)
+}
+
+// RunSweep runs the candidates of the sweep in batches of s.Parallel
+// tasks. Each candidate runs in its own job; the metrics are read from
+// the output while the tasks are running (see OnOutputLine()), hence the
+// backend needs to support output streaming.
+//
+// Example: Draw 16 candidates, run 8 at a time, and stop the worse half
+// after the first and after the third epoch.
+//
+//	result, err := flow.RunSweep(wfl.Sweep{
+//		Template: drmaa2interface.JobTemplate{
+//			RemoteCommand: "python3",
+//			Args:          []string{"train.py", "--lr={{lr}}", "--bs={{bs}}"},
+//			OutputPath:    "/tmp/train-{{.ID}}.out",
+//		},
+//		Space:    []wfl.Replacement{learningRates, batchSizes},
+//		Metric: func(line string) (float64, bool) {
+//			var epoch int
+//			var accuracy float64
+//			_, err := fmt.Sscanf(line, "epoch %d accuracy %f", &epoch, &accuracy)
+//			return accuracy, err == nil
+//		},
+//		Budget:   16,
+//		Parallel: 8,
+//		Rungs:    []int{1, 3},
+//	})
+//	best, _ := result.Best()
+func (w *Workflow) RunSweep(s Sweep) (*SweepResult, error) {
+	if s.Metric == nil {
+		return nil, errors.New("no metric function given")
+	}
+	ctx := s.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	opts := MatrixOptions{Seed: s.Seed}
+	if s.Budget > 0 {
+		opts.Sampling = RandomSampling
+		opts.Samples = s.Budget
+	}
+	candidates, err := getMatrixPoints(s.Template, opts, s.Space)
+	if err != nil {
+		return nil, fmt.Errorf("failed creating candidates: %w", err)
+	}
+	parallel := s.Parallel
+	if parallel <= 0 {
+		parallel = len(candidates)
+	}
+	result := &SweepResult{Trials: make([]Trial, 0, len(candidates)), minimize: s.Minimize}
+	for begin := 0; begin < len(candidates); begin += parallel {
+		end := begin + parallel
+		if end > len(candidates) {
+			end = len(candidates)
+		}
+		trials := w.runSweepBatch(ctx, s, candidates[begin:end])
+		for _, t := range trials {
+			result.Trials = append(result.Trials, t.trial)
+		}
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+func (w *Workflow) runSweepBatch(ctx context.Context, s Sweep, candidates []matrixPoint) []*sweepTrial {
+	trials := make([]*sweepTrial, 0, len(candidates))
+	for _, candidate := range candidates {
+		t := &sweepTrial{trial: Trial{
+			Coordinates: candidate.values,
+			State:       drmaa2interface.Undetermined,
+		}}
+		t.job = w.NewJob().WithContext(ctx).TerminateOnCancel().
+			runT(candidate.template, candidate.values)
+		if err := t.job.LastError(); err != nil {
+			t.trial.Error = err
+			trials = append(trials, t)
+			continue
+		}
+		t.trial.JobID = t.job.JobID()
+		t.job.OnOutputLine(func(line string) {
+			if metric, ok := s.Metric(line); ok {
+				t.mu.Lock()
+				t.trial.Metrics = append(t.trial.Metrics, metric)
+				t.mu.Unlock()
+			}
+		})
+		if err := t.job.LastError(); err != nil {
+			w.log.Warningf(ctx, "RunSweep(): cannot follow output of task %s: %v",
+				t.trial.JobID, err)
+		}
+		trials = append(trials, t)
+	}
+
+	eta := s.Eta
+	if eta < 2 {
+		eta = 2
+	}
+	for rung := 0; rung < len(s.Rungs); {
+		running := 0
+		reached := true
+		for _, t := range trials {
+			if t.trial.Error != nil || t.trial.Stopped || isTerminated(t.job.State()) {
+				continue
+			}
+			running++
+			if len(t.metrics()) < s.Rungs[rung] {
+				reached = false
+			}
+		}
+		if running == 0 {
+			break
+		}
+		if reached {
+			w.stopWorstTrials(ctx, s, trials, s.Rungs[rung], eta)
+			rung++
+			continue
+		}
+		select {
+		case <-time.After(sweepPollInterval):
+		case <-ctx.Done():
+			rung = len(s.Rungs)
+		}
+	}
+
+	for _, t := range trials {
+		if t.trial.Error != nil {
+			continue
+		}
+		t.job.Wait()
+		t.mu.Lock()
+		t.trial.State = t.job.State()
+		t.mu.Unlock()
+	}
+	return trials
+}
+
+// stopWorstTrials ranks all trials which reported the metric of the
+// rung and kills the running ones which are not in the best 1/eta.
+func (w *Workflow) stopWorstTrials(ctx context.Context, s Sweep, trials []*sweepTrial, rung, eta int) {
+	type ranked struct {
+		trial  *sweepTrial
+		metric float64
+	}
+	candidates := []ranked{}
+	for _, t := range trials {
+		if t.trial.Error != nil || t.trial.Stopped {
+			continue
+		}
+		metrics := t.metrics()
+		if len(metrics) < rung {
+			continue
+		}
+		candidates = append(candidates, ranked{trial: t, metric: metrics[rung-1]})
+	}
+	sort.SliceStable(candidates, func(a, b int) bool {
+		if s.Minimize {
+			return candidates[a].metric < candidates[b].metric
+		}
+		return candidates[a].metric > candidates[b].metric
+	})
+	keep := int(math.Ceil(float64(len(candidates)) / float64(eta)))
+	for _, c := range candidates[keep:] {
+		if isTerminated(c.trial.job.State()) {
+			continue
+		}
+		w.log.Infof(ctx, "RunSweep(): stopping task %s with metric %v after %d reports",
+			c.trial.trial.JobID, c.metric, rung)
+		c.trial.job.Kill()
+		c.trial.mu.Lock()
+		c.trial.trial.Stopped = true
+		c.trial.mu.Unlock()
+	}
+}
@@ -0,0 +1,151 @@
+package wfl_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/dgruber/drmaa2interface"
+	"github.com/dgruber/wfl"
+	"github.com/dgruber/wfl/pkg/context/fake"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Sweep", func() {
+
+	metric := func(line string) (float64, bool) {
+		value, err := strconv.ParseFloat(strings.TrimPrefix(line, "metric "), 64)
+		return value, err == nil
+	}
+
+	scores := wfl.Replacement{
+		Fields:       []wfl.JobTemplateField{wfl.Args},
+		Pattern:      "{{score}}",
+		Replacements: []string{"1", "2", "3", "4"},
+	}
+
+	It("should stop the worse half of the candidates early", func() {
+		tmpDir, err := os.MkdirTemp("", "wflsweep")
+		Ω(err).Should(BeNil())
+		defer os.RemoveAll(tmpDir)
+
+		flow := wfl.NewWorkflow(wfl.NewProcessContext())
+		// each candidate reports its score multiplied by the epoch
+		result, err := flow.RunSweep(wfl.Sweep{
+			Template: drmaa2interface.JobTemplate{
+				RemoteCommand: "/bin/bash",
+				Args:          []string{"-c", `for i in 1 2 3; do echo "metric $(({{score}} * i))"; sleep 0.5; done`},
+				OutputPath:    filepath.Join(tmpDir, "out-{{.ID}}"),
+			},
+			Space:  []wfl.Replacement{scores},
+			Metric: metric,
+			Rungs:  []int{1},
+		})
+		Ω(err).Should(BeNil())
+		Ω(result.Trials).Should(HaveLen(4))
+		for _, trial := range result.Trials {
+			Ω(trial.Error).Should(BeNil())
+			score := trial.Coordinates["{{score}}"]
+			if score == "1" || score == "2" {
+				Ω(trial.Stopped).Should(BeTrue())
+				Ω(trial.State).Should(Equal(drmaa2interface.Failed))
+				Ω(len(trial.Metrics)).Should(BeNumerically("<", 3))
+			} else {
+				Ω(trial.Stopped).Should(BeFalse())
+				Ω(trial.State).Should(Equal(drmaa2interface.Done))
+				Ω(trial.Metrics).Should(HaveLen(3))
+			}
+		}
+		best, found := result.Best()
+		Ω(found).Should(BeTrue())
+		Ω(best.Coordinates["{{score}}"]).Should(Equal("4"))
+		Ω(best.Metrics).Should(Equal([]float64{4, 8, 12}))
+	})
+
+	Context("Budget", func() {
+
+		var (
+			sm   *fake.SessionManager
+			flow *wfl.Workflow
+		)
+
+		factors := []string{"1", "10", "100"}
+
+		BeforeEach(func() {
+			// the loss is the product of both arguments
+			sm = fake.NewSessionManager()
+			for _, score := range scores.Replacements {
+				for _, factor := range factors {
+					a, _ := strconv.Atoi(score)
+					b, _ := strconv.Atoi(factor)
+					args := score + " " + factor
+					sm.On(func(jt drmaa2interface.JobTemplate) bool {
+						return strings.Join(jt.Args, " ") == args
+					}, fake.Outcome{Output: "metric " + strconv.Itoa(a*b) + "\n"})
+				}
+			}
+			flow = wfl.NewWorkflow(fake.NewFakeContextByCfg(fake.Config{SessionManager: sm}))
+			Ω(flow.HasError()).Should(BeFalse())
+		})
+
+		sweep := func() wfl.Sweep {
+			return wfl.Sweep{
+				Template: drmaa2interface.JobTemplate{
+					RemoteCommand: "train.sh",
+					Args:          []string{"{{score}}", "{{factor}}"},
+				},
+				Space: []wfl.Replacement{scores, {
+					Fields:       []wfl.JobTemplateField{wfl.Args},
+					Pattern:      "{{factor}}",
+					Replacements: factors,
+				}},
+				Metric: metric,
+			}
+		}
+
+		It("should run a sample of the search space in batches", func() {
+			s := sweep()
+			s.Budget = 5
+			s.Parallel = 2
+			s.Seed = 42
+			s.Minimize = true
+
+			result, err := flow.RunSweep(s)
+			Ω(err).Should(BeNil())
+			Ω(result.Trials).Should(HaveLen(5))
+			Ω(sm.Submitted()).Should(HaveLen(5))
+
+			best, found := result.Best()
+			Ω(found).Should(BeTrue())
+			for _, trial := range result.Trials {
+				Ω(trial.State).Should(Equal(drmaa2interface.Done))
+				Ω(trial.Metrics).Should(HaveLen(1))
+				Ω(best.Metrics[0]).Should(BeNumerically("<=", trial.Metrics[0]))
+			}
+		})
+
+		It("should fail without metric function", func() {
+			s := sweep()
+			s.Metric = nil
+			_, err := flow.RunSweep(s)
+			Ω(err).ShouldNot(BeNil())
+		})
+
+		It("should stop when the context is cancelled", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			s := sweep()
+			s.Context = ctx
+			s.Parallel = 1
+			result, err := flow.RunSweep(s)
+			Ω(err).Should(Equal(context.Canceled))
+			Ω(len(result.Trials)).Should(BeNumerically("<", 12))
+		})
+
+	})
+
+})
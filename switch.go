@@ -0,0 +1,266 @@
+package wfl
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/dgruber/drmaa2interface"
+)
+
+// TaskOutcome is the result of the finished previous task which is
+// evaluated by the conditions of Switch() and When().
+type TaskOutcome struct {
+	State      drmaa2interface.JobState
+	ExitStatus int
+	JobInfo    drmaa2interface.JobInfo
+	// SubmitError is set if the task could not be submitted.
+	SubmitError error
+
+	outputOnce sync.Once
+	output     string
+	outputErr  error
+	getOutput  func() (string, error)
+}
+
+// Output returns the output of the task. It is read only once when
+// the first condition requires it.
+func (o *TaskOutcome) Output() (string, error) {
+	o.outputOnce.Do(func() {
+		if o.getOutput == nil {
+			o.outputErr = errors.New("no output available")
+			return
+		}
+		o.output, o.outputErr = o.getOutput()
+	})
+	return o.output, o.outputErr
+}
+
+// Condition decides if a branch of Switch() or When() is taken.
+type Condition func(outcome *TaskOutcome) bool
+
+// Succeeded is true when the task ended in the state Done.
+func Succeeded() Condition {
+	return func(o *TaskOutcome) bool {
+		return o.State == drmaa2interface.Done
+	}
+}
+
+// ExitStatusIs is true when the task finished with one of the
+// given exit codes.
+func ExitStatusIs(codes ...int) Condition {
+	return func(o *TaskOutcome) bool {
+		if !isTerminated(o.State) {
+			return false
+		}
+		for _, code := range codes {
+			if o.ExitStatus == code {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// ExitStatusBetween is true when the task finished with an exit
+// code in the range [min, max].
+func ExitStatusBetween(min, max int) Condition {
+	return func(o *TaskOutcome) bool {
+		return isTerminated(o.State) && o.ExitStatus >= min && o.ExitStatus <= max
+	}
+}
+
+// OutputMatches is true when the output of the task matches the
+// regular expression. It panics if the expression cannot be parsed.
+func OutputMatches(expr string) Condition {
+	re := regexp.MustCompile(expr)
+	return func(o *TaskOutcome) bool {
+		output, err := o.Output()
+		return err == nil && re.MatchString(output)
+	}
+}
+
+// OutputJSON parses the output of the task as JSON and calls the
+// predicate with the value at the given path. The path consists of
+// object keys separated by dots (like "metrics.accuracy"); an empty
+// path selects the complete document. Numbers are float64 values.
+// The condition is false if the output is no JSON or the path does
+// not exist.
+//
+// Example:
+//
+//	wfl.OutputJSON("metrics.accuracy", func(v interface{}) bool {
+//		accuracy, ok := v.(float64)
+//		return ok && accuracy > 0.9
+//	})
+func OutputJSON(path string, predicate func(value interface{}) bool) Condition {
+	return func(o *TaskOutcome) bool {
+		output, err := o.Output()
+		if err != nil {
+			return false
+		}
+		var value interface{}
+		if err := json.Unmarshal([]byte(output), &value); err != nil {
+			return false
+		}
+		if path != "" {
+			for _, key := range strings.Split(path, ".") {
+				object, ok := value.(map[string]interface{})
+				if !ok {
+					return false
+				}
+				if value, ok = object[key]; !ok {
+					return false
+				}
+			}
+		}
+		return predicate(value)
+	}
+}
+
+// JobInfoMatches is true when the predicate returns true for the
+// JobInfo of the task.
+func JobInfoMatches(predicate func(ji drmaa2interface.JobInfo) bool) Condition {
+	return func(o *TaskOutcome) bool {
+		return predicate(o.JobInfo)
+	}
+}
+
+// Negate negates the condition.
+func Negate(c Condition) Condition {
+	return func(o *TaskOutcome) bool {
+		return !c(o)
+	}
+}
+
+// AllOf is true when all conditions are true.
+func AllOf(conditions ...Condition) Condition {
+	return func(o *TaskOutcome) bool {
+		for _, c := range conditions {
+			if !c(o) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// AnyOf is true when at least one condition is true.
+func AnyOf(conditions ...Condition) Condition {
+	return func(o *TaskOutcome) bool {
+		for _, c := range conditions {
+			if c(o) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Branch is a case of Switch(). When its condition is true the job
+// templates are submitted one after the other.
+type Branch struct {
+	condition Condition
+	templates []drmaa2interface.JobTemplate
+}
+
+// When creates a branch which submits the job templates when the
+// condition is true.
+func When(c Condition, templates ...drmaa2interface.JobTemplate) Branch {
+	return Branch{condition: c, templates: templates}
+}
+
+// Otherwise creates a branch which is taken when no other branch of
+// Switch() is taken.
+func Otherwise(templates ...drmaa2interface.JobTemplate) Branch {
+	return Branch{templates: templates}
+}
+
+// Switch waits until the previous task is finished and takes the first
+// branch whose condition is true for the task. The job templates of the
+// branch are submitted one after the other (like ThenRunT()), hence the
+// job chain continues with the last task of the branch. If no branch is
+// taken nothing is submitted.
+//
+// Example:
+//
+//	flow.Run("train.sh").Switch(
+//		wfl.When(wfl.ExitStatusIs(0), deployTemplate),
+//		wfl.When(wfl.OutputMatches("out of memory"), trainWithMoreMemoryTemplate),
+//		wfl.When(wfl.ExitStatusBetween(100, 199), notifyTemplate),
+//		wfl.Otherwise(cleanupTemplate),
+//	).Wait()
+func (j *Job) Switch(branches ...Branch) *Job {
+	j.begin(j.ctx, fmt.Sprintf("Switch(%d branches)", len(branches)))
+	outcome := j.taskOutcome()
+	if j.cancelled() {
+		return j
+	}
+	for i, b := range branches {
+		if b.condition != nil && !b.condition(outcome) {
+			continue
+		}
+		j.infof(j.ctx, "Switch(): taking branch %d", i)
+		return j.runBranch(b.templates)
+	}
+	j.infof(j.ctx, "Switch(): no branch taken")
+	return j
+}
+
+// When submits the job templates one after the other when the condition
+// is true for the previous task. It is a Switch() with a single branch.
+//
+// Example:
+//
+//	flow.Run("test.sh").When(wfl.ExitStatusIs(2), rerunFlakyTestsTemplate)
+func (j *Job) When(c Condition, templates ...drmaa2interface.JobTemplate) *Job {
+	return j.Switch(When(c, templates...))
+}
+
+// taskOutcome waits for the previous task and returns its outcome.
+func (j *Job) taskOutcome() *TaskOutcome {
+	outcome := &TaskOutcome{State: drmaa2interface.Undetermined, ExitStatus: -1}
+	t := j.lastJob()
+	if t == nil {
+		return outcome
+	}
+	if t.job == nil {
+		outcome.SubmitError = t.submitError
+		if t.jobArray != nil {
+			outcome.State = waitForJobEndAndState(j)
+		}
+		return outcome
+	}
+	if err := wait(j.ctx, t, drmaa2interface.InfiniteTime); err != nil {
+		return outcome
+	}
+	outcome.State = t.job.GetState()
+	if t.waitForEndStateCollectedJobInfo && t.jobinfoError == nil {
+		outcome.JobInfo = t.jobinfo
+		outcome.ExitStatus = t.jobinfo.ExitStatus
+	}
+	job := t.job
+	outcome.getOutput = func() (string, error) {
+		if !j.wfl.ctx.SupportsOutput() {
+			return "", ErrOutputNotSupported
+		}
+		return j.jobOutput(job)
+	}
+	return outcome
+}
+
+func (j *Job) runBranch(templates []drmaa2interface.JobTemplate) *Job {
+	for i, jt := range templates {
+		if i > 0 {
+			j.Wait()
+		}
+		j.RunT(jt)
+		if j.lastError != nil {
+			return j
+		}
+	}
+	return j
+}
@@ -0,0 +1,134 @@
+package wfl_test
+
+import (
+	"github.com/dgruber/drmaa2interface"
+	"github.com/dgruber/wfl"
+	"github.com/dgruber/wfl/pkg/context/fake"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Switch", func() {
+
+	var (
+		sm   *fake.SessionManager
+		flow *wfl.Workflow
+	)
+
+	command := func(cmd string) drmaa2interface.JobTemplate {
+		return drmaa2interface.JobTemplate{RemoteCommand: cmd}
+	}
+
+	commands := func() []string {
+		var submitted []string
+		for _, jt := range sm.Submitted() {
+			submitted = append(submitted, jt.RemoteCommand)
+		}
+		return submitted
+	}
+
+	BeforeEach(func() {
+		sm = fake.NewSessionManager().
+			OnCommand("exit2", fake.Outcome{ExitStatus: 2}).
+			OnCommand("exit150", fake.Outcome{ExitStatus: 150}).
+			OnCommand("oom", fake.Outcome{ExitStatus: 1, Output: "error: out of memory"}).
+			OnCommand("metrics", fake.Outcome{Output: `{"metrics": {"accuracy": 0.95}}`})
+		flow = wfl.NewWorkflow(fake.NewFakeContextByCfg(fake.Config{SessionManager: sm}))
+	})
+
+	It("should take the branch matching the exit status", func() {
+		job := flow.Run("exit2").Switch(
+			wfl.When(wfl.ExitStatusIs(0), command("deploy")),
+			wfl.When(wfl.ExitStatusIs(1, 2), command("retry")),
+			wfl.Otherwise(command("cleanup")),
+		)
+		Ω(job.LastError()).Should(BeNil())
+		Ω(commands()).Should(Equal([]string{"exit2", "retry"}))
+	})
+
+	It("should take the branch matching the exit status range", func() {
+		flow.Run("exit150").Switch(
+			wfl.When(wfl.ExitStatusBetween(1, 99), command("low")),
+			wfl.When(wfl.ExitStatusBetween(100, 199), command("high")),
+		)
+		Ω(commands()).Should(Equal([]string{"exit150", "high"}))
+	})
+
+	It("should take the branch matching the output", func() {
+		flow.Run("oom").Switch(
+			wfl.When(wfl.Succeeded(), command("deploy")),
+			wfl.When(wfl.OutputMatches("out of (memory|disk)"), command("more-memory")),
+			wfl.Otherwise(command("cleanup")),
+		)
+		Ω(commands()).Should(Equal([]string{"oom", "more-memory"}))
+	})
+
+	It("should take the branch matching the JSON output", func() {
+		accuracy := func(min float64) wfl.Condition {
+			return wfl.OutputJSON("metrics.accuracy", func(v interface{}) bool {
+				accuracy, ok := v.(float64)
+				return ok && accuracy > min
+			})
+		}
+		flow.Run("metrics").Switch(
+			wfl.When(accuracy(0.99), command("publish")),
+			wfl.When(accuracy(0.9), command("deploy")),
+		)
+		Ω(commands()).Should(Equal([]string{"metrics", "deploy"}))
+
+		flow.Run("metrics").When(wfl.OutputJSON("metrics.unknown",
+			func(v interface{}) bool { return true }), command("unexpected"))
+		flow.Run("exit2").When(wfl.OutputJSON("",
+			func(v interface{}) bool { return true }), command("unexpected"))
+		Ω(commands()).ShouldNot(ContainElement("unexpected"))
+	})
+
+	It("should take the branch matching the JobInfo", func() {
+		flow.Run("exit2").Switch(
+			wfl.When(wfl.JobInfoMatches(func(ji drmaa2interface.JobInfo) bool {
+				return ji.ExitStatus == 2
+			}), command("matched")),
+		)
+		Ω(commands()).Should(Equal([]string{"exit2", "matched"}))
+	})
+
+	It("should combine conditions", func() {
+		flow.Run("oom").Switch(
+			wfl.When(wfl.AllOf(wfl.ExitStatusIs(1), wfl.Negate(wfl.OutputMatches("memory"))),
+				command("first")),
+			wfl.When(wfl.AnyOf(wfl.Succeeded(), wfl.OutputMatches("memory")),
+				command("second")),
+		)
+		Ω(commands()).Should(Equal([]string{"oom", "second"}))
+	})
+
+	It("should take the Otherwise branch when no other branch matches", func() {
+		flow.Run("exit2").Switch(
+			wfl.When(wfl.Succeeded(), command("deploy")),
+			wfl.Otherwise(command("cleanup")),
+		)
+		Ω(commands()).Should(Equal([]string{"exit2", "cleanup"}))
+	})
+
+	It("should submit nothing when no branch is taken", func() {
+		job := flow.Run("exit2").Switch(
+			wfl.When(wfl.Succeeded(), command("deploy")),
+		)
+		Ω(job.LastError()).Should(BeNil())
+		Ω(job.ExitStatus()).Should(Equal(2))
+		Ω(commands()).Should(Equal([]string{"exit2"}))
+	})
+
+	It("should submit the templates of a branch one after the other", func() {
+		job := flow.Run("true").
+			When(wfl.Succeeded(), command("build"), command("exit150")).
+			Switch(
+				wfl.When(wfl.ExitStatusIs(150), command("notify"), command("cleanup")),
+			)
+		Ω(job.LastError()).Should(BeNil())
+		Ω(commands()).Should(Equal([]string{"true", "build", "exit150", "notify", "cleanup"}))
+		Ω(job.Wait().Success()).Should(BeTrue())
+	})
+
+})
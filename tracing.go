@@ -0,0 +1,333 @@
+package wfl
+
+import (
+	"context"
+	"sync"
+
+	"github.com/dgruber/drmaa2interface"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName is the name of the OpenTelemetry tracer of wfl.
+const tracerName = "github.com/dgruber/wfl"
+
+// tracing creates the OpenTelemetry spans of a workflow. It is disabled
+// when no tracer is set.
+type tracing struct {
+	mu     sync.Mutex
+	tracer trace.Tracer
+	// ctx contains the span of the workflow
+	ctx  context.Context
+	span trace.Span
+	// tasks contains the spans of the unfinished tasks by job ID
+	tasks        map[string]*taskSpan
+	subscription int
+}
+
+// taskSpan is the span of a task and the span of its current phase
+// (queued, running, or suspended).
+type taskSpan struct {
+	ctx   context.Context
+	span  trace.Span
+	phase trace.Span
+	state EventType
+}
+
+// WithTracing enables OpenTelemetry tracing for the workflow. A span is
+// created for the workflow as child of the span in the given context.
+// Each task gets a span from its submission until it is finished with
+// the child spans "submit", "queued", "running", and "suspended" and
+// attributes from the JobTemplate and the JobInfo. Resubmitted tasks
+// get a new span with the retry count and the ID of the failed job.
+// Output retrieval creates "output" spans. The state transitions are
+// detected by the event poller of the workflow, hence the accuracy of
+// the phases depends on WithEventPollInterval().
+//
+// EndTracing() ends the span of the workflow.
+//
+// Example:
+//
+//	exporter, _ := stdouttrace.New()
+//	tp := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter))
+//	defer tp.Shutdown(context.Background())
+//
+//	flow := wfl.NewWorkflow(wfl.NewProcessContext()).
+//		WithTracing(context.Background(), tp)
+//	defer flow.EndTracing()
+func (w *Workflow) WithTracing(ctx context.Context, tp trace.TracerProvider) *Workflow {
+	if tp == nil {
+		return w
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	w.EndTracing()
+	tracer := tp.Tracer(tracerName)
+	var attributes []attribute.KeyValue
+	if w.ctx != nil {
+		attributes = append(attributes,
+			attribute.String("wfl.job_session", w.ctx.JobSessionName))
+	}
+	ctx, span := tracer.Start(ctx, "workflow", trace.WithAttributes(attributes...))
+
+	w.tracing.mu.Lock()
+	defer w.tracing.mu.Unlock()
+	w.tracing.tracer = tracer
+	w.tracing.ctx = ctx
+	w.tracing.span = span
+	w.tracing.tasks = make(map[string]*taskSpan)
+	w.tracing.subscription = w.events.subscribe(w.tracing.handle)
+	return w
+}
+
+// EndTracing ends the span of the workflow and the spans of the tasks
+// which are not finished yet.
+func (w *Workflow) EndTracing() {
+	w.tracing.mu.Lock()
+	if w.tracing.tracer == nil {
+		w.tracing.mu.Unlock()
+		return
+	}
+	subscription := w.tracing.subscription
+	w.tracing.mu.Unlock()
+	<-w.events.unsubscribe(subscription)
+
+	w.tracing.mu.Lock()
+	defer w.tracing.mu.Unlock()
+	for _, t := range w.tracing.tasks {
+		t.end()
+	}
+	w.tracing.span.End()
+	w.tracing.tracer = nil
+	w.tracing.tasks = nil
+	w.tracing.ctx = nil
+}
+
+// TraceContext returns a context which contains the span of the
+// workflow when tracing is enabled.
+func (w *Workflow) TraceContext() context.Context {
+	w.tracing.mu.Lock()
+	defer w.tracing.mu.Unlock()
+	if w.tracing.ctx == nil {
+		return context.Background()
+	}
+	return w.tracing.ctx
+}
+
+// parent returns the context for the spans of a job with the given
+// context. The span of the job context (see WithContext()) is used
+// when it has one.
+func (t *tracing) parent(ctx context.Context) context.Context {
+	if ctx != nil && trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx
+	}
+	return t.ctx
+}
+
+// startTask starts the span of a task which is submitted.
+func (t *tracing) startTask(j *Job, jt drmaa2interface.JobTemplate) (*taskSpan, trace.Span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.tracer == nil {
+		return nil, nil
+	}
+	ctx, span := t.tracer.Start(t.parent(j.ctx), "task",
+		trace.WithAttributes(templateAttributes(jt)...))
+	if j.tag != "" {
+		span.SetAttributes(attribute.String("wfl.job.tag", j.tag))
+	}
+	_, submit := t.tracer.Start(ctx, "submit")
+	return &taskSpan{ctx: ctx, span: span}, submit
+}
+
+// submitted ends the submit span. The task span is ended when the
+// submission failed.
+func (t *tracing) submitted(ts *taskSpan, submit trace.Span, job drmaa2interface.Job, err error) {
+	if ts == nil {
+		return
+	}
+	if err != nil {
+		submit.RecordError(err)
+		submit.SetStatus(codes.Error, err.Error())
+		submit.End()
+		ts.span.SetStatus(codes.Error, "submission failed")
+		ts.span.End()
+		return
+	}
+	submit.End()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.tracer == nil {
+		ts.span.End()
+		return
+	}
+	ts.span.SetAttributes(attribute.String("wfl.task.job_id", job.GetID()))
+	ts.startPhase(t.tracer, EventQueued)
+	t.tasks[job.GetID()] = ts
+}
+
+// handle updates the spans of the task when its state changed.
+func (t *tracing) handle(e Event) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.tracer == nil {
+		return
+	}
+	ts, exists := t.tasks[e.JobID]
+	if !exists {
+		if e.Type != EventSubmitted {
+			return
+		}
+		// tasks of job arrays are not submitted individually
+		ts = &taskSpan{}
+		ts.ctx, ts.span = t.tracer.Start(t.parent(e.ctx), "task", trace.WithAttributes(
+			attribute.String("wfl.task.job_id", e.JobID),
+			attribute.String("wfl.task.array_job_id", e.ArrayJobID)))
+		if e.job != nil {
+			if jt, err := e.job.GetJobTemplate(); err == nil {
+				ts.span.SetAttributes(templateAttributes(jt)...)
+			}
+		}
+		ts.startPhase(t.tracer, EventQueued)
+		t.tasks[e.JobID] = ts
+		return
+	}
+	switch e.Type {
+	case EventRetried:
+		ts.span.SetAttributes(
+			attribute.Int("wfl.task.retry", e.Retry),
+			attribute.String("wfl.task.previous_job_id", e.PreviousJobID))
+	case EventQueued, EventRunning, EventSuspended:
+		ts.startPhase(t.tracer, e.Type)
+	case EventDone, EventFailed:
+		if e.JobInfo != nil {
+			ts.span.SetAttributes(jobInfoAttributes(*e.JobInfo)...)
+		}
+		if e.Type == EventFailed {
+			ts.span.SetStatus(codes.Error, "task failed")
+		}
+		ts.end()
+		delete(t.tasks, e.JobID)
+	case EventReaped:
+		ts.end()
+		delete(t.tasks, e.JobID)
+	}
+}
+
+// startPhase ends the span of the current phase and starts a new one.
+func (ts *taskSpan) startPhase(tracer trace.Tracer, state EventType) {
+	if ts.state == state {
+		return
+	}
+	if ts.phase != nil {
+		ts.phase.End()
+	}
+	ts.state = state
+	_, ts.phase = tracer.Start(ts.ctx, string(state))
+}
+
+func (ts *taskSpan) end() {
+	if ts.phase != nil {
+		ts.phase.End()
+	}
+	ts.span.End()
+}
+
+// jobOutput returns the output of the job within an "output" span.
+func (j *Job) jobOutput(job drmaa2interface.Job) (string, error) {
+	return j.traceOutput("stdout", job, getJobOutpuForJob)
+}
+
+// jobErrorOutput returns the error output of the job within an
+// "output" span.
+func (j *Job) jobErrorOutput(job drmaa2interface.Job) (string, error) {
+	return j.traceOutput("stderr", job, getJobErrorForJob)
+}
+
+func (j *Job) traceOutput(stream string, job drmaa2interface.Job,
+	get func(context.Context, SessionManagerType, drmaa2interface.Job) (string, error)) (string, error) {
+	t := &j.wfl.tracing
+	t.mu.Lock()
+	tracer := t.tracer
+	var parent context.Context
+	if tracer != nil {
+		parent = t.parent(j.ctx)
+	}
+	t.mu.Unlock()
+	if tracer == nil {
+		return get(j.ctx, j.wfl.ctx.SMType, job)
+	}
+	_, span := tracer.Start(parent, "output", trace.WithAttributes(
+		attribute.String("wfl.task.job_id", job.GetID()),
+		attribute.String("wfl.output.stream", stream)))
+	defer span.End()
+	output, err := get(j.ctx, j.wfl.ctx.SMType, job)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.SetAttributes(attribute.Int("wfl.output.size", len(output)))
+	return output, err
+}
+
+// templateAttributes returns the span attributes of the job template.
+// Empty values are left out.
+func templateAttributes(jt drmaa2interface.JobTemplate) []attribute.KeyValue {
+	attributes := []attribute.KeyValue{
+		attribute.String("wfl.task.command", jt.RemoteCommand),
+	}
+	if len(jt.Args) > 0 {
+		attributes = append(attributes, attribute.StringSlice("wfl.task.args", jt.Args))
+	}
+	for key, value := range map[string]string{
+		"wfl.task.job_name":          jt.JobName,
+		"wfl.task.job_category":      jt.JobCategory,
+		"wfl.task.queue":             jt.QueueName,
+		"wfl.task.working_directory": jt.WorkingDirectory,
+		"wfl.task.account":           jt.AccountingID,
+	} {
+		if value != "" {
+			attributes = append(attributes, attribute.String(key, value))
+		}
+	}
+	if jt.MinSlots > 0 {
+		attributes = append(attributes, attribute.Int64("wfl.task.min_slots", jt.MinSlots))
+	}
+	if jt.MaxSlots > 0 {
+		attributes = append(attributes, attribute.Int64("wfl.task.max_slots", jt.MaxSlots))
+	}
+	return attributes
+}
+
+// jobInfoAttributes returns the span attributes of the job info of a
+// finished task.
+func jobInfoAttributes(ji drmaa2interface.JobInfo) []attribute.KeyValue {
+	attributes := []attribute.KeyValue{
+		attribute.Int("wfl.task.exit_status", ji.ExitStatus),
+		attribute.String("wfl.task.state", ji.State.String()),
+	}
+	if len(ji.AllocatedMachines) > 0 {
+		attributes = append(attributes,
+			attribute.StringSlice("wfl.task.allocated_machines", ji.AllocatedMachines))
+	}
+	if ji.QueueName != "" {
+		attributes = append(attributes, attribute.String("wfl.task.queue", ji.QueueName))
+	}
+	if ji.TerminatingSignal != "" {
+		attributes = append(attributes,
+			attribute.String("wfl.task.terminating_signal", ji.TerminatingSignal))
+	}
+	if ji.Slots > 0 {
+		attributes = append(attributes, attribute.Int64("wfl.task.slots", ji.Slots))
+	}
+	if ji.WallclockTime > 0 {
+		attributes = append(attributes,
+			attribute.Float64("wfl.task.wallclock_seconds", ji.WallclockTime.Seconds()))
+	}
+	if ji.CPUTime > 0 {
+		attributes = append(attributes, attribute.Int64("wfl.task.cpu_seconds", ji.CPUTime))
+	}
+	return attributes
+}
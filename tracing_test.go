@@ -0,0 +1,139 @@
+package wfl_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/dgruber/drmaa2interface"
+	"github.com/dgruber/wfl"
+	"github.com/dgruber/wfl/pkg/context/fake"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Tracing", func() {
+
+	var (
+		sm       *fake.SessionManager
+		flow     *wfl.Workflow
+		exporter *tracetest.InMemoryExporter
+	)
+
+	spansNamed := func(name string) tracetest.SpanStubs {
+		var spans tracetest.SpanStubs
+		for _, span := range exporter.GetSpans() {
+			if span.Name == name {
+				spans = append(spans, span)
+			}
+		}
+		return spans
+	}
+
+	attributeOf := func(span tracetest.SpanStub, key string) attribute.Value {
+		for _, kv := range span.Attributes {
+			if string(kv.Key) == key {
+				return kv.Value
+			}
+		}
+		return attribute.Value{}
+	}
+
+	BeforeEach(func() {
+		exporter = tracetest.NewInMemoryExporter()
+		tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+		sm = fake.NewSessionManager().WithManualClock().
+			OnCommand("sleep", fake.Outcome{Duration: time.Minute, Output: "slept"}).
+			OnCommand("fail", fake.Outcome{ExitStatus: 3}).
+			OnCommand("broken", fake.Outcome{SubmitError: errors.New("broken")})
+		flow = wfl.NewWorkflow(fake.NewFakeContextByCfg(fake.Config{SessionManager: sm})).
+			WithEventPollInterval(time.Millisecond).
+			WithTracing(context.Background(), tp)
+	})
+
+	It("should create spans for the workflow and the phases of a task", func() {
+		job := flow.NewJob().TagWith("training").RunT(drmaa2interface.JobTemplate{
+			RemoteCommand: "sleep",
+			Args:          []string{"60"},
+			JobName:       "train",
+		})
+		Eventually(func() int { return len(spansNamed("queued")) }).Should(Equal(1))
+		sm.Clock().Advance(time.Minute)
+		Eventually(func() int { return len(spansNamed("task")) }).Should(Equal(1))
+		Ω(job.Output()).Should(Equal("slept"))
+		flow.EndTracing()
+
+		workflow := spansNamed("workflow")
+		Ω(workflow).Should(HaveLen(1))
+		task := spansNamed("task")[0]
+		Ω(task.Parent.SpanID()).Should(Equal(workflow[0].SpanContext.SpanID()))
+		Ω(attributeOf(task, "wfl.task.command").AsString()).Should(Equal("sleep"))
+		Ω(attributeOf(task, "wfl.task.args").AsStringSlice()).Should(Equal([]string{"60"}))
+		Ω(attributeOf(task, "wfl.task.job_name").AsString()).Should(Equal("train"))
+		Ω(attributeOf(task, "wfl.job.tag").AsString()).Should(Equal("training"))
+		Ω(attributeOf(task, "wfl.task.job_id").AsString()).Should(Equal(job.JobID()))
+		Ω(attributeOf(task, "wfl.task.exit_status").AsInt64()).Should(BeEquivalentTo(0))
+		Ω(attributeOf(task, "wfl.task.allocated_machines").AsStringSlice()).Should(
+			Equal([]string{"localhost"}))
+
+		for _, name := range []string{"submit", "queued", "running"} {
+			spans := spansNamed(name)
+			Ω(spans).Should(HaveLen(1), name)
+			Ω(spans[0].Parent.SpanID()).Should(Equal(task.SpanContext.SpanID()), name)
+		}
+		output := spansNamed("output")
+		Ω(output).Should(HaveLen(1))
+		Ω(attributeOf(output[0], "wfl.output.stream").AsString()).Should(Equal("stdout"))
+	})
+
+	It("should mark failed tasks and record retries", func() {
+		job := flow.Run("fail")
+		failedID := job.JobID()
+		job.Retry(1)
+		Eventually(func() int { return len(spansNamed("task")) }).Should(Equal(2))
+		flow.EndTracing()
+
+		for _, task := range spansNamed("task") {
+			Ω(task.Status.Code).Should(Equal(codes.Error))
+			Ω(attributeOf(task, "wfl.task.exit_status").AsInt64()).Should(BeEquivalentTo(3))
+			if attributeOf(task, "wfl.task.job_id").AsString() != failedID {
+				Ω(attributeOf(task, "wfl.task.retry").AsInt64()).Should(BeEquivalentTo(1))
+				Ω(attributeOf(task, "wfl.task.previous_job_id").AsString()).Should(
+					Equal(failedID))
+			}
+		}
+	})
+
+	It("should record submission errors", func() {
+		flow.Run("broken")
+		flow.EndTracing()
+
+		submit := spansNamed("submit")
+		Ω(submit).Should(HaveLen(1))
+		Ω(submit[0].Status.Code).Should(Equal(codes.Error))
+		Ω(submit[0].Status.Description).Should(Equal("broken"))
+		Ω(spansNamed("task")[0].Status.Code).Should(Equal(codes.Error))
+	})
+
+	It("should create spans for the tasks of job arrays", func() {
+		flow.RunArrayJob(1, 2, 1, 2, "true")
+		Eventually(func() int { return len(spansNamed("task")) }).Should(Equal(2))
+		flow.EndTracing()
+		for _, task := range spansNamed("task") {
+			Ω(attributeOf(task, "wfl.task.array_job_id").AsString()).ShouldNot(BeEmpty())
+		}
+	})
+
+	It("should end the spans of unfinished tasks with EndTracing()", func() {
+		flow.Run("sleep")
+		flow.EndTracing()
+		Ω(spansNamed("task")).Should(HaveLen(1))
+		Ω(spansNamed("workflow")).Should(HaveLen(1))
+	})
+
+})
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sync"
 
 	"github.com/dgruber/drmaa2interface"
 	"github.com/dgruber/wfl/pkg/log"
@@ -17,6 +18,21 @@ type Workflow struct {
 	workflowCreationError error
 	log                   log.Logger
 	llmConfig             *llmConfig
+	checkpointMutex       sync.Mutex
+	checkpointStore       CheckpointStore
+	checkpoint            WorkflowCheckpoint
+	jobsMutex             sync.Mutex
+	reportJobs            bool
+	jobs                  []*Job
+	limitsMutex           sync.Mutex
+	limiter               *limiter
+	tagLimiters           map[string]*limiter
+	artifacts             artifacts
+	events                eventBus
+	tracing               tracing
+	metricsMutex          sync.Mutex
+	metrics               *Metrics
+	metricsSubscription   int
 }
 
 // NewWorkflow creates a new Workflow based on the given execution context.
@@ -44,6 +60,7 @@ func NewWorkflow(context *Context) *Workflow {
 			js:                    js,
 			workflowCreationError: err,
 			log:                   logger,
+			events:                eventBus{session: js},
 		}
 	}
 	return &Workflow{ctx: nil,
@@ -59,7 +76,8 @@ func (w *Workflow) Logger() log.Logger {
 
 // SetLogger sets a new logger for the workflow which writes
 // processes internal log messages. Note that nil loggers are
-// not accepted.
+// not accepted. Loggers implementing log.StructuredLogger
+// get the job ID, tag, and command of jobs as separate fields.
 //
 // Example: w.SetLogger(log.NewKlogLogger("INFO"))
 func (w *Workflow) SetLogger(log log.Logger) *Workflow {
@@ -163,6 +181,28 @@ func (w *Workflow) RunMatrixT(jt drmaa2interface.JobTemplate, x, y Replacement)
 	return job
 }
 
+// Pipe connects the output of each stage with the input of the next
+// stage like the shell pipe stage1 | stage2. See Job.Pipe().
+func (w *Workflow) Pipe(stages ...drmaa2interface.JobTemplate) *Job {
+	return w.NewJob().Pipe(stages...)
+}
+
+// RunMatrixNT submits a task for each combination of any number of
+// replacements. See Job.RunMatrixNT().
+func (w *Workflow) RunMatrixNT(jt drmaa2interface.JobTemplate, replacements ...Replacement) *Job {
+	return w.RunMatrixOptsT(jt, MatrixOptions{}, replacements...)
+}
+
+// RunMatrixOptsT submits a task for each selected combination of the
+// replacements. See Job.RunMatrixOptsT().
+func (w *Workflow) RunMatrixOptsT(jt drmaa2interface.JobTemplate, opts MatrixOptions, replacements ...Replacement) *Job {
+	job := NewJob(w)
+	job.ctx = context.WithValue(job.ctx, "log-depth", 4)
+	job.RunMatrixOptsT(jt, opts, replacements...)
+	job.ctx = context.WithValue(job.ctx, "log-depth", 3)
+	return job
+}
+
 // ListJobs returns all jobs visible in the workflow (i.e. available
 // in the underlying drmaa2session). It may wrap one task in one Job
 // object and return multiple Job objects even when only one Job with
@@ -1,10 +1,15 @@
 package wfl_test
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"log/slog"
+	"strings"
 
 	"github.com/dgruber/wfl"
+	"github.com/dgruber/wfl/pkg/context/fake"
 	"github.com/dgruber/wfl/pkg/log"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -30,6 +35,27 @@ func (tl *testLogger) Errorf(ctx context.Context, s string, args ...interface{})
 	tl.errors++
 }
 
+// printfLogger records the messages of a logger which has no structured
+// methods.
+type printfLogger struct {
+	testLogger
+	begins   []string
+	infos    []string
+	warnings []string
+}
+
+func (pl *printfLogger) Begin(ctx context.Context, f string) {
+	pl.begins = append(pl.begins, f)
+}
+
+func (pl *printfLogger) Infof(ctx context.Context, s string, args ...interface{}) {
+	pl.infos = append(pl.infos, fmt.Sprintf(s, args...))
+}
+
+func (pl *printfLogger) Warningf(ctx context.Context, s string, args ...interface{}) {
+	pl.warnings = append(pl.warnings, fmt.Sprintf(s, args...))
+}
+
 var _ = Describe("Workflow", func() {
 
 	Context("Create a workflow successfully", func() {
@@ -219,6 +245,61 @@ var _ = Describe("Workflow", func() {
 			Ω(tl.errors).Should(BeNumerically("==", 1))
 		})
 
+		It("should pass the fields of the job to a structured logger", func() {
+			var buf bytes.Buffer
+			logger, err := log.NewSlogLogger(slog.New(
+				slog.NewJSONHandler(&buf, &slog.HandlerOptions{AddSource: true})))
+			Ω(err).Should(BeNil())
+			flow := wfl.NewWorkflow(fake.NewFakeContext()).
+				SetLogger(logger).SetLogLevel(log.InfoLevel)
+
+			job := flow.NewJob().TagWith("training").Run("train")
+			job.JobID()
+
+			var entry struct {
+				Msg     string `json:"msg"`
+				Level   string `json:"level"`
+				JobID   string `json:"job_id"`
+				Tag     string `json:"tag"`
+				Command string `json:"command"`
+				Attempt int    `json:"attempt"`
+				Source  struct {
+					File string `json:"file"`
+				} `json:"source"`
+			}
+			found := false
+			for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+				Ω(json.Unmarshal([]byte(line), &entry)).Should(Succeed())
+				if entry.Msg == "Entry: JobID()" {
+					found = true
+					break
+				}
+			}
+			Ω(found).Should(BeTrue())
+			Ω(entry.Level).Should(Equal("INFO"))
+			Ω(entry.JobID).Should(Equal(job.JobID()))
+			Ω(entry.Tag).Should(Equal("training"))
+			Ω(entry.Command).Should(Equal("train"))
+			Ω(entry.Attempt).Should(Equal(1))
+			Ω(entry.Source.File).Should(HaveSuffix("workflow_test.go"))
+
+			buf.Reset()
+			flow.SetLogLevel(log.WarningLevel)
+			job.JobID()
+			Ω(buf.Len()).Should(BeZero())
+		})
+
+		It("should append the fields of the job to the messages of other loggers", func() {
+			pl := &printfLogger{}
+			flow := wfl.NewWorkflow(fake.NewFakeContext()).SetLogger(pl)
+			job := flow.NewJob().TagWith("training").Run("train")
+			job.Retry(0)
+			Ω(pl.infos).Should(ContainElement(
+				"Retry() session=wfl tag=training job_id=" + job.JobID() + " command=train attempt=1"))
+			// Begin() of the logger is used without fields
+			Ω(pl.begins).Should(ContainElement("JobID()"))
+		})
+
 	})
 
 })